@@ -7,17 +7,26 @@ import (
 	"flag"
 	"fmt"
 	"graphdb/internal/config"
+	"graphdb/internal/embedding"
 	"graphdb/internal/graph"
 	"graphdb/internal/ingest"
 	"graphdb/internal/loader"
+	"graphdb/internal/mcp"
+	"graphdb/internal/policy"
 	"graphdb/internal/query"
 	"graphdb/internal/rpg"
+	"graphdb/internal/server"
 	"graphdb/internal/storage"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -46,6 +55,14 @@ func main() {
 		handleEnrichFeatures(os.Args[2:])
 	case "import":
 		handleImport(os.Args[2:])
+	case "sync":
+		handleSync(os.Args[2:])
+	case "cache":
+		handleCache(os.Args[2:])
+	case "mcp":
+		handleMCP(os.Args[2:])
+	case "serve":
+		handleServe(os.Args[2:])
 	case "help", "--help", "-h":
 		printUsage()
 	default:
@@ -62,6 +79,10 @@ func printUsage() {
 	fmt.Println("  query            Query the graph (structural or semantic)")
 	fmt.Println("  enrich-features  Build the RPG (Repository Planning Graph) Intent Layer")
 	fmt.Println("  import           Import JSONL files into Neo4j")
+	fmt.Println("  sync             Update Neo4j with the files changed since the imported commit")
+	fmt.Println("  cache            Inspect (stats) or prune the embedding cache")
+	fmt.Println("  mcp              Serve the graph to agents over the Model Context Protocol (stdio)")
+	fmt.Println("  serve            Serve the graph queries as HTTP/JSON endpoints")
 	fmt.Println("\nRun 'graphdb <command> --help' for command-specific options.")
 }
 
@@ -75,8 +96,15 @@ func handleIngest(args []string) {
 	outputPtr := fs.String("output", "graph.jsonl", "Output file path (combined)")
 	nodesPtr := fs.String("nodes", "", "Output file path for nodes")
 	edgesPtr := fs.String("edges", "", "Output file path for edges")
+	dbPtr := fs.String("db", "", "Write into an embedded graph store at this path instead of JSONL")
+	incrementalPtr := fs.Bool("incremental", false, "Skip files unchanged since the last run and emit only upserts and tombstones")
+	newFilter := filterFlags(fs)
+	newDocuments := documentFlags(fs)
+	newContamination := contaminationFlags(fs)
 	
 	fs.Parse(args)
+	documents := newDocuments()
+	contamination := newContamination()
 
 	cfg := config.LoadConfig()
 	
@@ -91,7 +119,13 @@ func handleIngest(args []string) {
 	}
 
 	var emitter storage.Emitter
-	if *nodesPtr != "" || *edgesPtr != "" {
+	if *dbPtr != "" {
+		store, err := query.OpenBoltStore(*dbPtr)
+		if err != nil {
+			log.Fatalf("Failed to open graph store: %v", err)
+		}
+		emitter = store
+	} else if *nodesPtr != "" || *edgesPtr != "" {
 		if *nodesPtr == "" || *edgesPtr == "" {
 			log.Fatalf("Both -nodes and -edges must be provided for split output")
 		}
@@ -119,6 +153,28 @@ func handleIngest(args []string) {
 
 	// Setup Walker
 	walker := ingest.NewWalker(*workersPtr, embedder, emitter)
+	walker.Filter = newFilter(*dirPtr)
+	walker.WorkerPool.Documents = documents
+	walker.WorkerPool.EmbeddingModel = embedderModel(embedder, model)
+	walker.WorkerPool.Contamination = contamination
+
+	// The manifest lives next to whatever the graph is written to
+	var manifestPath string
+	if *incrementalPtr {
+		switch {
+		case *dbPtr != "":
+			manifestPath = ingest.ManifestPath(*dbPtr)
+		case *nodesPtr != "":
+			manifestPath = ingest.ManifestPath(*nodesPtr)
+		default:
+			manifestPath = ingest.ManifestPath(*outputPtr)
+		}
+		manifest, err := ingest.LoadManifest(manifestPath)
+		if err != nil {
+			log.Fatalf("Failed to load manifest: %v", err)
+		}
+		walker.WorkerPool.Manifest = manifest
+	}
 
 	// Context with Cancel
 	ctx, cancel := context.WithCancel(context.Background())
@@ -158,8 +214,18 @@ func handleIngest(args []string) {
 		if err := walker.Run(ctx, *dirPtr); err != nil {
 			log.Fatalf("Walker failed: %v", err)
 		}
+		if walker.Skipped > 0 {
+			log.Printf("Skipped %d ignored, excluded, oversized or binary files.", walker.Skipped)
+		}
 	}
 
+	if manifestPath != "" {
+		if err := walker.WorkerPool.Manifest.Save(manifestPath); err != nil {
+			log.Fatalf("Failed to save manifest: %v", err)
+		}
+	}
+
+	logCacheStats(embedder)
 	log.Printf("Done in %v.", time.Since(start))
 }
 
@@ -172,7 +238,11 @@ func handleEnrichFeatures(args []string) {
 	dirPtr := fs.String("dir", ".", "Directory to analyze")
 	inputPtr := fs.String("input", "graph.jsonl", "Input graph file")
 	outputPtr := fs.String("output", "rpg.jsonl", "Output file for RPG nodes and edges")
-	batchSizePtr := fs.Int("batch-size", 20, "Batch size for LLM feature extraction")
+	dbPtr := fs.String("db", "", "Write into an embedded graph store at this path instead of -output")
+	batchSizePtr := fs.Int("batch-size", 20, "Functions per LLM feature extraction prompt")
+	workersPtr := fs.Int("workers", 4, "Concurrent LLM feature extraction prompts")
+	checkpointPtr := fs.String("checkpoint", "", "Checkpoint of LLM answers (default: <output or db>.checkpoint.jsonl; 'off' disables)")
+	resumePtr := fs.Bool("resume", true, "Reuse the answers in the checkpoint; -resume=false starts it afresh")
 	clusterModePtr := fs.String("cluster-mode", "file", "Clustering mode: 'file' (structural) or 'semantic' (embedding-based)")
 
 	fs.Parse(args)
@@ -198,26 +268,58 @@ func handleEnrichFeatures(args []string) {
 	}
 	log.Printf("Loaded %d functions from %s", len(functions), *inputPtr)
 
-	// 2. Extract atomic features per function
+	// 2. Extract atomic features per function, skipping what the checkpoint has
+	checkpointPath := *checkpointPtr
+	if checkpointPath == "" {
+		switch {
+		case *dbPtr != "":
+			checkpointPath = rpg.CheckpointPath(*dbPtr)
+		case *outputPtr == os.DevNull:
+			checkpointPath = "off"
+		default:
+			checkpointPath = rpg.CheckpointPath(*outputPtr)
+		}
+	}
+	var checkpoint *rpg.Checkpoint
+	if checkpointPath != "off" {
+		checkpoint, err = rpg.OpenCheckpoint(checkpointPath, *resumePtr)
+		if err != nil {
+			log.Printf("Warning: continuing without checkpoint: %v", err)
+		} else {
+			defer checkpoint.Close()
+			log.Printf("Checkpointing LLM answers to %s (%d reusable)", checkpointPath, checkpoint.Len())
+		}
+	}
+
 	extractor := setupExtractor(cfg.GoogleCloudProject, loc)
-	log.Printf("Extracting atomic features (batch size: %d)...", *batchSizePtr)
-	for i := range functions {
-		fn := &functions[i]
+	if checkpoint != nil {
+		extractor = &rpg.CheckpointExtractor{Extractor: extractor, Checkpoint: checkpoint}
+	}
+	log.Printf("Extracting atomic features (batch size: %d, workers: %d)...", *batchSizePtr, *workersPtr)
+	sources := make([]rpg.FunctionSource, len(functions))
+	for i, fn := range functions {
 		name, _ := fn.Properties["name"].(string)
 		code, _ := fn.Properties["content"].(string)
-
-		descriptors, err := extractor.Extract(code, name)
-		if err != nil {
-			log.Printf("Warning: extraction failed for %s: %v", name, err)
+		sources[i] = rpg.FunctionSource{ID: fn.ID, Name: name, Code: code}
+	}
+	pool := &rpg.ExtractionPool{
+		Extractor: extractor,
+		BatchSize: *batchSizePtr,
+		Workers:   *workersPtr,
+		Progress: func(done, total int) {
+			log.Printf("  Extracted features for %d/%d functions", done, total)
+		},
+	}
+	extracted, failures := pool.Run(sources)
+	for i := range functions {
+		fn := &functions[i]
+		if err, failed := failures[fn.ID]; failed {
+			log.Printf("Warning: extraction failed for %s: %v", sources[i].Name, err)
 			continue
 		}
-		fn.Properties["atomic_features"] = descriptors
-
-		if (i+1)%(*batchSizePtr) == 0 {
-			log.Printf("  Extracted features for %d/%d functions", i+1, len(functions))
-		}
+		fn.Properties["atomic_features"] = extracted[fn.ID]
 	}
-	log.Printf("Extracted atomic features for %d functions", len(functions))
+	log.Printf("Extracted atomic features for %d functions (%d failed)", len(functions)-len(failures), len(failures))
 
 	// 3. Setup Builder
 	var clusterer rpg.Clusterer
@@ -245,6 +347,9 @@ func handleEnrichFeatures(args []string) {
 
 	// 5. Setup Enricher
 	summarizer := setupSummarizer(cfg.GoogleCloudProject, loc)
+	if checkpoint != nil {
+		summarizer = &rpg.CheckpointSummarizer{Summarizer: summarizer, Checkpoint: checkpoint}
+	}
 	embedder := setupEmbedder(cfg.GoogleCloudProject, loc, model)
 	enricher := &rpg.Enricher{
 		Client:   summarizer,
@@ -267,14 +372,25 @@ func handleEnrichFeatures(args []string) {
 
 	// 7. Flatten for Persistence
 	nodes, allEdges := rpg.Flatten(features, edges)
+	updates := rpg.FunctionUpdates(functions, edges)
 
 	// 8. Persistence (Emit to storage)
-	outFile, err := os.Create(*outputPtr)
-	if err != nil {
-		log.Fatalf("Failed to create output file: %v", err)
+	var emitter storage.Emitter
+	destination := *outputPtr
+	if *dbPtr != "" {
+		store, err := query.OpenBoltStore(*dbPtr)
+		if err != nil {
+			log.Fatalf("Failed to open graph store: %v", err)
+		}
+		emitter = store
+		destination = *dbPtr
+	} else {
+		outFile, err := os.Create(*outputPtr)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		emitter = storage.NewJSONLEmitter(outFile)
 	}
-	defer outFile.Close()
-	emitter := storage.NewJSONLEmitter(outFile)
 	defer emitter.Close()
 
 	for i := range nodes {
@@ -287,8 +403,24 @@ func handleEnrichFeatures(args []string) {
 			log.Printf("Warning: failed to emit edge: %v", err)
 		}
 	}
+	// Atomic features and feature IDs are merged into the Function nodes on load
+	if updater, ok := emitter.(storage.Updater); ok {
+		for i := range updates {
+			if err := updater.UpdateNode(&updates[i]); err != nil {
+				log.Printf("Warning: failed to emit function update: %v", err)
+			}
+		}
+	}
+
+	log.Printf("Successfully emitted %d nodes, %d edges and %d function updates to %s", len(nodes), len(allEdges), len(updates), destination)
 
-	log.Printf("Successfully emitted %d nodes and %d edges to %s", len(nodes), len(allEdges), *outputPtr)
+	if checkpoint != nil {
+		reused, recorded := checkpoint.Stats()
+		log.Printf("Checkpoint: reused %d LLM answers, recorded %d", reused, recorded)
+		if err := checkpoint.Compact(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
 }
 
 func handleImport(args []string) {
@@ -298,6 +430,7 @@ func handleImport(args []string) {
 	inputPtr := fs.String("input", "", "Path to combined JSONL file (nodes + edges)")
 	batchSizePtr := fs.Int("batch-size", 500, "Batch size for insertion")
 	cleanPtr := fs.Bool("clean", false, "Wipe database before importing")
+	similarityPtr := fs.String("similarity", "", "Vector index similarity function: cosine or euclidean (default: $NEO4J_VECTOR_SIMILARITY or cosine)")
 	
 	fs.Parse(args)
 
@@ -317,6 +450,11 @@ func handleImport(args []string) {
 	defer driver.Close(context.Background())
 
 	loader := loader.NewNeo4jLoader(driver, "neo4j") // Default DB name
+	if *similarityPtr != "" {
+		loader.Similarity = *similarityPtr
+	} else if cfg.VectorSimilarity != "" {
+		loader.Similarity = cfg.VectorSimilarity
+	}
 
 	ctx := context.Background()
 
@@ -343,10 +481,14 @@ func handleImport(args []string) {
 		nodeFiles = append(nodeFiles, *nodesPtr)
 	}
 
+	// Partial updates are applied once every node file is in, as the nodes
+	// they update may come from a later file
+	var updates []graph.Node
 	for _, path := range nodeFiles {
 		log.Printf("Importing nodes from %s...", path)
 		if err := processBatches(path, *batchSizePtr, func(batch []json.RawMessage) error {
 			var nodes []graph.Node
+			var deleted []string
 			for _, raw := range batch {
 				var flat map[string]interface{}
 				if err := json.Unmarshal(raw, &flat); err != nil {
@@ -365,6 +507,12 @@ func handleImport(args []string) {
 					continue
 				}
 
+				// Tombstones from incremental ingest
+				if isDeleted(flat) {
+					deleted = append(deleted, id)
+					continue
+				}
+
 				// Remove ID and Type from properties
 				delete(flat, "id")
 				delete(flat, "type")
@@ -374,13 +522,36 @@ func handleImport(args []string) {
 					Label:      label,
 					Properties: flat,
 				}
+				if isPartial(flat) {
+					delete(flat, "partial")
+					updates = append(updates, n)
+					continue
+				}
 				nodes = append(nodes, n)
 			}
-			return loader.BatchLoadNodes(ctx, nodes)
+			if err := loader.BatchLoadNodes(ctx, nodes); err != nil {
+				return err
+			}
+			return loader.BatchDeleteNodes(ctx, deleted)
 		}); err != nil {
 			log.Fatalf("Failed to import nodes: %v", err)
 		}
 	}
+	for start := 0; start < len(updates); start += *batchSizePtr {
+		end := min(start+*batchSizePtr, len(updates))
+		if err := loader.BatchUpdateNodes(ctx, updates[start:end]); err != nil {
+			log.Fatalf("Failed to update nodes: %v", err)
+		}
+	}
+	if len(updates) > 0 {
+		log.Printf("Applied %d partial node updates.", len(updates))
+	}
+
+	// Vector indexes need the embeddings in place to detect their dimensions
+	log.Println("Applying vector indexes...")
+	if err := loader.ApplyVectorIndexes(ctx); err != nil {
+		log.Printf("Warning: failed to apply vector indexes: %v", err)
+	}
 
 	// 3. Load Edges
 	var edgeFiles []string
@@ -394,7 +565,7 @@ func handleImport(args []string) {
 	for _, path := range edgeFiles {
 		log.Printf("Importing edges from %s...", path)
 		if err := processBatches(path, *batchSizePtr, func(batch []json.RawMessage) error {
-			var edges []graph.Edge
+			var edges, deleted []graph.Edge
 			for _, raw := range batch {
 				var flat map[string]interface{}
 				if err := json.Unmarshal(raw, &flat); err != nil {
@@ -419,9 +590,16 @@ func handleImport(args []string) {
 					TargetID: tgt,
 					Type:     typ,
 				}
+				if isDeleted(flat) {
+					deleted = append(deleted, e)
+					continue
+				}
 				edges = append(edges, e)
 			}
-			return loader.BatchLoadEdges(ctx, edges)
+			if err := loader.BatchLoadEdges(ctx, edges); err != nil {
+				return err
+			}
+			return loader.BatchDeleteEdges(ctx, deleted)
 		}); err != nil {
 			log.Fatalf("Failed to import edges: %v", err)
 		}
@@ -439,6 +617,511 @@ func handleImport(args []string) {
 	}
 }
 
+func handleSync(args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	dirPtr := fs.String("dir", ".", "Repository directory (the same -dir the graph was ingested with)")
+	workersPtr := fs.Int("workers", 4, "Number of workers")
+	batchSizePtr := fs.Int("batch-size", 500, "Number of records per Neo4j batch")
+	newFilter := filterFlags(fs)
+	newDocuments := documentFlags(fs)
+
+	fs.Parse(args)
+	documents := newDocuments()
+
+	cfg := config.LoadConfig()
+	if cfg.Neo4jURI == "" {
+		log.Fatal("NEO4J_URI environment variable is not set")
+	}
+
+	loc := cfg.GoogleCloudLocation
+	if loc == "" {
+		loc = "us-central1"
+	}
+
+	model := cfg.GeminiEmbeddingModel
+	if model == "" {
+		model = "gemini-embedding-001"
+	}
+
+	driver, err := neo4j.NewDriverWithContext(cfg.Neo4jURI, neo4j.BasicAuth(cfg.Neo4jUser, cfg.Neo4jPassword, ""))
+	if err != nil {
+		log.Fatalf("Failed to create Neo4j driver: %v", err)
+	}
+	defer driver.Close(context.Background())
+
+	loader := loader.NewNeo4jLoader(driver, "neo4j") // Default DB name
+	ctx := context.Background()
+	start := time.Now()
+
+	// 1. Work out what changed since the graph was built
+	stored, err := loader.GraphState(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read graph state: %v", err)
+	}
+	if stored == "" {
+		log.Fatal("The graph has no recorded commit; run ingest and import first")
+	}
+	head, err := ingest.GitHead(*dirPtr)
+	if err != nil {
+		log.Fatalf("Failed to resolve HEAD: %v", err)
+	}
+	if stored == head {
+		log.Printf("Graph is already at %s", head)
+		return
+	}
+
+	changes, err := ingest.GitDiff(*dirPtr, stored, head)
+	if err != nil {
+		log.Fatalf("Failed to diff commits: %v", err)
+	}
+
+	// Files that are now filtered out are dropped and not re-parsed
+	filter := newFilter(*dirPtr)
+	var stale, reparse, removed []string
+	isStale := make(map[string]bool)
+	for _, c := range changes {
+		stale = append(stale, c.Path)
+		if c.Status == 'R' {
+			stale = append(stale, c.OldPath)
+			removed = append(removed, c.OldPath)
+		}
+		if c.Status != 'D' && !filter.SkipFile(c.Path) {
+			reparse = append(reparse, c.Path)
+		} else {
+			removed = append(removed, c.Path)
+		}
+	}
+	for _, path := range stale {
+		isStale[path] = true
+	}
+	log.Printf("Syncing %d changed files from %s to %s...", len(changes), stored, head)
+
+	// 2. Drop the subgraphs of removed files, remembering the calls into
+	// changed files so they are linked again if their targets move. Re-parsed
+	// files are updated in place, keeping what enrichment and other files
+	// attached to their nodes.
+	inbound, err := loader.InboundCalls(ctx, stale)
+	if err != nil {
+		log.Fatalf("Failed to collect inbound calls: %v", err)
+	}
+	previous, err := loader.FileGraph(ctx, reparse)
+	if err != nil {
+		log.Fatalf("Failed to read changed files: %v", err)
+	}
+	if err := loader.DeleteFileSubgraphs(ctx, removed); err != nil {
+		log.Fatalf("Failed to delete removed files: %v", err)
+	}
+
+	// 3. Re-parse and re-embed, linking calls against the rest of the graph
+	all, err := loader.Symbols(ctx)
+	if err != nil {
+		log.Fatalf("Failed to load symbols: %v", err)
+	}
+	var symbols []*graph.Node
+	for _, n := range all {
+		if file, _ := n.Properties["file"].(string); !isStale[file] {
+			symbols = append(symbols, n)
+		}
+	}
+
+	embedder := setupEmbedder(cfg.GoogleCloudProject, loc, model)
+	emitter := loader.NewEmitter(ctx, *batchSizePtr)
+	emitter.Replace(previous)
+	pool := ingest.NewWorkerPool(*workersPtr, embedder, emitter)
+	pool.Documents = documents
+	pool.EmbeddingModel = embedderModel(embedder, model)
+	// Contamination spreads along CALLS across the whole graph, which sync
+	// only sees part of; the risk properties are left for a full ingest
+	pool.Contamination = nil
+	pool.Root = *dirPtr
+	pool.Linker.AddNodes(symbols)
+	for i := range inbound {
+		pool.Linker.AddCall(&inbound[i])
+	}
+
+	pool.Start()
+	for _, path := range reparse {
+		pool.Submit(path)
+	}
+	pool.Stop()
+
+	nodeCount, edgeCount := emitter.Counts()
+	if err := emitter.Close(); err != nil {
+		log.Fatalf("Failed to load changes: %v", err)
+	}
+
+	// 4. Advance the graph to HEAD
+	if err := loader.UpdateGraphState(ctx, head); err != nil {
+		log.Fatalf("Failed to update graph state: %v", err)
+	}
+
+	logCacheStats(embedder)
+	log.Printf("Synced %d files (%d nodes, %d edges) in %v.", len(changes), nodeCount, edgeCount, time.Since(start))
+}
+
+// filterFlags registers the file selection flags shared by ingest and sync and
+// returns a constructor for the resulting filter.
+func filterFlags(fs *flag.FlagSet) func(root string) *ingest.Filter {
+	includePtr := fs.String("include", "", "Comma-separated .gitignore-style globs; only matching files are ingested")
+	excludePtr := fs.String("exclude", "", "Comma-separated .gitignore-style globs of files and directories to skip")
+	maxSizePtr := fs.Int64("max-file-size", ingest.DefaultMaxFileSize, "Skip files larger than this many bytes (0 = no limit)")
+	noIgnorePtr := fs.Bool("no-ignore", false, "Do not read .gitignore and .graphdbignore files")
+
+	return func(root string) *ingest.Filter {
+		filter := ingest.NewFilter(root)
+		filter.Include = splitList(*includePtr)
+		filter.Exclude = splitList(*excludePtr)
+		filter.MaxFileSize = *maxSizePtr
+		filter.NoIgnoreFiles = *noIgnorePtr
+		return filter
+	}
+}
+
+// documentFlags registers the embedding document flags shared by ingest and
+// sync and returns a constructor for the resulting builder.
+func documentFlags(fs *flag.FlagSet) func() *ingest.DocumentBuilder {
+	templatePtr := fs.String("embed-template", ingest.TemplateBody, "Text embedded per function: 'name', 'signature' (with doc comment and scope) or 'body' (signature plus body)")
+	maxCharsPtr := fs.Int("embed-max-chars", 6000, "Truncate function bodies longer than this many characters")
+	chunkCharsPtr := fs.Int("embed-chunk-chars", 2000, "Embed longer bodies in chunks of this many characters and average the vectors")
+
+	return func() *ingest.DocumentBuilder {
+		documents := &ingest.DocumentBuilder{
+			Template:     *templatePtr,
+			MaxBodyChars: *maxCharsPtr,
+			ChunkChars:   *chunkCharsPtr,
+		}
+		if err := documents.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		return documents
+	}
+}
+
+// contaminationFlags registers the contamination rules flag of ingest and
+// returns a loader for the resulting rules, nil if disabled.
+func contaminationFlags(fs *flag.FlagSet) func() ingest.ContaminationRules {
+	rulesPtr := fs.String("contamination-rules", "", "JSON file of contamination rules overriding the built-in ui, db and io rules ('off' skips risk analysis)")
+
+	return func() ingest.ContaminationRules {
+		switch *rulesPtr {
+		case "":
+			return ingest.DefaultContaminationRules()
+		case "off":
+			return nil
+		}
+		rules, err := ingest.LoadContaminationRules(*rulesPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return rules
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func handleCache(args []string) {
+	if len(args) == 0 || (args[0] != "stats" && args[0] != "prune") {
+		fmt.Println("Usage: graphdb cache <stats|prune> [options]")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("cache "+args[0], flag.ExitOnError)
+	pathPtr := fs.String("path", embeddingCachePath(config.LoadConfig()), "Embedding cache file")
+	maxMBPtr := fs.Int64("max-mb", 0, "prune: evict least recently used entries until the cache fits in this many MB")
+	olderThanPtr := fs.Duration("older-than", 0, "prune: remove entries not used for this long (e.g. 720h)")
+	modelPtr := fs.String("model", "", "prune: remove entries of this embedding model")
+	allPtr := fs.Bool("all", false, "prune: remove every entry")
+	fs.Parse(args[1:])
+
+	if *pathPtr == "" {
+		log.Fatal("Embedding cache is disabled (GRAPHDB_EMBEDDING_CACHE=off)")
+	}
+	if _, err := os.Stat(*pathPtr); os.IsNotExist(err) {
+		fmt.Printf("No embedding cache at %s.\n", *pathPtr)
+		return
+	}
+	cache, err := embedding.OpenCache(*pathPtr)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer cache.Close()
+
+	if args[0] == "prune" {
+		opts := embedding.PruneOptions{
+			MaxBytes:  *maxMBPtr << 20,
+			OlderThan: *olderThanPtr,
+			Model:     *modelPtr,
+			All:       *allPtr,
+		}
+		if opts == (embedding.PruneOptions{}) {
+			log.Fatal("Nothing to prune: use -max-mb, -older-than, -model or -all")
+		}
+		removed, err := cache.Prune(opts)
+		if err != nil {
+			log.Fatalf("Prune failed: %v", err)
+		}
+		fmt.Printf("Removed %d entries.\n", removed)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		log.Fatalf("Failed to read cache: %v", err)
+	}
+	output := map[string]interface{}{
+		"path":     *pathPtr,
+		"entries":  stats.Entries,
+		"bytes":    stats.Bytes,
+		"fileSize": stats.FileSize,
+		"hits":     stats.Hits,
+		"misses":   stats.Misses,
+		"models":   stats.ByModel,
+	}
+	if stats.Entries > 0 {
+		output["oldestAccess"] = stats.Oldest.Format(time.RFC3339)
+		output["newestAccess"] = stats.Newest.Format(time.RFC3339)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(output); err != nil {
+		log.Fatalf("Failed to encode result: %v", err)
+	}
+}
+
+// embeddingCachePath returns the embedding cache file, or "" if caching is
+// turned off with GRAPHDB_EMBEDDING_CACHE=off.
+func embeddingCachePath(cfg config.Config) string {
+	switch cfg.EmbeddingCache {
+	case "off", "false", "0":
+		return ""
+	case "":
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(dir, "graphdb", "embeddings.db")
+	}
+	return cfg.EmbeddingCache
+}
+
+// Default models of the OpenAI-compatible provider.
+const (
+	defaultOpenAIEmbeddingModel = "text-embedding-3-small"
+	defaultOpenAIChatModel      = "gpt-4o-mini"
+)
+
+// setupEmbedder returns the embedder of newEmbedder, for the commands that
+// cannot run without one.
+func setupEmbedder(project, location, modelName string) embedding.Embedder {
+	embedder, err := newEmbedder(project, location, modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return embedder
+}
+
+// localEmbedder returns the embedder selected by GRAPHDB_EMBEDDER when it is
+// not Vertex AI: the offline hashed embedder or an OpenAI-compatible API.
+func localEmbedder() (embedding.Embedder, bool, error) {
+	cfg := config.LoadConfig()
+	switch cfg.Embedder {
+	case "", "vertex":
+		return nil, false, nil
+	case "hashed":
+		dims, err := embeddingDimensions(cfg)
+		if err != nil {
+			return nil, true, err
+		}
+		return embedding.NewHashedEmbedder(dims), true, nil
+	case "openai":
+		model := cfg.OpenAIEmbeddingModel
+		if model == "" {
+			model = defaultOpenAIEmbeddingModel
+		}
+		dims, err := embeddingDimensions(cfg)
+		if err != nil {
+			return nil, true, err
+		}
+		embedder := embedding.NewOpenAIEmbedder(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, model, dims)
+		if embedder.Policy, err = loadAPIPolicy(); err != nil {
+			return nil, true, err
+		}
+		cached, err := withEmbeddingCache(embedder, embedder.ModelName())
+		return cached, true, err
+	}
+	return nil, true, fmt.Errorf("unknown GRAPHDB_EMBEDDER %q (expected vertex, hashed or openai)", cfg.Embedder)
+}
+
+// embeddingDimensions parses GRAPHDB_EMBEDDING_DIMENSIONS; zero means the
+// embedder's default.
+func embeddingDimensions(cfg config.Config) (int, error) {
+	if cfg.EmbeddingDimensions == "" {
+		return 0, nil
+	}
+	dims, err := strconv.Atoi(cfg.EmbeddingDimensions)
+	if err != nil {
+		return 0, fmt.Errorf("invalid GRAPHDB_EMBEDDING_DIMENSIONS: %w", err)
+	}
+	return dims, nil
+}
+
+// openAIChatModel returns the chat model of the OpenAI-compatible provider if
+// GRAPHDB_LLM selects it instead of Vertex AI.
+func openAIChatModel() (string, bool) {
+	cfg := config.LoadConfig()
+	switch cfg.LLM {
+	case "", "vertex":
+		return "", false
+	case "openai":
+		if cfg.OpenAIChatModel == "" {
+			return defaultOpenAIChatModel, true
+		}
+		return cfg.OpenAIChatModel, true
+	}
+	log.Fatalf("Unknown GRAPHDB_LLM %q (expected vertex or openai)", cfg.LLM)
+	return "", false
+}
+
+// localSummarizer returns the OpenAI-compatible summarizer if GRAPHDB_LLM
+// selects it.
+func localSummarizer() (rpg.Summarizer, bool) {
+	model, ok := openAIChatModel()
+	if !ok {
+		return nil, false
+	}
+	cfg := config.LoadConfig()
+	summarizer := rpg.NewOpenAISummarizer(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, model)
+	summarizer.Policy = apiPolicy()
+	return summarizer, true
+}
+
+// localExtractor returns the OpenAI-compatible feature extractor if
+// GRAPHDB_LLM selects it.
+func localExtractor() (rpg.FeatureExtractor, bool) {
+	model, ok := openAIChatModel()
+	if !ok {
+		return nil, false
+	}
+	cfg := config.LoadConfig()
+	extractor := rpg.NewOpenAIFeatureExtractor(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, model)
+	extractor.Policy = apiPolicy()
+	return extractor, true
+}
+
+// apiPolicy returns the policy of loadAPIPolicy, for the commands that
+// cannot run without one.
+func apiPolicy() *policy.Policy {
+	p, err := loadAPIPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return p
+}
+
+// loadAPIPolicy returns the retry, rate limit and concurrency policy for one
+// model, from the defaults and the GRAPHDB_API_* variables. Each model gets
+// its own policy, as providers set quotas per model.
+func loadAPIPolicy() (*policy.Policy, error) {
+	cfg := config.LoadConfig()
+	p := policy.Default()
+
+	for _, v := range []struct {
+		name, value string
+		dst         *int
+	}{
+		{"GRAPHDB_API_MAX_RETRIES", cfg.APIMaxRetries, &p.MaxRetries},
+		{"GRAPHDB_API_CONCURRENCY", cfg.APIConcurrency, &p.Concurrency},
+		{"GRAPHDB_API_RPM", cfg.APIRequestsPerMinute, &p.RequestsPerMinute},
+		{"GRAPHDB_API_TPM", cfg.APITokensPerMinute, &p.TokensPerMinute},
+	} {
+		if v.value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v.value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s: %q", v.name, v.value)
+		}
+		*v.dst = n
+	}
+
+	if cfg.APITimeout != "" {
+		timeout, err := time.ParseDuration(cfg.APITimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GRAPHDB_API_TIMEOUT: %w", err)
+		}
+		p.Timeout = timeout
+	}
+	return p, nil
+}
+
+// embedderModel names the model behind embedder, for embedding provenance:
+// model unless the embedder names itself.
+func embedderModel(embedder embedding.Embedder, model string) string {
+	if named, ok := embedder.(interface{ ModelName() string }); ok {
+		return named.ModelName()
+	}
+	return model
+}
+
+// withEmbeddingCache wraps embedder in the persistent embedding cache. If the
+// cache cannot be opened, e.g. because another run holds it, embedder is
+// returned uncached.
+func withEmbeddingCache(embedder embedding.Embedder, model string) (embedding.Embedder, error) {
+	cfg := config.LoadConfig()
+	path := embeddingCachePath(cfg)
+	if path == "" {
+		return embedder, nil
+	}
+	maxBytes := int64(1024 << 20)
+	if cfg.EmbeddingCacheMaxMB != "" {
+		mb, err := strconv.ParseInt(cfg.EmbeddingCacheMaxMB, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GRAPHDB_EMBEDDING_CACHE_MAX_MB: %w", err)
+		}
+		maxBytes = mb << 20
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("WARNING: embedding cache disabled: %v", err)
+		return embedder, nil
+	}
+	cache, err := embedding.OpenCache(path)
+	if err != nil {
+		log.Printf("WARNING: embedding cache disabled: %v", err)
+		return embedder, nil
+	}
+	cache.MaxBytes = maxBytes
+	return embedding.NewCachingEmbedder(embedder, cache, model), nil
+}
+
+// logCacheStats reports how many embeddings came from the cache.
+func logCacheStats(embedder embedding.Embedder) {
+	if cached, ok := embedder.(*embedding.CachingEmbedder); ok {
+		hits, misses := cached.Stats()
+		log.Printf("Embedding cache: %d hits, %d misses.", hits, misses)
+	}
+}
+
+// isDeleted reports whether a JSONL record is a tombstone.
+func isDeleted(record map[string]interface{}) bool {
+	deleted, _ := record["deleted"].(bool)
+	return deleted
+}
+
+// isPartial reports whether a JSONL record updates properties of a node
+// emitted elsewhere rather than defining it.
+func isPartial(record map[string]interface{}) bool {
+	partial, _ := record["partial"].(bool)
+	return partial
+}
+
 func getGitCommit() (string, error) {
 	// Simple git rev-parse HEAD
 	// In a real CLI, we might use the git library or exec
@@ -532,11 +1215,15 @@ func handleQuery(args []string) {
 	typePtr := fs.String("type", "", "Query type: search-features, search-similar, hybrid-context, neighbors, impact, globals, seams, explore-domain")
 	targetPtr := fs.String("target", "", "Target function name or query text")
 	target2Ptr := fs.String("target2", "", "Second target (e.g. for locate-usage)")
+	filePtr := fs.String("file", "", "Narrow an ambiguous -target name to the node in this file (path suffix)")
+	labelPtr := fs.String("label", "", "Narrow an ambiguous -target name to the node with this label (e.g. Function, Class)")
 	depthPtr := fs.Int("depth", 1, "Traversal depth")
 	limitPtr := fs.Int("limit", 10, "Result limit")
 	modulePtr := fs.String("module", ".*", "Module pattern for seams")
+	groupByPtr := fs.String("group-by", "", "Group impact callers by 'file' or 'feature'")
 	edgeTypesPtr := fs.String("edge-types", "", "Comma-separated relationship types for traverse")
 	directionPtr := fs.String("direction", "outgoing", "Traversal direction: incoming, outgoing, both")
+	openBackend := backendFlags(fs)
 	
 	// Embedder args for 'features' type
 	locationPtr := fs.String("location", "us-central1", "GCP Location")
@@ -553,16 +1240,26 @@ func handleQuery(args []string) {
 		model = "gemini-embedding-001"
 	}
 
-	if cfg.Neo4jURI == "" {
-		log.Fatal("NEO4J_URI environment variable is not set")
-	}
-
-	provider, err := query.NewNeo4jProvider(cfg)
+	provider, err := openBackend(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to Neo4j: %v", err)
+		log.Fatal(err)
 	}
 	defer provider.Close()
 
+	// Node targets are resolved up front, so that an ambiguous or unknown name
+	// fails with its candidates or suggestions instead of an arbitrary match
+	node, node2 := *targetPtr, *target2Ptr
+	if nodeQueries[*typePtr] && node != "" {
+		qualifier := query.Qualifier{Label: *labelPtr, File: *filePtr}
+		if *typePtr == "explore-domain" && qualifier.Label == "" {
+			qualifier.Label = "Feature"
+		}
+		node = resolveTarget(provider, node, qualifier)
+	}
+	if *typePtr == "locate-usage" && node2 != "" {
+		node2 = resolveTarget(provider, node2, query.Qualifier{})
+	}
+
 	var result any
 
 	switch *typePtr {
@@ -573,7 +1270,7 @@ func handleQuery(args []string) {
 			log.Fatal("-target is required for 'search-features'")
 		}
 		embedder := setupEmbedder(cfg.GoogleCloudProject, *locationPtr, model)
-		embeddings, err := embedder.EmbedBatch([]string{*targetPtr})
+		embeddings, err := embedder.EmbedBatch([]string{*targetPtr}, embedding.PurposeQuery)
 		if err != nil {
 			 log.Fatalf("Embedding failed: %v", err)
 		}
@@ -584,7 +1281,7 @@ func handleQuery(args []string) {
 			log.Fatal("-target is required for 'search-similar'")
 		}
 		embedder := setupEmbedder(cfg.GoogleCloudProject, *locationPtr, model)
-		embeddings, err := embedder.EmbedBatch([]string{*targetPtr})
+		embeddings, err := embedder.EmbedBatch([]string{*targetPtr}, embedding.PurposeCodeQuery)
 		if err != nil {
 			 log.Fatalf("Embedding failed: %v", err)
 		}
@@ -595,14 +1292,14 @@ func handleQuery(args []string) {
 			log.Fatal("-target is required for 'hybrid-context'")
 		}
 		// 1. Structural Neighbors (Dependency Layer)
-		neighbors, err := provider.GetNeighbors(*targetPtr, *depthPtr)
+		neighbors, err := provider.GetNeighbors(node, *depthPtr)
 		if err != nil {
 			log.Fatalf("Neighbors lookup failed: %v", err)
 		}
 
 		// 2. Semantic Search (Dependency Layer)
 		embedder := setupEmbedder(cfg.GoogleCloudProject, *locationPtr, model)
-		embeddings, err := embedder.EmbedBatch([]string{*targetPtr})
+		embeddings, err := embedder.EmbedBatch([]string{*targetPtr}, embedding.PurposeCodeQuery)
 		if err != nil {
 			log.Printf("Warning: Embedding failed for hybrid search: %v", err)
 		}
@@ -623,19 +1320,28 @@ func handleQuery(args []string) {
 		if *targetPtr == "" {
 			log.Fatal("-target is required for 'neighbors'")
 		}
-		result, err = provider.GetNeighbors(*targetPtr, *depthPtr)
+		result, err = provider.GetNeighbors(node, *depthPtr)
 		
 	case "impact":
 		if *targetPtr == "" {
 			log.Fatal("-target is required for 'impact'")
 		}
-		result, err = provider.GetImpact(*targetPtr, *depthPtr)
+		impact, err := provider.GetImpact(node, *depthPtr)
+		if err != nil {
+			log.Fatalf("Query failed: %v", err)
+		}
+		if *groupByPtr != "" {
+			if err := impact.GroupBy(*groupByPtr); err != nil {
+				log.Fatal(err)
+			}
+		}
+		result = impact
 		
 	case "globals":
 		if *targetPtr == "" {
 			log.Fatal("-target is required for 'globals'")
 		}
-		result, err = provider.GetGlobals(*targetPtr)
+		result, err = provider.GetGlobals(node)
 		
 	case "seams":
 		result, err = provider.GetSeams(*modulePtr)
@@ -644,13 +1350,13 @@ func handleQuery(args []string) {
 		if *targetPtr == "" || *target2Ptr == "" {
 			log.Fatal("-target and -target2 are required for 'locate-usage'")
 		}
-		result, err = provider.LocateUsage(*targetPtr, *target2Ptr)
+		result, err = provider.LocateUsage(node, node2)
 
 	case "fetch-source":
 		if *targetPtr == "" {
 			log.Fatal("-target is required for 'fetch-source'")
 		}
-		source, err := provider.FetchSource(*targetPtr)
+		source, err := provider.FetchSource(node)
 		if err != nil {
 			log.Fatalf("FetchSource failed: %v", err)
 		}
@@ -661,7 +1367,7 @@ func handleQuery(args []string) {
 		if *targetPtr == "" {
 			log.Fatal("-target is required for 'explore-domain'")
 		}
-		result, err = provider.ExploreDomain(*targetPtr)
+		result, err = provider.ExploreDomain(node)
 
 	case "traverse":
 		if *targetPtr == "" {
@@ -674,7 +1380,7 @@ func handleQuery(args []string) {
 		case "both":
 			dir = query.Both
 		}
-		result, err = provider.Traverse(*targetPtr, *edgeTypesPtr, dir, *depthPtr)
+		result, err = provider.Traverse(node, *edgeTypesPtr, dir, *depthPtr)
 
 	case "status":
 		commit, err := provider.GetGraphState()
@@ -699,3 +1405,201 @@ func handleQuery(args []string) {
 		log.Fatalf("Failed to encode result: %v", err)
 	}
 }
+
+// nodeQueries are the query types whose -target names a node rather than
+// being search text.
+var nodeQueries = map[string]bool{
+	"hybrid-context": true,
+	"test-context":   true,
+	"neighbors":      true,
+	"impact":         true,
+	"globals":        true,
+	"locate-usage":   true,
+	"fetch-source":   true,
+	"explore-domain": true,
+	"traverse":       true,
+}
+
+// resolveTarget returns the ID of the one node a name refers to, exiting with
+// the candidates if it is ambiguous or with suggestions if it matches nothing.
+func resolveTarget(provider query.GraphProvider, name string, qualifier query.Qualifier) string {
+	candidate, err := query.Resolve(provider, name, qualifier)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return candidate.ID
+}
+
+// backendFlags registers the graph backend flags shared by query, mcp and serve and
+// returns an opener for the selected provider.
+func backendFlags(fs *flag.FlagSet) func(cfg config.Config) (query.GraphProvider, error) {
+	backendPtr := fs.String("backend", "neo4j", "Graph backend: neo4j, jsonl or bolt")
+	inputPtr := fs.String("input", "graph.jsonl", "Graph JSONL file (jsonl backend)")
+	rpgPtr := fs.String("rpg", "rpg.jsonl", "RPG JSONL file, loaded if present (jsonl backend)")
+	dbPtr := fs.String("db", "graph.db", "Embedded graph store (bolt backend)")
+
+	return func(cfg config.Config) (query.GraphProvider, error) {
+		return openProvider(*backendPtr, cfg, *inputPtr, *rpgPtr, *dbPtr)
+	}
+}
+
+// openProvider creates the GraphProvider for the selected backend.
+func openProvider(backend string, cfg config.Config, input, rpgInput, dbPath string) (query.GraphProvider, error) {
+	switch backend {
+	case "neo4j":
+		if cfg.Neo4jURI == "" {
+			return nil, fmt.Errorf("NEO4J_URI environment variable is not set")
+		}
+		provider, err := query.NewNeo4jProvider(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to Neo4j: %w", err)
+		}
+		return provider, nil
+
+	case "jsonl":
+		paths := []string{input}
+		if _, err := os.Stat(rpgInput); err == nil {
+			paths = append(paths, rpgInput)
+		}
+		provider, err := query.NewJSONLProvider(paths...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JSONL graph: %w", err)
+		}
+		return provider, nil
+
+	case "bolt":
+		if _, err := os.Stat(dbPath); err != nil {
+			return nil, fmt.Errorf("graph store not found: %w", err)
+		}
+		return query.OpenBoltStore(dbPath)
+
+	default:
+		return nil, fmt.Errorf("unknown backend %q (expected neo4j, jsonl or bolt)", backend)
+	}
+}
+
+func handleMCP(args []string) {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	openBackend := backendFlags(fs)
+	locationPtr := fs.String("location", "us-central1", "GCP Location (search tools)")
+	modelPtr := fs.String("model", "", "Embedding model name (search tools)")
+
+	fs.Parse(args)
+
+	cfg := config.LoadConfig()
+	model := *modelPtr
+	if model == "" {
+		model = cfg.GeminiEmbeddingModel
+	}
+	if model == "" {
+		model = "gemini-embedding-001"
+	}
+
+	provider, err := openBackend(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer provider.Close()
+
+	// Only the search tools embed, so the embedder is set up on first use
+	embedder := &lazyEmbedder{setup: func() (embedding.Embedder, error) {
+		return newEmbedder(cfg.GoogleCloudProject, *locationPtr, model)
+	}}
+
+	// stdout carries the protocol; logs go to stderr
+	log.Println("Serving the graph over MCP on stdio...")
+	server := mcp.NewGraphServer(provider, embedder, buildVersion())
+	if err := server.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("MCP session failed: %v", err)
+	}
+	logCacheStats(embedder.embedder)
+}
+
+func handleServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addrPtr := fs.String("addr", "localhost:8080", "Address to listen on")
+	timeoutPtr := fs.Duration("timeout", 30*time.Second, "Per-query timeout (0 for none)")
+	openBackend := backendFlags(fs)
+	locationPtr := fs.String("location", "us-central1", "GCP Location (search endpoints)")
+	modelPtr := fs.String("model", "", "Embedding model name (search endpoints)")
+
+	fs.Parse(args)
+
+	cfg := config.LoadConfig()
+	model := *modelPtr
+	if model == "" {
+		model = cfg.GeminiEmbeddingModel
+	}
+	if model == "" {
+		model = "gemini-embedding-001"
+	}
+
+	provider, err := openBackend(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer provider.Close()
+
+	embedder := &lazyEmbedder{setup: func() (embedding.Embedder, error) {
+		return newEmbedder(cfg.GoogleCloudProject, *locationPtr, model)
+	}}
+
+	httpServer := &http.Server{
+		Addr:              *addrPtr,
+		Handler:           server.New(provider, embedder, *timeoutPtr),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Finish the requests in flight on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		log.Println("Received shutdown signal...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), *timeoutPtr+5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutdown failed: %v", err)
+		}
+	}()
+
+	log.Printf("Serving the graph on http://%s ...", *addrPtr)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Server failed: %v", err)
+	}
+	<-drained
+	logCacheStats(embedder.embedder)
+}
+
+// lazyEmbedder sets up an embedder when it is first asked for a vector. A
+// failed setup fails that request and is tried again on the next one.
+type lazyEmbedder struct {
+	mu       sync.Mutex
+	setup    func() (embedding.Embedder, error)
+	embedder embedding.Embedder
+}
+
+func (e *lazyEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
+	e.mu.Lock()
+	if e.embedder == nil {
+		embedder, err := e.setup()
+		if err != nil {
+			e.mu.Unlock()
+			return nil, fmt.Errorf("failed to set up the embedder: %w", err)
+		}
+		e.embedder = embedder
+	}
+	embedder := e.embedder
+	e.mu.Unlock()
+	return embedder.EmbedBatch(texts, purpose)
+}
+
+// buildVersion returns the module version the binary was built from.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -4,17 +4,15 @@ package main
 
 import (
 	"fmt"
+	"graphdb/internal/embedding"
 )
 
-// MockEmbedder for testing/dry-run
+// MockEmbedder for testing/dry-run. Vectors come from the offline hashed
+// embedder, so similar texts still get similar 768-dim vectors.
 type MockEmbedder struct{}
 
-func (m *MockEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
-	res := make([][]float32, len(texts))
-	for i := range texts {
-		res[i] = make([]float32, 768) // Dummy 768-dim vector
-	}
-	return res, nil
+func (m *MockEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
+	return embedding.NewHashedEmbedder(768).EmbedBatch(texts, purpose)
 }
 
 // MockSummarizer for placeholder RPG
@@ -4,24 +4,38 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"graphdb/internal/config"
 	"graphdb/internal/embedding"
 	"graphdb/internal/rpg"
 	"log"
 	"os"
 )
 
-func setupEmbedder(project, location, modelName string) embedding.Embedder {
+// newEmbedder returns the embedder selected by the configuration, or an error
+// describing what is missing or invalid.
+func newEmbedder(project, location, modelName string) (embedding.Embedder, error) {
 	if os.Getenv("GRAPHDB_MOCK_ENABLED") == "true" {
 		log.Println("Using Mock Embedder (test_mocks build)")
-		return &MockEmbedder{}
+		return &MockEmbedder{}, nil
+	}
+
+	if embedder, ok, err := localEmbedder(); ok {
+		return embedder, err
 	}
 
 	ctx := context.Background()
 	embedder, err := embedding.NewVertexEmbedder(ctx, project, location, modelName)
 	if err != nil {
-		log.Fatalf("Failed to initialize Vertex Embedder: %v", err)
+		return nil, fmt.Errorf("failed to initialize Vertex Embedder: %w", err)
+	}
+	if embedder.Dimensions, err = embeddingDimensions(config.LoadConfig()); err != nil {
+		return nil, err
+	}
+	if embedder.Policy, err = loadAPIPolicy(); err != nil {
+		return nil, err
 	}
-	return embedder
+	return withEmbeddingCache(embedder, embedder.ModelName())
 }
 
 func setupSummarizer(project, location string) rpg.Summarizer {
@@ -30,11 +44,16 @@ func setupSummarizer(project, location string) rpg.Summarizer {
 		return &MockSummarizer{}
 	}
 
+	if summarizer, ok := localSummarizer(); ok {
+		return summarizer
+	}
+
 	ctx := context.Background()
 	summarizer, err := rpg.NewVertexSummarizer(ctx, project, location)
 	if err != nil {
 		log.Fatalf("Failed to initialize Vertex Summarizer: %v", err)
 	}
+	summarizer.Policy = apiPolicy()
 	return summarizer
 }
 
@@ -44,10 +63,15 @@ func setupExtractor(project, location string) rpg.FeatureExtractor {
 		return &rpg.MockFeatureExtractor{}
 	}
 
+	if extractor, ok := localExtractor(); ok {
+		return extractor
+	}
+
 	ctx := context.Background()
 	extractor, err := rpg.NewLLMFeatureExtractor(ctx, project, location)
 	if err != nil {
 		log.Fatalf("Failed to initialize Vertex Feature Extractor: %v", err)
 	}
+	extractor.Policy = apiPolicy()
 	return extractor
 }
@@ -9,6 +9,7 @@ require (
 
 require (
 	github.com/joho/godotenv v1.5.1
+	go.etcd.io/bbolt v1.3.11
 	google.golang.org/genai v1.46.0
 )
 
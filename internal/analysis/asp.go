@@ -56,8 +56,17 @@ func (p *AspParser) Parse(filePath string, content []byte) ([]*graph.Node, []*gr
 		
 		// Adjust line numbers: -1
 		for _, n := range nodes {
-			if line, ok := n.Properties["line"].(int); ok {
-				n.Properties["line"] = line - 1
+			for _, key := range []string{"line", "start_line", "end_line"} {
+				if line, ok := n.Properties[key].(int); ok {
+					n.Properties[key] = line - 1
+				}
+			}
+
+			// The synthetic wrapper spans the whole page rather than the added lines
+			if n.ID == filePath+":AspWrapper" {
+				n.Properties["start_line"] = 1
+				n.Properties["end_line"] = strings.Count(string(maskedContent), "\n") + 1
+				n.Properties["content"] = string(maskedContent)
 			}
 		}
 		
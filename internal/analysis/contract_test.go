@@ -0,0 +1,88 @@
+package analysis_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"graphdb/internal/analysis"
+)
+
+// TestParserContract_SourceSpans verifies that every parser records the span
+// and source text of the definitions it emits.
+func TestParserContract_SourceSpans(t *testing.T) {
+	fixtures := []string{
+		"asp/sample.asp",
+		"asp/sample.aspx",
+		"cpp/sample.cpp",
+		"csharp/sample.cs",
+		"golang/geometry/geometry.go",
+		"java/sample.java",
+		"python/shop/models/order.py",
+		"sql/sample.sql",
+		"typescript/sample.ts",
+		"vbnet/sample.vb",
+	}
+
+	definitionLabels := map[string]bool{
+		"Function":  true,
+		"Class":     true,
+		"Interface": true,
+		"Enum":      true,
+		"Field":     true,
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture, func(t *testing.T) {
+			parser, ok := analysis.GetParser(filepath.Ext(fixture))
+			if !ok {
+				t.Fatalf("No parser registered for %s", filepath.Ext(fixture))
+			}
+
+			absPath, err := filepath.Abs(filepath.Join("../../test/fixtures", fixture))
+			if err != nil {
+				t.Fatalf("Failed to get absolute path: %v", err)
+			}
+			content, err := os.ReadFile(absPath)
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			nodes, _, err := parser.Parse(absPath, content)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			checked := 0
+			for _, n := range nodes {
+				if !definitionLabels[n.Label] {
+					continue
+				}
+				checked++
+
+				start, okStart := n.Properties["start_line"].(int)
+				end, okEnd := n.Properties["end_line"].(int)
+				body, okBody := n.Properties["content"].(string)
+				if !okStart || !okEnd || !okBody {
+					t.Errorf("%s %s: missing start_line/end_line/content (got %v, %v, %T)",
+						n.Label, n.ID, n.Properties["start_line"], n.Properties["end_line"], n.Properties["content"])
+					continue
+				}
+				if start < 1 || end < start {
+					t.Errorf("%s %s: invalid span %d-%d", n.Label, n.ID, start, end)
+				}
+				if lines := strings.Count(body, "\n") + 1; lines != end-start+1 {
+					t.Errorf("%s %s: content has %d lines, span %d-%d covers %d", n.Label, n.ID, lines, start, end, end-start+1)
+				}
+				if strings.TrimSpace(body) == "" {
+					t.Errorf("%s %s: empty content", n.Label, n.ID)
+				}
+			}
+
+			if checked == 0 {
+				t.Errorf("No definitions found in %s", fixture)
+			}
+		})
+	}
+}
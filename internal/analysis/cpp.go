@@ -128,14 +128,17 @@ func (p *CppParser) Parse(filePath string, content []byte) ([]*graph.Node, []*gr
 			}
 
 			nodeID := fmt.Sprintf("%s:%s", filePath, nodeContent)
+			properties := map[string]interface{}{
+				"name": nodeContent,
+				"file": filePath,
+				"line": c.Node.StartPoint().Row + 1,
+			}
+			defNode := findDefinitionNode(c.Node, "function_definition", "field_declaration", "class_specifier", "declaration")
+			setSourceSpan(properties, defNode, content)
 			nodes = append(nodes, &graph.Node{
 				ID:    nodeID,
 				Label: label,
-				Properties: map[string]interface{}{
-					"name": nodeContent,
-					"file": filePath,
-					"line": c.Node.StartPoint().Row + 1,
-				},
+				Properties: properties,
 			})
 			localDefs[nodeContent] = nodeID
 		}
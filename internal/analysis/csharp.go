@@ -107,6 +107,12 @@ func (p *CSharpParser) Parse(filePath string, content []byte) ([]*graph.Node, []
 
 			namespace := findEnclosingNamespace(c.Node, content)
 
+			// Name captures sit directly under their declaration; fields capture the declaration itself
+			defNode := c.Node
+			if strings.HasSuffix(captureName, ".name") {
+				defNode = c.Node.Parent()
+			}
+
 			for _, nodeName := range nodeNames {
 				var label string
 				var fullID string
@@ -115,6 +121,7 @@ func (p *CSharpParser) Parse(filePath string, content []byte) ([]*graph.Node, []
 					"file": filePath,
 					"line": c.Node.StartPoint().Row + 1,
 				}
+				setSourceSpan(properties, defNode, content)
 
 				if strings.HasPrefix(captureName, "class") {
 					label = "Class"
@@ -0,0 +1,900 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+	"graphdb/internal/graph"
+)
+
+// GoParser parses Go source files.
+//
+// Node IDs are package-qualified rather than file-based so that references
+// across packages can be resolved without knowing which file declares them:
+//
+//	Function: <importpath>:<Func>
+//	Method:   <importpath>.<Type>:<Method>
+//	Class:    <importpath>.<Type>   (structs and interfaces)
+//	Global:   <importpath>:<Name>   (package-level var and const)
+//
+// The import path of a file is derived from the nearest go.mod. Methods are
+// emitted as Function nodes (as the Java parser does) so that they take part
+// in Function-scoped queries; the "receiver" property distinguishes them.
+//
+// A package spans several files, so the package-level names a file may refer
+// to (types, globals, method sets and interfaces) are read from the other .go
+// files of its directory as well, and cached until they change.
+type GoParser struct {
+	mu      sync.Mutex
+	modules map[string]goModule // directory -> enclosing module
+
+	declMu sync.Mutex
+	decls  map[string]*goFileDecls // file path -> its package-level declarations
+}
+
+// goModule describes the module that owns a directory.
+type goModule struct {
+	Root string // Directory containing go.mod
+	Path string // Module path declared in go.mod
+}
+
+func init() {
+	RegisterParser(".go", &GoParser{})
+}
+
+// goBuiltins are predeclared functions that never resolve to graph nodes.
+var goBuiltins = map[string]bool{
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true, "make": true,
+	"max": true, "min": true, "new": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true,
+}
+
+func (p *GoParser) Parse(filePath string, content []byte) ([]*graph.Node, []*graph.Edge, error) {
+	parser := sitter.NewParser()
+	parser.SetLanguage(golang.GetLanguage())
+
+	tree, err := parser.ParseCtx(context.Background(), nil, content)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tree.Close()
+
+	root := tree.RootNode()
+	pkgPath := p.packagePath(filePath)
+
+	var nodes []*graph.Node
+	var edges []*graph.Edge
+	seenEdges := make(map[string]bool)
+	addEdge := func(source, target, edgeType string) {
+		key := source + "|" + target + "|" + edgeType
+		if seenEdges[key] {
+			return
+		}
+		seenEdges[key] = true
+		edges = append(edges, &graph.Edge{SourceID: source, TargetID: target, Type: edgeType})
+	}
+
+	nodes = append(nodes, &graph.Node{
+		ID:    filePath,
+		Label: "File",
+		Properties: map[string]interface{}{
+			"name":    filePath,
+			"file":    filePath,
+			"lang":    "go",
+			"package": pkgPath,
+		},
+	})
+
+	// Local state
+	imports := make(map[string]string)  // Alias -> Import Path
+	localTypes := make(map[string]bool) // Type names declared in this file, including function-local ones
+
+	// Package-level declarations of this file, and of the whole package
+	fileDecls := collectGoDecls(root, content)
+	pkgDecls := newGoDecls()
+	pkgDecls.merge(fileDecls)
+	pkgDecls.merge(p.siblingDecls(filePath, goPackageName(root, content)))
+
+	// 1. Definition Query
+	defQueryStr := `
+		(import_spec) @import.spec
+
+		(function_declaration name: (identifier) @function.name) @function.def
+		(method_declaration name: (field_identifier) @method.name) @method.def
+
+		(type_spec name: (type_identifier) @class.name type: (struct_type)) @class.def
+		(type_spec name: (type_identifier) @interface.name type: (interface_type)) @interface.def
+		(type_spec name: (type_identifier) @type.name) @type.def
+
+		(var_spec name: (identifier) @global.name) @global.def
+		(const_spec name: (identifier) @global.name) @global.def
+	`
+
+	qDef, err := sitter.NewQuery([]byte(defQueryStr), golang.GetLanguage())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid definition query: %w", err)
+	}
+	defer qDef.Close()
+
+	qcDef := sitter.NewQueryCursor()
+	defer qcDef.Close()
+	qcDef.Exec(qDef, root)
+
+	for {
+		m, ok := qcDef.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, c := range m.Captures {
+			captureName := qDef.CaptureNameForId(c.Index)
+			nodeContent := c.Node.Content(content)
+
+			switch captureName {
+			case "import.spec":
+				pathNode := c.Node.ChildByFieldName("path")
+				if pathNode == nil {
+					continue
+				}
+				importPath := strings.Trim(pathNode.Content(content), "\"`")
+				alias := importPath[strings.LastIndex(importPath, "/")+1:]
+				if nameNode := c.Node.ChildByFieldName("name"); nameNode != nil {
+					alias = nameNode.Content(content)
+				}
+				// Dot and blank imports don't introduce a usable qualifier
+				if alias != "_" && alias != "." {
+					imports[alias] = importPath
+				}
+
+			case "function.name":
+				properties := map[string]interface{}{
+					"name":    nodeContent,
+					"file":    filePath,
+					"line":    c.Node.StartPoint().Row + 1,
+					"package": pkgPath,
+				}
+				setSourceSpan(properties, c.Node.Parent(), content)
+				nodes = append(nodes, &graph.Node{
+					ID:         fmt.Sprintf("%s:%s", pkgPath, nodeContent),
+					Label:      "Function",
+					Properties: properties,
+				})
+
+			case "method.name":
+				decl := c.Node.Parent()
+				recvType := goReceiverType(decl, content)
+				if recvType == "" {
+					continue
+				}
+				classID := fmt.Sprintf("%s.%s", pkgPath, recvType)
+				methodID := fmt.Sprintf("%s:%s", classID, nodeContent)
+				properties := map[string]interface{}{
+					"name":     nodeContent,
+					"receiver": recvType,
+					"file":     filePath,
+					"line":     c.Node.StartPoint().Row + 1,
+					"package":  pkgPath,
+				}
+				setSourceSpan(properties, decl, content)
+				nodes = append(nodes, &graph.Node{
+					ID:         methodID,
+					Label:      "Function",
+					Properties: properties,
+				})
+				addEdge(classID, methodID, "HAS_METHOD")
+
+			case "class.name", "interface.name":
+				if findEnclosingGoFunction(c.Node) != nil {
+					continue // Function-local types are not part of the package API
+				}
+				kind := "struct"
+				if captureName == "interface.name" {
+					kind = "interface"
+				}
+				properties := map[string]interface{}{
+					"name":    nodeContent,
+					"kind":    kind,
+					"file":    filePath,
+					"line":    c.Node.StartPoint().Row + 1,
+					"package": pkgPath,
+				}
+				setSourceSpan(properties, c.Node.Parent(), content)
+				nodes = append(nodes, &graph.Node{
+					ID:         fmt.Sprintf("%s.%s", pkgPath, nodeContent),
+					Label:      "Class",
+					Properties: properties,
+				})
+
+			case "type.name":
+				localTypes[nodeContent] = true
+
+			case "global.name":
+				if findEnclosingGoFunction(c.Node) != nil {
+					continue // Local variable
+				}
+				if nodeContent == "_" {
+					continue
+				}
+				kind := "var"
+				if c.Node.Parent().Type() == "const_spec" {
+					kind = "const"
+				}
+				properties := map[string]interface{}{
+					"name":    nodeContent,
+					"kind":    kind,
+					"file":    filePath,
+					"line":    c.Node.StartPoint().Row + 1,
+					"package": pkgPath,
+				}
+				setSourceSpan(properties, c.Node.Parent(), content)
+				nodes = append(nodes, &graph.Node{
+					ID:         fmt.Sprintf("%s:%s", pkgPath, nodeContent),
+					Label:      "Global",
+					Properties: properties,
+				})
+			}
+		}
+	}
+
+	// 2. Implicit interface satisfaction of the types declared in this file,
+	// against the method sets and interfaces of the whole package
+	for typeName := range fileDecls.types {
+		methods := pkgDecls.methodSets[typeName]
+		if len(methods) == 0 {
+			continue
+		}
+		have := make(map[string]bool, len(methods))
+		for _, m := range methods {
+			have[m] = true
+		}
+		for ifaceName, required := range pkgDecls.interfaces {
+			if ifaceName == typeName || len(required) == 0 {
+				continue
+			}
+			satisfied := true
+			for _, r := range required {
+				if !have[r] {
+					satisfied = false
+					break
+				}
+			}
+			if satisfied {
+				addEdge(fmt.Sprintf("%s.%s", pkgPath, typeName), fmt.Sprintf("%s.%s", pkgPath, ifaceName), "IMPLEMENTS")
+			}
+		}
+	}
+
+	// 3. Reference/Call Query
+	refQueryStr := `
+		(call_expression
+			function: (identifier) @call.target
+		) @call.site
+
+		(call_expression
+			function: (selector_expression
+				operand: (identifier) @call.scope
+				field: (field_identifier) @call.target
+			)
+		) @call.site
+
+		(selector_expression
+			operand: (identifier) @ref.scope
+			field: (field_identifier) @ref.field
+		) @ref.site
+
+		(identifier) @ref.ident
+	`
+
+	qRef, err := sitter.NewQuery([]byte(refQueryStr), golang.GetLanguage())
+	if err != nil {
+		return nodes, edges, fmt.Errorf("invalid reference query: %w", err)
+	}
+	defer qRef.Close()
+
+	qcRef := sitter.NewQueryCursor()
+	defer qcRef.Close()
+	qcRef.Exec(qRef, root)
+
+	// Variable -> Class ID, cached per enclosing declaration
+	scopeTypes := make(map[uint32]map[string]string)
+	varTypesFor := func(decl *sitter.Node) map[string]string {
+		if vt, ok := scopeTypes[decl.StartByte()]; ok {
+			return vt
+		}
+		vt := goVariableTypes(decl, content, pkgPath, imports)
+		scopeTypes[decl.StartByte()] = vt
+		return vt
+	}
+
+	for {
+		m, ok := qcRef.NextMatch()
+		if !ok {
+			break
+		}
+
+		var targetName, scopeName string
+		var siteNode, identNode *sitter.Node
+		isCall := false
+
+		for _, c := range m.Captures {
+			switch qRef.CaptureNameForId(c.Index) {
+			case "call.target", "ref.field":
+				targetName = c.Node.Content(content)
+			case "call.scope", "ref.scope":
+				scopeName = c.Node.Content(content)
+			case "call.site":
+				siteNode = c.Node
+				isCall = true
+			case "ref.site":
+				siteNode = c.Node
+			case "ref.ident":
+				identNode = c.Node
+				siteNode = c.Node
+			}
+		}
+
+		if siteNode == nil {
+			continue
+		}
+		decl := findEnclosingGoFunction(siteNode)
+		if decl == nil {
+			continue
+		}
+		sourceID := goDeclarationID(decl, content, pkgPath)
+		if sourceID == "" {
+			continue
+		}
+
+		switch {
+		case identNode != nil:
+			// Same-package global read or write
+			name := identNode.Content(content)
+			if !pkgDecls.globals[name] || isGoKeyedElementKey(identNode) {
+				continue
+			}
+			if _, shadowed := varTypesFor(decl)[name]; shadowed {
+				continue
+			}
+			addEdge(sourceID, fmt.Sprintf("%s:%s", pkgPath, name), "USES_GLOBAL")
+
+		case isCall && scopeName == "":
+			// Unqualified call: same package function (skip builtins, conversions
+			// and calls of local func variables and parameters)
+			if goBuiltins[targetName] || localTypes[targetName] || pkgDecls.types[targetName] {
+				continue
+			}
+			if _, shadowed := varTypesFor(decl)[targetName]; shadowed {
+				continue
+			}
+			addEdge(sourceID, fmt.Sprintf("%s:%s", pkgPath, targetName), "CALLS")
+
+		case isCall:
+			if importPath, ok := imports[scopeName]; ok {
+				if _, shadowed := varTypesFor(decl)[scopeName]; !shadowed {
+					addEdge(sourceID, fmt.Sprintf("%s:%s", importPath, targetName), "CALLS")
+					continue
+				}
+			}
+			if classID, ok := varTypesFor(decl)[scopeName]; ok && classID != "" {
+				addEdge(sourceID, fmt.Sprintf("%s:%s", classID, targetName), "CALLS")
+			}
+
+		default:
+			// Non-call selector on an imported package: exported var/const
+			if siteNode.Parent() != nil && siteNode.Parent().Type() == "call_expression" &&
+				siteNode.Parent().ChildByFieldName("function") == siteNode {
+				continue
+			}
+			importPath, ok := imports[scopeName]
+			if !ok {
+				continue
+			}
+			if _, shadowed := varTypesFor(decl)[scopeName]; shadowed {
+				continue
+			}
+			addEdge(sourceID, fmt.Sprintf("%s:%s", importPath, targetName), "USES_GLOBAL")
+		}
+	}
+
+	return nodes, edges, nil
+}
+
+// packagePath returns the import path of the package containing filePath,
+// resolved against the nearest go.mod. Without a module the directory path
+// is used so that IDs stay unique per package.
+func (p *GoParser) packagePath(filePath string) string {
+	dir := filepath.Dir(filePath)
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.ToSlash(dir)
+	}
+
+	mod, ok := p.findModule(absDir)
+	if !ok {
+		return filepath.ToSlash(dir)
+	}
+
+	rel, err := filepath.Rel(mod.Root, absDir)
+	if err != nil || rel == "." {
+		return mod.Path
+	}
+	return mod.Path + "/" + filepath.ToSlash(rel)
+}
+
+// findModule walks up from dir looking for a go.mod, caching results per directory.
+func (p *GoParser) findModule(dir string) (goModule, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.modules == nil {
+		p.modules = make(map[string]goModule)
+	}
+
+	var visited []string
+	curr := dir
+	for {
+		if mod, ok := p.modules[curr]; ok {
+			for _, v := range visited {
+				p.modules[v] = mod
+			}
+			return mod, mod.Path != ""
+		}
+		visited = append(visited, curr)
+
+		if data, err := os.ReadFile(filepath.Join(curr, "go.mod")); err == nil {
+			mod := goModule{Root: curr, Path: parseModulePath(data)}
+			for _, v := range visited {
+				p.modules[v] = mod
+			}
+			return mod, mod.Path != ""
+		}
+
+		parent := filepath.Dir(curr)
+		if parent == curr {
+			break
+		}
+		curr = parent
+	}
+
+	// Cache the miss so we don't stat the whole tree again
+	for _, v := range visited {
+		p.modules[v] = goModule{}
+	}
+	return goModule{}, false
+}
+
+// parseModulePath extracts the module path from go.mod contents.
+func parseModulePath(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module") {
+			rest := strings.TrimSpace(strings.TrimPrefix(line, "module"))
+			if idx := strings.Index(rest, "//"); idx != -1 {
+				rest = strings.TrimSpace(rest[:idx])
+			}
+			return strings.Trim(rest, "\"`")
+		}
+	}
+	return ""
+}
+
+// goTypeName reduces a type expression to its base type name,
+// stripping pointers and type arguments (e.g. *Stack[T] -> Stack).
+func goTypeName(n *sitter.Node, content []byte) string {
+	for n != nil {
+		switch n.Type() {
+		case "type_identifier":
+			return n.Content(content)
+		case "pointer_type":
+			n = n.NamedChild(0)
+		case "generic_type":
+			n = n.ChildByFieldName("type")
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// goReceiverType returns the receiver type name of a method_declaration.
+func goReceiverType(decl *sitter.Node, content []byte) string {
+	if decl == nil {
+		return ""
+	}
+	recv := decl.ChildByFieldName("receiver")
+	if recv == nil {
+		return ""
+	}
+	param := findChildOfType(recv, "parameter_declaration")
+	if param == nil {
+		return ""
+	}
+	return goTypeName(param.ChildByFieldName("type"), content)
+}
+
+// goInterfaceMethods lists the method names declared directly on an interface type_spec.
+func goInterfaceMethods(typeSpec *sitter.Node, content []byte) []string {
+	if typeSpec == nil {
+		return nil
+	}
+	iface := typeSpec.ChildByFieldName("type")
+	if iface == nil {
+		return nil
+	}
+	var methods []string
+	count := int(iface.NamedChildCount())
+	for i := 0; i < count; i++ {
+		child := iface.NamedChild(i)
+		if child.Type() == "method_elem" || child.Type() == "method_spec" {
+			if name := child.ChildByFieldName("name"); name != nil {
+				methods = append(methods, name.Content(content))
+			}
+		}
+	}
+	return methods
+}
+
+// goDeclarationID returns the node ID of a function_declaration or method_declaration.
+func goDeclarationID(decl *sitter.Node, content []byte, pkgPath string) string {
+	nameNode := decl.ChildByFieldName("name")
+	if nameNode == nil {
+		return ""
+	}
+	name := nameNode.Content(content)
+	if decl.Type() == "method_declaration" {
+		recvType := goReceiverType(decl, content)
+		if recvType == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s.%s:%s", pkgPath, recvType, name)
+	}
+	return fmt.Sprintf("%s:%s", pkgPath, name)
+}
+
+// goVariableTypes maps the receiver, parameters and simply-typed locals of a
+// declaration to the Class ID of their type. Variables whose type cannot be
+// inferred map to "" so that they still shadow package-level names.
+func goVariableTypes(decl *sitter.Node, content []byte, pkgPath string, imports map[string]string) map[string]string {
+	types := make(map[string]string)
+
+	classID := func(typeNode *sitter.Node) string {
+		for typeNode != nil && typeNode.Type() == "pointer_type" {
+			typeNode = typeNode.NamedChild(0)
+		}
+		if typeNode == nil {
+			return ""
+		}
+		if typeNode.Type() == "qualified_type" {
+			pkg := typeNode.ChildByFieldName("package")
+			name := typeNode.ChildByFieldName("name")
+			if pkg != nil && name != nil {
+				if importPath, ok := imports[pkg.Content(content)]; ok {
+					return fmt.Sprintf("%s.%s", importPath, name.Content(content))
+				}
+			}
+			return ""
+		}
+		if name := goTypeName(typeNode, content); name != "" {
+			return fmt.Sprintf("%s.%s", pkgPath, name)
+		}
+		return ""
+	}
+
+	addParams := func(list *sitter.Node) {
+		if list == nil {
+			return
+		}
+		count := int(list.NamedChildCount())
+		for i := 0; i < count; i++ {
+			param := list.NamedChild(i)
+			if param.Type() != "parameter_declaration" && param.Type() != "variadic_parameter_declaration" {
+				continue
+			}
+			id := classID(param.ChildByFieldName("type"))
+			nameCount := int(param.NamedChildCount())
+			for k := 0; k < nameCount; k++ {
+				child := param.NamedChild(k)
+				if child.Type() == "identifier" {
+					types[child.Content(content)] = id
+				}
+			}
+		}
+	}
+
+	addParams(decl.ChildByFieldName("receiver"))
+	addParams(decl.ChildByFieldName("parameters"))
+
+	body := decl.ChildByFieldName("body")
+	if body == nil {
+		return types
+	}
+
+	var visit func(n *sitter.Node)
+	visit = func(n *sitter.Node) {
+		switch n.Type() {
+		case "short_var_declaration":
+			left := n.ChildByFieldName("left")
+			right := n.ChildByFieldName("right")
+			if left != nil {
+				leftCount := int(left.NamedChildCount())
+				for i := 0; i < leftCount; i++ {
+					name := left.NamedChild(i).Content(content)
+					id := ""
+					if right != nil && int(right.NamedChildCount()) == leftCount {
+						id = goLiteralClassID(right.NamedChild(i), classID)
+					}
+					if _, exists := types[name]; !exists || id != "" {
+						types[name] = id
+					}
+				}
+			}
+		case "var_spec":
+			id := classID(n.ChildByFieldName("type"))
+			count := int(n.ChildCount())
+			for i := 0; i < count; i++ {
+				child := n.Child(i)
+				if child.Type() == "identifier" && n.FieldNameForChild(i) == "name" {
+					types[child.Content(content)] = id
+				}
+			}
+		case "func_literal":
+			addParams(n.ChildByFieldName("parameters"))
+		case "range_clause":
+			if left := n.ChildByFieldName("left"); left != nil {
+				count := int(left.NamedChildCount())
+				for i := 0; i < count; i++ {
+					if _, exists := types[left.NamedChild(i).Content(content)]; !exists {
+						types[left.NamedChild(i).Content(content)] = ""
+					}
+				}
+			}
+		}
+		count := int(n.NamedChildCount())
+		for i := 0; i < count; i++ {
+			visit(n.NamedChild(i))
+		}
+	}
+	visit(body)
+
+	return types
+}
+
+// goLiteralClassID infers the Class ID of T{...} or &T{...} expressions.
+func goLiteralClassID(expr *sitter.Node, classID func(*sitter.Node) string) string {
+	if expr == nil {
+		return ""
+	}
+	if expr.Type() == "unary_expression" {
+		expr = expr.ChildByFieldName("operand")
+		if expr == nil {
+			return ""
+		}
+	}
+	if expr.Type() == "composite_literal" {
+		return classID(expr.ChildByFieldName("type"))
+	}
+	return ""
+}
+
+// isGoKeyedElementKey reports whether ident is the key of a keyed composite
+// literal element (e.g. Name in T{Name: x}), which names a field rather than a variable.
+func isGoKeyedElementKey(ident *sitter.Node) bool {
+	elem := ident.Parent()
+	if elem == nil || elem.Type() != "literal_element" {
+		return false
+	}
+	keyed := elem.Parent()
+	if keyed == nil || keyed.Type() != "keyed_element" {
+		return false
+	}
+	return keyed.NamedChildCount() > 0 && keyed.NamedChild(0) == elem
+}
+
+func findEnclosingGoFunction(n *sitter.Node) *sitter.Node {
+	curr := n.Parent()
+	for curr != nil {
+		t := curr.Type()
+		if t == "function_declaration" || t == "method_declaration" {
+			return curr
+		}
+		curr = curr.Parent()
+	}
+	return nil
+}
+
+func findChildOfType(n *sitter.Node, nodeType string) *sitter.Node {
+	count := int(n.NamedChildCount())
+	for i := 0; i < count; i++ {
+		child := n.NamedChild(i)
+		if child.Type() == nodeType {
+			return child
+		}
+	}
+	return nil
+}
+
+// goDecls are the package-level declarations of a file, or of a package once
+// merged across its files.
+type goDecls struct {
+	types      map[string]bool     // Type names
+	globals    map[string]bool     // var and const names
+	methodSets map[string][]string // Receiver type name -> method names
+	interfaces map[string][]string // Interface name -> method names
+}
+
+func newGoDecls() *goDecls {
+	return &goDecls{
+		types:      make(map[string]bool),
+		globals:    make(map[string]bool),
+		methodSets: make(map[string][]string),
+		interfaces: make(map[string][]string),
+	}
+}
+
+func (d *goDecls) merge(other *goDecls) {
+	for name := range other.types {
+		d.types[name] = true
+	}
+	for name := range other.globals {
+		d.globals[name] = true
+	}
+	for typeName, methods := range other.methodSets {
+		d.methodSets[typeName] = append(d.methodSets[typeName], methods...)
+	}
+	for name, methods := range other.interfaces {
+		d.interfaces[name] = methods
+	}
+}
+
+// goFileDecls caches the declarations of a file for as long as it is unchanged.
+type goFileDecls struct {
+	modTime time.Time
+	size    int64
+	pkg     string
+	decls   *goDecls
+}
+
+// collectGoDecls reads the package-level declarations of a parsed file.
+func collectGoDecls(root *sitter.Node, content []byte) *goDecls {
+	d := newGoDecls()
+
+	var addValues func(n *sitter.Node)
+	addValues = func(n *sitter.Node) {
+		switch n.Type() {
+		case "func_literal":
+			return // Its body declares locals
+		case "var_spec", "const_spec":
+			count := int(n.ChildCount())
+			for i := 0; i < count; i++ {
+				child := n.Child(i)
+				if child.Type() == "identifier" && n.FieldNameForChild(i) == "name" && child.Content(content) != "_" {
+					d.globals[child.Content(content)] = true
+				}
+			}
+			return
+		}
+		count := int(n.NamedChildCount())
+		for i := 0; i < count; i++ {
+			addValues(n.NamedChild(i))
+		}
+	}
+
+	count := int(root.NamedChildCount())
+	for i := 0; i < count; i++ {
+		decl := root.NamedChild(i)
+		switch decl.Type() {
+		case "type_declaration":
+			specCount := int(decl.NamedChildCount())
+			for k := 0; k < specCount; k++ {
+				spec := decl.NamedChild(k)
+				nameNode := spec.ChildByFieldName("name")
+				if nameNode == nil {
+					continue
+				}
+				name := nameNode.Content(content)
+				d.types[name] = true
+				if typeNode := spec.ChildByFieldName("type"); typeNode != nil && typeNode.Type() == "interface_type" {
+					d.interfaces[name] = goInterfaceMethods(spec, content)
+				}
+			}
+
+		case "var_declaration", "const_declaration":
+			addValues(decl)
+
+		case "method_declaration":
+			recvType := goReceiverType(decl, content)
+			nameNode := decl.ChildByFieldName("name")
+			if recvType != "" && nameNode != nil {
+				d.methodSets[recvType] = append(d.methodSets[recvType], nameNode.Content(content))
+			}
+		}
+	}
+	return d
+}
+
+// goPackageName returns the name in the package clause of a parsed file.
+func goPackageName(root *sitter.Node, content []byte) string {
+	if clause := findChildOfType(root, "package_clause"); clause != nil {
+		if name := findChildOfType(clause, "package_identifier"); name != nil {
+			return name.Content(content)
+		}
+	}
+	return ""
+}
+
+// siblingDecls merges the declarations of the other .go files of filePath's
+// directory that belong to package pkgName, as they are on disk.
+func (p *GoParser) siblingDecls(filePath, pkgName string) *goDecls {
+	merged := newGoDecls()
+	dir := filepath.Dir(filePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil || pkgName == "" {
+		return merged
+	}
+	self := filepath.Clean(filePath)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".go" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if path == self {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if fd := p.fileDecls(path, info); fd != nil && fd.pkg == pkgName {
+			merged.merge(fd.decls)
+		}
+	}
+	return merged
+}
+
+// fileDecls returns the declarations of a file, parsing it again only when
+// its size or modification time changed.
+func (p *GoParser) fileDecls(path string, info os.FileInfo) *goFileDecls {
+	p.declMu.Lock()
+	cached, ok := p.decls[path]
+	p.declMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	parser := sitter.NewParser()
+	parser.SetLanguage(golang.GetLanguage())
+	tree, err := parser.ParseCtx(context.Background(), nil, content)
+	if err != nil {
+		return nil
+	}
+	defer tree.Close()
+
+	fd := &goFileDecls{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		pkg:     goPackageName(tree.RootNode(), content),
+		decls:   collectGoDecls(tree.RootNode(), content),
+	}
+	p.declMu.Lock()
+	if p.decls == nil {
+		p.decls = make(map[string]*goFileDecls)
+	}
+	p.decls[path] = fd
+	p.declMu.Unlock()
+	return fd
+}
@@ -0,0 +1,193 @@
+package analysis_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"graphdb/internal/analysis"
+	"graphdb/internal/graph"
+)
+
+func parseGoFixture(t *testing.T, rel string) ([]*graph.Node, []*graph.Edge) {
+	t.Helper()
+	parser, ok := analysis.GetParser(".go")
+	if !ok {
+		t.Fatalf("Go parser not registered")
+	}
+
+	absPath, err := filepath.Abs(filepath.Join("../../test/fixtures/golang", rel))
+	if err != nil {
+		t.Fatalf("Failed to get absolute path: %v", err)
+	}
+
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	nodes, edges, err := parser.Parse(absPath, content)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return nodes, edges
+}
+
+func TestParseGo_Definitions(t *testing.T) {
+	nodes, edges := parseGoFixture(t, "geometry/geometry.go")
+
+	findNode := func(id, label string) *graph.Node {
+		for _, n := range nodes {
+			if n.ID == id && n.Label == label {
+				return n
+			}
+		}
+		return nil
+	}
+	hasEdge := func(src, tgt, edgeType string) bool {
+		for _, e := range edges {
+			if e.SourceID == src && e.TargetID == tgt && e.Type == edgeType {
+				return true
+			}
+		}
+		return false
+	}
+
+	const pkg = "example.com/shapes/geometry"
+
+	// 1. Structure
+	if findNode(pkg+":Round", "Function") == nil {
+		t.Errorf("Expected Function %s:Round not found", pkg)
+	}
+	area := findNode(pkg+".Circle:Area", "Function")
+	if area == nil {
+		t.Fatalf("Expected method %s.Circle:Area not found", pkg)
+	}
+	if area.Properties["receiver"] != "Circle" {
+		t.Errorf("Expected receiver 'Circle', got %v", area.Properties["receiver"])
+	}
+	if n := findNode(pkg+".Circle", "Class"); n == nil || n.Properties["kind"] != "struct" {
+		t.Errorf("Expected struct Class %s.Circle", pkg)
+	}
+	if n := findNode(pkg+".Shape", "Class"); n == nil || n.Properties["kind"] != "interface" {
+		t.Errorf("Expected interface Class %s.Shape", pkg)
+	}
+	if findNode(pkg+":Precision", "Global") == nil {
+		t.Errorf("Expected Global %s:Precision not found", pkg)
+	}
+	if findNode(pkg+":Unit", "Global") == nil {
+		t.Errorf("Expected Global %s:Unit not found", pkg)
+	}
+	if findNode(pkg+":scale", "Global") != nil {
+		t.Errorf("Local variable 'scale' should not be a Global")
+	}
+
+	foundFile := false
+	for _, n := range nodes {
+		if n.Label == "File" {
+			foundFile = true
+		}
+	}
+	if !foundFile {
+		t.Errorf("Expected File node not found")
+	}
+
+	// 2. Edges
+	if !hasEdge(pkg+".Circle", pkg+".Circle:Area", "HAS_METHOD") {
+		t.Errorf("Missing HAS_METHOD edge Circle -> Area")
+	}
+	if !hasEdge(pkg+".Circle", pkg+".Shape", "IMPLEMENTS") {
+		t.Errorf("Missing IMPLEMENTS edge Circle -> Shape")
+	}
+	if !hasEdge(pkg+".Circle:Area", pkg+":Round", "CALLS") {
+		t.Errorf("Missing CALLS edge Circle.Area -> Round")
+	}
+	if !hasEdge(pkg+":Round", "math:Pow", "CALLS") {
+		t.Errorf("Missing CALLS edge Round -> math:Pow")
+	}
+	if !hasEdge(pkg+":Round", pkg+":Precision", "USES_GLOBAL") {
+		t.Errorf("Missing USES_GLOBAL edge Round -> Precision")
+	}
+	if !hasEdge(pkg+".Circle:Area", "math:Pi", "USES_GLOBAL") {
+		t.Errorf("Missing USES_GLOBAL edge Circle.Area -> math:Pi")
+	}
+}
+
+func TestParseGo_CrossPackageResolution(t *testing.T) {
+	_, edges := parseGoFixture(t, "main.go")
+
+	hasEdge := func(src, tgt, edgeType string) bool {
+		for _, e := range edges {
+			if e.SourceID == src && e.TargetID == tgt && e.Type == edgeType {
+				return true
+			}
+		}
+		return false
+	}
+
+	const mainPkg = "example.com/shapes"
+	const geoPkg = "example.com/shapes/geometry"
+
+	// Aliased import resolves to the module import path
+	if !hasEdge(mainPkg+":main", geoPkg+":Round", "CALLS") {
+		t.Errorf("Missing CALLS edge main -> %s:Round", geoPkg)
+	}
+	// Method call on a local variable with an inferred type
+	if !hasEdge(mainPkg+":main", geoPkg+".Circle:Area", "CALLS") {
+		t.Errorf("Missing CALLS edge main -> %s.Circle:Area", geoPkg)
+	}
+	// Method call through a typed parameter
+	if !hasEdge(mainPkg+":describe", geoPkg+".Shape:Name", "CALLS") {
+		t.Errorf("Missing CALLS edge describe -> %s.Shape:Name", geoPkg)
+	}
+	if !hasEdge(mainPkg+":main", mainPkg+":describe", "CALLS") {
+		t.Errorf("Missing CALLS edge main -> describe")
+	}
+	if !hasEdge(mainPkg+":describe", geoPkg+":Unit", "USES_GLOBAL") {
+		t.Errorf("Missing USES_GLOBAL edge describe -> %s:Unit", geoPkg)
+	}
+
+	for _, e := range edges {
+		if e.Type == "CALLS" && (e.TargetID == mainPkg+":Println" || e.TargetID == mainPkg+":len") {
+			t.Errorf("Unexpected unresolved CALLS edge to %s", e.TargetID)
+		}
+	}
+}
+
+func TestParseGo_PackageAcrossFiles(t *testing.T) {
+	_, edges := parseGoFixture(t, "geometry/square.go")
+
+	hasEdge := func(src, tgt, edgeType string) bool {
+		for _, e := range edges {
+			if e.SourceID == src && e.TargetID == tgt && e.Type == edgeType {
+				return true
+			}
+		}
+		return false
+	}
+
+	const pkg = "example.com/shapes/geometry"
+
+	// Shape is declared in geometry.go and Square's Name method in units.go
+	if !hasEdge(pkg+".Square", pkg+".Shape", "IMPLEMENTS") {
+		t.Errorf("Missing IMPLEMENTS edge Square -> Shape")
+	}
+	if !hasEdge(pkg+":Resize", pkg+":Precision", "USES_GLOBAL") {
+		t.Errorf("Missing USES_GLOBAL edge Resize -> Precision")
+	}
+	// Meters is a conversion to a type of units.go, grow the parameter
+	for _, target := range []string{pkg + ":Meters", pkg + ":grow"} {
+		if hasEdge(pkg+":Resize", target, "CALLS") {
+			t.Errorf("Unexpected CALLS edge Resize -> %s", target)
+		}
+	}
+	if !hasEdge(pkg+".Square:Area", pkg+":Round", "CALLS") {
+		t.Errorf("Missing CALLS edge Square.Area -> Round")
+	}
+
+	// units.go does not declare Square, so it leaves the IMPLEMENTS edge to square.go
+	_, edges = parseGoFixture(t, "geometry/units.go")
+	if hasEdge(pkg+".Square", pkg+".Shape", "IMPLEMENTS") {
+		t.Errorf("Expected the IMPLEMENTS edge only from the file declaring Square")
+	}
+}
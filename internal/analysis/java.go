@@ -130,14 +130,16 @@ func (p *JavaParser) Parse(filePath string, content []byte) ([]*graph.Node, []*g
 					id = fmt.Sprintf("%s.%s", packageName, nodeContent)
 				}
 				
+				properties := map[string]interface{}{
+					"name": nodeContent,
+					"file": filePath,
+					"line": int(c.Node.StartPoint().Row + 1),
+				}
+				setSourceSpan(properties, c.Node.Parent(), content)
 				nodes = append(nodes, &graph.Node{
-					ID:    id,
-					Label: label,
-					Properties: map[string]interface{}{
-						"name": nodeContent,
-						"file": filePath,
-						"line": int(c.Node.StartPoint().Row + 1),
-					},
+					ID:         id,
+					Label:      label,
+					Properties: properties,
 				})
 
 			case "class.extends", "class.implements":
@@ -185,14 +187,16 @@ func (p *JavaParser) Parse(filePath string, content []byte) ([]*graph.Node, []*g
 					
 					methodID := fmt.Sprintf("%s:%s", classID, nodeContent)
 					
+					properties := map[string]interface{}{
+						"name": nodeContent,
+						"file": filePath,
+						"line": int(c.Node.StartPoint().Row + 1),
+					}
+					setSourceSpan(properties, c.Node.Parent(), content)
 					nodes = append(nodes, &graph.Node{
-						ID:    methodID,
-						Label: "Function",
-						Properties: map[string]interface{}{
-							"name": nodeContent,
-							"file": filePath,
-							"line": int(c.Node.StartPoint().Row + 1),
-						},
+						ID:         methodID,
+						Label:      "Function",
+						Properties: properties,
 					})
 					
 					edges = append(edges, &graph.Edge{
@@ -206,10 +210,12 @@ func (p *JavaParser) Parse(filePath string, content []byte) ([]*graph.Node, []*g
 
 		// Handle Fields specifically within the match to pair name and type
 		var fieldName, fieldType string
+		var fieldNode *sitter.Node
 		for _, c := range m.Captures {
 			name := qDef.CaptureNameForId(c.Index)
 			if name == "field.name" {
 				fieldName = c.Node.Content(content)
+				fieldNode = c.Node
 			}
 			if name == "field.type" {
 				fieldType = c.Node.Content(content)
@@ -236,14 +242,17 @@ func (p *JavaParser) Parse(filePath string, content []byte) ([]*graph.Node, []*g
 				
 				// Create Field Node
 				fieldID := fmt.Sprintf("%s:%s", classID, fieldName)
+				properties := map[string]interface{}{
+					"name": fieldName,
+					"type": resolvedType,
+					"file": filePath,
+					"line": int(fieldNode.StartPoint().Row + 1),
+				}
+				setSourceSpan(properties, findDefinitionNode(fieldNode, "field_declaration"), content)
 				nodes = append(nodes, &graph.Node{
 					ID: fieldID,
 					Label: "Field",
-					Properties: map[string]interface{}{
-						"name": fieldName,
-						"type": resolvedType,
-						"file": filePath,
-					},
+					Properties: properties,
 				})
 				
 				edges = append(edges, &graph.Edge{
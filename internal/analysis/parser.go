@@ -1,12 +1,22 @@
 package analysis
 
-import "graphdb/internal/graph"
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+	"graphdb/internal/graph"
+)
 
 // LanguageParser defines the interface for parsing source code files.
 type LanguageParser interface {
 	Parse(filePath string, content []byte) ([]*graph.Node, []*graph.Edge, error)
 }
 
+// RootedParser is implemented by parsers that resolve imports to other files
+// on disk. ParseInRoot confines that lookup to rootDir, the directory being
+// ingested, so that imports never resolve to files outside of it.
+type RootedParser interface {
+	ParseInRoot(rootDir, filePath string, content []byte) ([]*graph.Node, []*graph.Edge, error)
+}
+
 var parsers = make(map[string]LanguageParser)
 
 // RegisterParser registers a parser for a specific file extension (e.g., ".go").
@@ -19,3 +29,24 @@ func GetParser(ext string) (LanguageParser, bool) {
 	p, ok := parsers[ext]
 	return p, ok
 }
+
+// setSourceSpan records the full line span and source text of a definition node
+// so that source lookups and enrichment do not need to re-read the file.
+func setSourceSpan(properties map[string]interface{}, n *sitter.Node, content []byte) {
+	properties["start_line"] = int(n.StartPoint().Row) + 1
+	properties["end_line"] = int(n.EndPoint().Row) + 1
+	properties["content"] = n.Content(content)
+}
+
+// findDefinitionNode walks up from a captured name to the nearest node of one of
+// the given types, falling back to the name itself if none encloses it.
+func findDefinitionNode(n *sitter.Node, types ...string) *sitter.Node {
+	for curr := n; curr != nil; curr = curr.Parent() {
+		for _, t := range types {
+			if curr.Type() == t {
+				return curr
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,785 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/python"
+	"graphdb/internal/graph"
+)
+
+// PythonParser parses Python source files.
+//
+// Definitions use file-based IDs qualified by their enclosing scope
+// (e.g. "pkg/models.py:User.save"). The module itself is emitted as the
+// File node, so imports of a module resolve to its file path and imports
+// of a symbol resolve to "<file>:<symbol>".
+//
+// Absolute imports are looked up from each ancestor directory of the file,
+// up to the ingest root when parsing with ParseInRoot.
+type PythonParser struct{}
+
+func init() {
+	RegisterParser(".py", &PythonParser{})
+}
+
+// pythonBuiltins are builtin callables that never resolve to graph nodes.
+var pythonBuiltins = map[string]bool{
+	"abs": true, "all": true, "any": true, "bool": true, "bytes": true, "callable": true,
+	"dict": true, "dir": true, "enumerate": true, "filter": true, "float": true,
+	"format": true, "getattr": true, "hasattr": true, "hash": true, "id": true,
+	"input": true, "int": true, "isinstance": true, "issubclass": true, "iter": true,
+	"len": true, "list": true, "map": true, "max": true, "min": true, "next": true,
+	"object": true, "open": true, "print": true, "range": true, "repr": true,
+	"reversed": true, "round": true, "set": true, "setattr": true, "sorted": true,
+	"str": true, "sum": true, "super": true, "tuple": true, "type": true, "vars": true,
+	"zip": true, "staticmethod": true, "classmethod": true, "property": true,
+}
+
+func (p *PythonParser) Parse(filePath string, content []byte) ([]*graph.Node, []*graph.Edge, error) {
+	return p.ParseInRoot("", filePath, content)
+}
+
+// ParseInRoot parses a file whose absolute imports resolve to files under
+// rootDir only. An empty rootDir bounds nothing.
+func (p *PythonParser) ParseInRoot(rootDir, filePath string, content []byte) ([]*graph.Node, []*graph.Edge, error) {
+	parser := sitter.NewParser()
+	parser.SetLanguage(python.GetLanguage())
+
+	tree, err := parser.ParseCtx(context.Background(), nil, content)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tree.Close()
+
+	root := tree.RootNode()
+
+	var nodes []*graph.Node
+	var edges []*graph.Edge
+	seenEdges := make(map[string]bool)
+	addEdge := func(source, target, edgeType string) {
+		key := source + "|" + target + "|" + edgeType
+		if seenEdges[key] {
+			return
+		}
+		seenEdges[key] = true
+		edges = append(edges, &graph.Edge{SourceID: source, TargetID: target, Type: edgeType})
+	}
+
+	nodes = append(nodes, &graph.Node{
+		ID:    filePath,
+		Label: "File",
+		Properties: map[string]interface{}{
+			"name":   filePath,
+			"file":   filePath,
+			"lang":   "python",
+			"module": pythonModuleName(filePath),
+		},
+	})
+
+	// Map of local name (possibly dotted, e.g. "os.path") -> resolved target ID
+	imports := make(map[string]string)
+	// Locally defined top-level names -> ID
+	localDefs := make(map[string]string)
+	globals := make(map[string]bool)
+
+	// 1. Import Query
+	importQueryStr := `
+		(import_statement) @import.plain
+		(import_from_statement) @import.from
+	`
+	qImport, err := sitter.NewQuery([]byte(importQueryStr), python.GetLanguage())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid import query: %w", err)
+	}
+	defer qImport.Close()
+
+	qcImport := sitter.NewQueryCursor()
+	defer qcImport.Close()
+	qcImport.Exec(qImport, root)
+
+	for {
+		m, ok := qcImport.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, c := range m.Captures {
+			stmt := c.Node
+			switch qImport.CaptureNameForId(c.Index) {
+			case "import.plain":
+				// import a.b, c as d
+				count := int(stmt.ChildCount())
+				for i := 0; i < count; i++ {
+					if stmt.FieldNameForChild(i) != "name" {
+						continue
+					}
+					child := stmt.Child(i)
+					moduleName, alias := pythonImportName(child, content)
+					resolved := resolvePyPath(rootDir, filePath, moduleName, 0)
+					if alias != "" {
+						imports[alias] = resolved
+					} else {
+						imports[moduleName] = resolved
+					}
+					addEdge(filePath, resolved, "IMPORTS")
+				}
+
+			case "import.from":
+				// from .a.b import c as d
+				moduleNode := stmt.ChildByFieldName("module_name")
+				if moduleNode == nil {
+					continue
+				}
+				moduleName, level := pythonRelativeModule(moduleNode, content)
+				moduleID := resolvePyPath(rootDir, filePath, moduleName, level)
+				addEdge(filePath, moduleID, "IMPORTS")
+
+				count := int(stmt.ChildCount())
+				for i := 0; i < count; i++ {
+					if stmt.FieldNameForChild(i) != "name" {
+						continue
+					}
+					remoteName, alias := pythonImportName(stmt.Child(i), content)
+					if alias == "" {
+						alias = remoteName
+					}
+
+					// "from pkg import sub" may name a submodule rather than a symbol
+					subModule := moduleName + "." + remoteName
+					if moduleName == "" {
+						subModule = remoteName
+					}
+					if subPath, ok := findPyModuleFile(rootDir, filePath, subModule, level); ok {
+						imports[alias] = subPath
+					} else {
+						imports[alias] = fmt.Sprintf("%s:%s", moduleID, remoteName)
+					}
+				}
+			}
+		}
+	}
+
+	// 2. Definition Query
+	defQueryStr := `
+		(class_definition name: (identifier) @class.name) @class.def
+		(function_definition name: (identifier) @function.name) @function.def
+		(module (expression_statement (assignment left: (identifier) @global.name)))
+	`
+	qDef, err := sitter.NewQuery([]byte(defQueryStr), python.GetLanguage())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid definition query: %w", err)
+	}
+	defer qDef.Close()
+
+	qcDef := sitter.NewQueryCursor()
+	defer qcDef.Close()
+	qcDef.Exec(qDef, root)
+
+	var classDefs []*sitter.Node
+
+	for {
+		m, ok := qcDef.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, c := range m.Captures {
+			captureName := qDef.CaptureNameForId(c.Index)
+			if !strings.HasSuffix(captureName, ".name") {
+				continue
+			}
+
+			nodeName := c.Node.Content(content)
+
+			if captureName == "global.name" {
+				id := fmt.Sprintf("%s:%s", filePath, nodeName)
+				globals[nodeName] = true
+				localDefs[nodeName] = id
+				properties := map[string]interface{}{
+					"name": nodeName,
+					"file": filePath,
+					"line": c.Node.StartPoint().Row + 1,
+				}
+				setSourceSpan(properties, c.Node.Parent(), content)
+				nodes = append(nodes, &graph.Node{
+					ID:         id,
+					Label:      "Global",
+					Properties: properties,
+				})
+				continue
+			}
+
+			def := c.Node.Parent()
+			qualified := pythonQualifiedName(def, content)
+			id := fmt.Sprintf("%s:%s", filePath, qualified)
+
+			properties := map[string]interface{}{
+				"name": nodeName,
+				"file": filePath,
+				"line": c.Node.StartPoint().Row + 1,
+			}
+			if qualified != nodeName {
+				properties["qualified_name"] = qualified
+			}
+
+			decorators := pythonDecorators(def, content)
+			if len(decorators) > 0 {
+				properties["decorators"] = decorators
+				// Decorators are part of the definition's source
+				setSourceSpan(properties, def.Parent(), content)
+			} else {
+				setSourceSpan(properties, def, content)
+			}
+
+			label := "Function"
+			if captureName == "class.name" {
+				label = "Class"
+				classDefs = append(classDefs, def)
+			} else if cls := findEnclosingPythonScope(def, "class_definition"); cls != nil && pythonDirectChild(cls, def) {
+				properties["class"] = pythonQualifiedName(cls, content)
+				addEdge(fmt.Sprintf("%s:%s", filePath, pythonQualifiedName(cls, content)), id, "HAS_METHOD")
+			}
+
+			if findEnclosingPythonScope(def, "") == nil {
+				localDefs[nodeName] = id
+			}
+
+			nodes = append(nodes, &graph.Node{
+				ID:         id,
+				Label:      label,
+				Properties: properties,
+			})
+
+			for _, d := range decorators {
+				if pythonBuiltins[d] {
+					continue
+				}
+				addEdge(id, resolvePyTarget(d, imports, localDefs, filePath), "DECORATED_BY")
+			}
+		}
+	}
+
+	// 3. Inheritance
+	for _, def := range classDefs {
+		supers := def.ChildByFieldName("superclasses")
+		if supers == nil {
+			continue
+		}
+		sourceID := fmt.Sprintf("%s:%s", filePath, pythonQualifiedName(def, content))
+		count := int(supers.NamedChildCount())
+		for i := 0; i < count; i++ {
+			base := supers.NamedChild(i)
+			if base.Type() != "identifier" && base.Type() != "attribute" {
+				continue // keyword arguments such as metaclass=...
+			}
+			baseName := base.Content(content)
+			if baseName == "object" {
+				continue
+			}
+			addEdge(sourceID, resolvePyTarget(baseName, imports, localDefs, filePath), "INHERITS")
+		}
+	}
+
+	// 4. Reference/Call Query
+	refQueryStr := `
+		(call function: (identifier) @call.target) @call.site
+		(call function: (attribute object: (_) @call.scope attribute: (identifier) @call.target)) @call.site
+		(identifier) @ref.ident
+	`
+	qRef, err := sitter.NewQuery([]byte(refQueryStr), python.GetLanguage())
+	if err != nil {
+		return nodes, edges, fmt.Errorf("invalid reference query: %w", err)
+	}
+	defer qRef.Close()
+
+	qcRef := sitter.NewQueryCursor()
+	defer qcRef.Close()
+	qcRef.Exec(qRef, root)
+
+	scopes := &pythonScopes{content: content, filePath: filePath, cache: make(map[uint32]*pythonScope)}
+
+	for {
+		m, ok := qcRef.NextMatch()
+		if !ok {
+			break
+		}
+
+		var targetName, scopeName string
+		var siteNode, identNode *sitter.Node
+
+		for _, c := range m.Captures {
+			switch qRef.CaptureNameForId(c.Index) {
+			case "call.target":
+				targetName = c.Node.Content(content)
+			case "call.scope":
+				scopeName = c.Node.Content(content)
+			case "call.site":
+				siteNode = c.Node
+			case "ref.ident":
+				identNode = c.Node
+			}
+		}
+
+		if identNode != nil {
+			name := identNode.Content(content)
+			if !globals[name] || isPythonMemberName(identNode) {
+				continue
+			}
+			fn := findEnclosingPythonScope(identNode, "function_definition")
+			if fn == nil {
+				continue
+			}
+			if _, bound := scopes.binding(identNode, name); bound {
+				continue // A local, parameter or comprehension variable
+			}
+			sourceID := fmt.Sprintf("%s:%s", filePath, pythonQualifiedName(fn, content))
+			addEdge(sourceID, fmt.Sprintf("%s:%s", filePath, name), "USES_GLOBAL")
+			continue
+		}
+
+		if targetName == "" || siteNode == nil {
+			continue
+		}
+		fn := findEnclosingPythonScope(siteNode, "function_definition")
+		if fn == nil {
+			continue
+		}
+		sourceID := fmt.Sprintf("%s:%s", filePath, pythonQualifiedName(fn, content))
+
+		var targetID string
+		switch {
+		case scopeName == "":
+			if id, bound := scopes.binding(siteNode, targetName); bound {
+				if id == "" {
+					continue // Calls a local variable or parameter
+				}
+				targetID = id // A nested function or class
+				break
+			}
+			if pythonBuiltins[targetName] {
+				continue
+			}
+			targetID = resolvePyTarget(targetName, imports, localDefs, filePath)
+		case scopeName == "self" || scopeName == "cls":
+			cls := findEnclosingPythonScope(fn, "class_definition")
+			if cls == nil {
+				continue
+			}
+			targetID = fmt.Sprintf("%s:%s.%s", filePath, pythonQualifiedName(cls, content), targetName)
+		default:
+			resolved, ok := imports[scopeName]
+			if !ok {
+				if classID, isLocal := localDefs[scopeName]; isLocal {
+					// ClassName.method()
+					resolved = classID
+					targetID = fmt.Sprintf("%s.%s", resolved, targetName)
+					break
+				}
+				continue // Attribute call on a value of unknown type
+			}
+			targetID = fmt.Sprintf("%s:%s", resolved, targetName)
+		}
+
+		addEdge(sourceID, targetID, "CALLS")
+	}
+
+	return nodes, edges, nil
+}
+
+// pythonImportName returns the module (or symbol) name and optional alias of a
+// dotted_name or aliased_import node.
+func pythonImportName(n *sitter.Node, content []byte) (string, string) {
+	if n.Type() == "aliased_import" {
+		name := n.ChildByFieldName("name")
+		alias := n.ChildByFieldName("alias")
+		if name == nil {
+			return "", ""
+		}
+		if alias == nil {
+			return name.Content(content), ""
+		}
+		return name.Content(content), alias.Content(content)
+	}
+	return n.Content(content), ""
+}
+
+// pythonRelativeModule splits a from-import module into its dotted name and
+// relative level (number of leading dots).
+func pythonRelativeModule(n *sitter.Node, content []byte) (string, int) {
+	text := n.Content(content)
+	level := len(text) - len(strings.TrimLeft(text, "."))
+	return strings.TrimLeft(text, "."), level
+}
+
+// resolvePyPath maps a module reference to its file-based ID. Relative imports
+// (level > 0) are resolved against the importing file's package; absolute
+// imports are looked up from each ancestor directory up to root, which covers
+// both repository-root and src/ layouts. Modules that cannot be found there
+// (stdlib, third-party) keep their dotted name.
+func resolvePyPath(root, currentFile, moduleName string, level int) string {
+	if path, ok := findPyModuleFile(root, currentFile, moduleName, level); ok {
+		return path
+	}
+	if level > 0 {
+		base := pyRelativeBase(currentFile, level)
+		if moduleName == "" {
+			return filepath.Join(base, "__init__.py")
+		}
+		return filepath.Join(base, filepath.FromSlash(strings.ReplaceAll(moduleName, ".", "/"))) + ".py"
+	}
+	return moduleName
+}
+
+// findPyModuleFile looks for <module>.py or <module>/__init__.py on disk. An
+// absolute import is searched for from the file's directory up to root, or up
+// to the filesystem root if root is empty.
+func findPyModuleFile(root, currentFile, moduleName string, level int) (string, bool) {
+	rel := filepath.FromSlash(strings.ReplaceAll(moduleName, ".", "/"))
+
+	var bases []string
+	if level > 0 {
+		bases = []string{pyRelativeBase(currentFile, level)}
+	} else {
+		dir := filepath.Dir(currentFile)
+		for {
+			bases = append(bases, dir)
+			parent := filepath.Dir(dir)
+			if parent == dir || (root != "" && !pyWithin(root, parent)) {
+				break
+			}
+			dir = parent
+		}
+	}
+
+	for _, base := range bases {
+		if moduleName == "" {
+			candidate := filepath.Join(base, "__init__.py")
+			if fileExists(candidate) {
+				return candidate, true
+			}
+			continue
+		}
+		candidate := filepath.Join(base, rel) + ".py"
+		if fileExists(candidate) {
+			return candidate, true
+		}
+		candidate = filepath.Join(base, rel, "__init__.py")
+		if fileExists(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// pyWithin reports whether dir is root or one of its subdirectories.
+func pyWithin(root, dir string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absDir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func pyRelativeBase(currentFile string, level int) string {
+	base := filepath.Dir(currentFile)
+	for i := 1; i < level; i++ {
+		base = filepath.Dir(base)
+	}
+	return base
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// pythonModuleName derives a dotted module name from a file path.
+func pythonModuleName(filePath string) string {
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	if name == "__init__" {
+		return filepath.Base(filepath.Dir(filePath))
+	}
+	return name
+}
+
+// resolvePyTarget resolves a (possibly dotted) symbol against imports and
+// top-level definitions, defaulting to a symbol in the current file.
+func resolvePyTarget(symbol string, imports map[string]string, localDefs map[string]string, currentFile string) string {
+	if resolved, ok := imports[symbol]; ok {
+		return resolved
+	}
+	if id, ok := localDefs[symbol]; ok {
+		return id
+	}
+	if idx := strings.LastIndex(symbol, "."); idx != -1 {
+		if resolved, ok := imports[symbol[:idx]]; ok {
+			return fmt.Sprintf("%s:%s", resolved, symbol[idx+1:])
+		}
+	}
+	return fmt.Sprintf("%s:%s", currentFile, symbol)
+}
+
+// pythonDecorators lists decorator expressions (without "@" or call arguments)
+// applied to a class or function definition.
+func pythonDecorators(def *sitter.Node, content []byte) []string {
+	parent := def.Parent()
+	if parent == nil || parent.Type() != "decorated_definition" {
+		return nil
+	}
+	var decorators []string
+	count := int(parent.NamedChildCount())
+	for i := 0; i < count; i++ {
+		child := parent.NamedChild(i)
+		if child.Type() != "decorator" || child.NamedChildCount() == 0 {
+			continue
+		}
+		expr := child.NamedChild(0)
+		if expr.Type() == "call" {
+			if fn := expr.ChildByFieldName("function"); fn != nil {
+				expr = fn
+			}
+		}
+		decorators = append(decorators, expr.Content(content))
+	}
+	return decorators
+}
+
+// pythonQualifiedName returns the dotted name of a definition including its
+// enclosing classes and functions (e.g. "Outer.Inner.method").
+func pythonQualifiedName(def *sitter.Node, content []byte) string {
+	var parts []string
+	for curr := def; curr != nil; curr = curr.Parent() {
+		if curr.Type() == "class_definition" || curr.Type() == "function_definition" {
+			if name := curr.ChildByFieldName("name"); name != nil {
+				parts = append([]string{name.Content(content)}, parts...)
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// findEnclosingPythonScope returns the nearest enclosing definition of the
+// given type, or of either type when nodeType is empty.
+func findEnclosingPythonScope(n *sitter.Node, nodeType string) *sitter.Node {
+	curr := n.Parent()
+	for curr != nil {
+		t := curr.Type()
+		if (nodeType == "" && (t == "class_definition" || t == "function_definition")) || t == nodeType {
+			return curr
+		}
+		curr = curr.Parent()
+	}
+	return nil
+}
+
+// pythonDirectChild reports whether def is declared directly in the body of cls
+// (as opposed to inside one of its methods).
+func pythonDirectChild(cls, def *sitter.Node) bool {
+	return findEnclosingPythonScope(def, "") == cls
+}
+
+// pythonScope holds the names a function binds: its parameters, assigned
+// variables, imports and nested definitions, less those it declares global.
+type pythonScope struct {
+	locals  map[string]string // Name -> ID of the nested definition it names, or "" for a variable
+	globals map[string]bool   // Names declared with "global"
+}
+
+// pythonScopes finds what a name refers to inside the functions of a file,
+// caching the scope of each function.
+type pythonScopes struct {
+	content  []byte
+	filePath string
+	cache    map[uint32]*pythonScope // Function start byte -> scope
+}
+
+// pythonComprehensions are the expressions whose for clauses bind variables
+// of their own.
+var pythonComprehensions = map[string]bool{
+	"list_comprehension": true, "set_comprehension": true,
+	"dictionary_comprehension": true, "generator_expression": true,
+}
+
+// binding reports whether name, used at n, is bound by an enclosing function,
+// lambda or comprehension rather than being a module-level name. id is the ID
+// of the nested definition it names, or "" for a variable or parameter.
+func (s *pythonScopes) binding(n *sitter.Node, name string) (id string, bound bool) {
+	for curr := n.Parent(); curr != nil; curr = curr.Parent() {
+		switch t := curr.Type(); {
+		case pythonComprehensions[t]:
+			count := int(curr.NamedChildCount())
+			for i := 0; i < count; i++ {
+				clause := curr.NamedChild(i)
+				if clause.Type() != "for_in_clause" {
+					continue
+				}
+				names := make(map[string]string)
+				pythonBindTarget(clause.ChildByFieldName("left"), s.content, names)
+				if _, ok := names[name]; ok {
+					return "", true
+				}
+			}
+		case t == "lambda":
+			names := make(map[string]string)
+			pythonBindParameters(curr.ChildByFieldName("parameters"), s.content, names)
+			if _, ok := names[name]; ok {
+				return "", true
+			}
+		case t == "function_definition":
+			scope := s.scope(curr)
+			if scope.globals[name] {
+				return "", false
+			}
+			if id, ok := scope.locals[name]; ok {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (s *pythonScopes) scope(fn *sitter.Node) *pythonScope {
+	if scope, ok := s.cache[fn.StartByte()]; ok {
+		return scope
+	}
+	scope := &pythonScope{locals: make(map[string]string), globals: make(map[string]bool)}
+	pythonBindParameters(fn.ChildByFieldName("parameters"), s.content, scope.locals)
+
+	var visit func(n *sitter.Node)
+	visit = func(n *sitter.Node) {
+		switch n.Type() {
+		case "function_definition", "class_definition":
+			// Nested definitions bind their name; their bodies are scopes of their own
+			if name := n.ChildByFieldName("name"); name != nil {
+				scope.locals[name.Content(s.content)] = fmt.Sprintf("%s:%s", s.filePath, pythonQualifiedName(n, s.content))
+			}
+			return
+		case "lambda":
+			return
+		case "assignment", "augmented_assignment", "for_statement", "for_in_clause":
+			pythonBindTarget(n.ChildByFieldName("left"), s.content, scope.locals)
+		case "named_expression":
+			pythonBindTarget(n.ChildByFieldName("name"), s.content, scope.locals)
+		case "as_pattern":
+			if alias := n.ChildByFieldName("alias"); alias != nil {
+				pythonBindTarget(alias, s.content, scope.locals)
+			}
+		case "except_clause":
+			// except E as e
+			count := int(n.ChildCount())
+			for i := 0; i+1 < count; i++ {
+				if n.Child(i).Type() == "as" {
+					pythonBindTarget(n.Child(i+1), s.content, scope.locals)
+				}
+			}
+		case "import_statement", "import_from_statement":
+			count := int(n.ChildCount())
+			for i := 0; i < count; i++ {
+				if n.FieldNameForChild(i) != "name" {
+					continue
+				}
+				name, alias := pythonImportName(n.Child(i), s.content)
+				if alias == "" {
+					// "import a.b" binds a
+					alias = strings.SplitN(name, ".", 2)[0]
+				}
+				scope.locals[alias] = ""
+			}
+			return
+		case "global_statement":
+			count := int(n.NamedChildCount())
+			for i := 0; i < count; i++ {
+				scope.globals[n.NamedChild(i).Content(s.content)] = true
+			}
+			return
+		case "nonlocal_statement":
+			count := int(n.NamedChildCount())
+			for i := 0; i < count; i++ {
+				scope.locals[n.NamedChild(i).Content(s.content)] = ""
+			}
+			return
+		}
+		if pythonComprehensions[n.Type()] {
+			return // Its variables are its own
+		}
+		count := int(n.NamedChildCount())
+		for i := 0; i < count; i++ {
+			visit(n.NamedChild(i))
+		}
+	}
+	if body := fn.ChildByFieldName("body"); body != nil {
+		visit(body)
+	}
+	for name := range scope.globals {
+		delete(scope.locals, name)
+	}
+
+	s.cache[fn.StartByte()] = scope
+	return scope
+}
+
+// pythonBindParameters adds the names of a parameter list to names.
+func pythonBindParameters(params *sitter.Node, content []byte, names map[string]string) {
+	if params == nil {
+		return
+	}
+	count := int(params.NamedChildCount())
+	for i := 0; i < count; i++ {
+		param := params.NamedChild(i)
+		switch param.Type() {
+		case "default_parameter", "typed_default_parameter":
+			pythonBindTarget(param.ChildByFieldName("name"), content, names)
+		case "typed_parameter":
+			if param.NamedChildCount() > 0 {
+				pythonBindTarget(param.NamedChild(0), content, names)
+			}
+		default:
+			pythonBindTarget(param, content, names)
+		}
+	}
+}
+
+// pythonBindTarget adds the variables an assignment target binds to names:
+// identifiers and the elements of tuple, list and star patterns, but not
+// attributes or subscripts, which bind nothing.
+func pythonBindTarget(target *sitter.Node, content []byte, names map[string]string) {
+	if target == nil {
+		return
+	}
+	switch target.Type() {
+	case "identifier":
+		names[target.Content(content)] = ""
+	case "pattern_list", "tuple_pattern", "list_pattern", "expression_list", "tuple", "list",
+		"list_splat_pattern", "dictionary_splat_pattern", "list_splat", "parenthesized_expression", "as_pattern_target":
+		count := int(target.NamedChildCount())
+		for i := 0; i < count; i++ {
+			pythonBindTarget(target.NamedChild(i), content, names)
+		}
+	}
+}
+
+// isPythonMemberName reports whether ident names an attribute (obj.name) or a
+// keyword argument (f(name=...)) rather than a variable.
+func isPythonMemberName(ident *sitter.Node) bool {
+	parent := ident.Parent()
+	if parent == nil {
+		return false
+	}
+	switch parent.Type() {
+	case "attribute":
+		return parent.ChildByFieldName("attribute") == ident
+	case "keyword_argument":
+		return parent.ChildByFieldName("name") == ident
+	}
+	return false
+}
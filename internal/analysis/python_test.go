@@ -0,0 +1,245 @@
+package analysis_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"graphdb/internal/analysis"
+	"graphdb/internal/graph"
+)
+
+func parsePythonFixture(t *testing.T, rel string) (string, []*graph.Node, []*graph.Edge) {
+	t.Helper()
+	parser, ok := analysis.GetParser(".py")
+	if !ok {
+		t.Fatalf("Python parser not registered")
+	}
+
+	root, err := filepath.Abs("../../test/fixtures/python")
+	if err != nil {
+		t.Fatalf("Failed to get absolute path: %v", err)
+	}
+
+	absPath := filepath.Join(root, rel)
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	nodes, edges, err := parser.Parse(absPath, content)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return root, nodes, edges
+}
+
+func TestParsePython_Definitions(t *testing.T) {
+	root, nodes, edges := parsePythonFixture(t, "shop/models/order.py")
+
+	findNode := func(id, label string) *graph.Node {
+		for _, n := range nodes {
+			if n.ID == id && n.Label == label {
+				return n
+			}
+		}
+		return nil
+	}
+	hasEdge := func(src, tgt, edgeType string) bool {
+		for _, e := range edges {
+			if e.SourceID == src && e.TargetID == tgt && e.Type == edgeType {
+				return true
+			}
+		}
+		return false
+	}
+
+	file := filepath.Join(root, "shop/models/order.py")
+	base := filepath.Join(root, "shop/models/base.py")
+	config := filepath.Join(root, "shop/config.py")
+
+	// 1. Structure
+	if n := findNode(file, "File"); n == nil || n.Properties["module"] != "order" {
+		t.Errorf("Expected File node for module 'order'")
+	}
+	if findNode(file+":Order", "Class") == nil {
+		t.Errorf("Expected Class Order not found")
+	}
+	if findNode(file+":create_order", "Function") == nil {
+		t.Errorf("Expected Function create_order not found")
+	}
+	if findNode(file+":TAX_RATE", "Global") == nil {
+		t.Errorf("Expected Global TAX_RATE not found")
+	}
+	if findNode(file+":rate", "Global") != nil {
+		t.Errorf("Local variable 'rate' should not be a Global")
+	}
+
+	total := findNode(file+":Order.total", "Function")
+	if total == nil {
+		t.Fatalf("Expected method Order.total not found")
+	}
+	decorators, _ := total.Properties["decorators"].([]string)
+	if len(decorators) != 1 || decorators[0] != "cached" {
+		t.Errorf("Expected decorators [cached], got %v", total.Properties["decorators"])
+	}
+
+	// 2. Edges
+	if !hasEdge(file+":Order", file+":Order.total", "HAS_METHOD") {
+		t.Errorf("Missing HAS_METHOD edge Order -> total")
+	}
+	if !hasEdge(file+":Order.total", file+":cached", "DECORATED_BY") {
+		t.Errorf("Missing DECORATED_BY edge total -> cached")
+	}
+	if !hasEdge(file+":Order", base+":Model", "INHERITS") {
+		t.Errorf("Missing INHERITS edge Order -> base.py:Model")
+	}
+	if !hasEdge(file+":Order.total", file+":Order.tax", "CALLS") {
+		t.Errorf("Missing CALLS edge total -> self.tax")
+	}
+	// Aliased relative symbol import: from ..config import load_settings as settings
+	if !hasEdge(file+":Order.tax", config+":load_settings", "CALLS") {
+		t.Errorf("Missing CALLS edge tax -> config.py:load_settings")
+	}
+	// Relative submodule import: from .. import config
+	if !hasEdge(file+":Order.checkout", config+":load_settings", "CALLS") {
+		t.Errorf("Missing CALLS edge checkout -> config.py:load_settings")
+	}
+	if !hasEdge(file+":Order.tax", file+":TAX_RATE", "USES_GLOBAL") {
+		t.Errorf("Missing USES_GLOBAL edge tax -> TAX_RATE")
+	}
+	if !hasEdge(file+":create_order", file+":Order", "CALLS") {
+		t.Errorf("Missing CALLS edge create_order -> Order")
+	}
+
+	for _, e := range edges {
+		if e.TargetID == file+":print" || e.TargetID == file+":staticmethod" {
+			t.Errorf("Unexpected edge to builtin %s", e.TargetID)
+		}
+	}
+}
+
+func TestParsePython_AbsoluteImports(t *testing.T) {
+	root, _, edges := parsePythonFixture(t, "main.py")
+
+	hasEdge := func(src, tgt, edgeType string) bool {
+		for _, e := range edges {
+			if e.SourceID == src && e.TargetID == tgt && e.Type == edgeType {
+				return true
+			}
+		}
+		return false
+	}
+
+	file := filepath.Join(root, "main.py")
+	order := filepath.Join(root, "shop/models/order.py")
+
+	// import shop.models.order as order_mod
+	if !hasEdge(file, order, "IMPORTS") {
+		t.Errorf("Missing IMPORTS edge main.py -> %s", order)
+	}
+	if !hasEdge(file+":main", order+":create_order", "CALLS") {
+		t.Errorf("Missing CALLS edge main -> order.py:create_order")
+	}
+	// from shop.models import base (submodule)
+	if !hasEdge(file+":main", filepath.Join(root, "shop/models/base.py")+":Model", "CALLS") {
+		t.Errorf("Missing CALLS edge main -> base.py:Model")
+	}
+}
+
+func TestParsePython_Scopes(t *testing.T) {
+	parser, _ := analysis.GetParser(".py")
+	src := `LIMIT = 10
+items = []
+
+def helper():
+    return 1
+
+def shadowed(LIMIT, callback):
+    count = LIMIT
+    callback()
+    return [items for items in range(count)]
+
+def rebinds():
+    global LIMIT
+    LIMIT = 20
+    for items in range(3):
+        pass
+    return LIMIT
+
+def nested():
+    def helper():
+        return 2
+    helper()
+    helper()
+    return items.copy(LIMIT=1)
+`
+	nodes, edges, err := parser.Parse("app.py", []byte(src))
+	if err != nil || len(nodes) == 0 {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	count := func(src, tgt, edgeType string) int {
+		n := 0
+		for _, e := range edges {
+			if e.SourceID == src && e.TargetID == tgt && e.Type == edgeType {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Parameters, comprehension and loop variables shadow the globals
+	for _, e := range []struct{ src, tgt, edgeType string }{
+		{"app.py:shadowed", "app.py:LIMIT", "USES_GLOBAL"},
+		{"app.py:shadowed", "app.py:items", "USES_GLOBAL"},
+		{"app.py:shadowed", "app.py:callback", "CALLS"},
+		{"app.py:rebinds", "app.py:items", "USES_GLOBAL"},
+		{"app.py:nested", "app.py:helper", "CALLS"},
+		{"app.py:nested", "app.py:LIMIT", "USES_GLOBAL"}, // A keyword argument name
+	} {
+		if count(e.src, e.tgt, e.edgeType) != 0 {
+			t.Errorf("Unexpected %s edge %s -> %s", e.edgeType, e.src, e.tgt)
+		}
+	}
+
+	// "global" makes the assignment a write to the module global
+	if count("app.py:rebinds", "app.py:LIMIT", "USES_GLOBAL") != 1 {
+		t.Errorf("Missing USES_GLOBAL edge rebinds -> LIMIT")
+	}
+	if count("app.py:nested", "app.py:items", "USES_GLOBAL") != 1 {
+		t.Errorf("Missing USES_GLOBAL edge nested -> items")
+	}
+	// Repeated calls of the nested function make one edge
+	if n := count("app.py:nested", "app.py:nested.helper", "CALLS"); n != 1 {
+		t.Errorf("Expected one CALLS edge nested -> nested.helper, got %d", n)
+	}
+}
+
+func TestParsePython_ImportsStayInRoot(t *testing.T) {
+	parser, _ := analysis.GetParser(".py")
+	outside := t.TempDir()
+	root := filepath.Join(outside, "project")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "helpers.py"), []byte("def run():\n    pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(root, "app.py")
+	content := []byte("import helpers\n")
+
+	_, edges, err := parser.(analysis.RootedParser).ParseInRoot(root, file, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edges) != 1 || edges[0].TargetID != "helpers" {
+		t.Errorf("Expected helpers to stay an unresolved module, got %+v", edges[0])
+	}
+
+	// Without a root, every ancestor is searched
+	_, edges, _ = parser.Parse(file, content)
+	if len(edges) != 1 || edges[0].TargetID != filepath.Join(outside, "helpers.py") {
+		t.Errorf("Expected helpers.py above the file, got %+v", edges[0])
+	}
+}
@@ -65,14 +65,17 @@ func (p *SqlParser) Parse(filePath string, content []byte) ([]*graph.Node, []*gr
 
 			nodeName := c.Node.Content(content)
 
+			properties := map[string]interface{}{
+				"name": nodeName,
+				"file": filePath,
+				"line": c.Node.StartPoint().Row + 1,
+			}
+			setSourceSpan(properties, findDefinitionNode(c.Node, "create_function"), content)
+
 			n := &graph.Node{
 				ID:    fmt.Sprintf("%s:%s", filePath, nodeName),
 				Label: "Function",
-				Properties: map[string]interface{}{
-					"name": nodeName,
-					"file": filePath,
-					"line": c.Node.StartPoint().Row + 1,
-				},
+				Properties: properties,
 			}
 			nodes = append(nodes, n)
 		}
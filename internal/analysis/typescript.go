@@ -151,6 +151,13 @@ func (p *TypeScriptParser) Parse(filePath string, content []byte) ([]*graph.Node
 		if !ok {
 			break
 		}
+
+		var defNode *sitter.Node
+		for _, c := range m.Captures {
+			if strings.HasSuffix(qDef.CaptureNameForId(c.Index), ".def") {
+				defNode = c.Node
+			}
+		}
 		
 		for _, c := range m.Captures {
 			captureName := qDef.CaptureNameForId(c.Index)
@@ -172,14 +179,19 @@ func (p *TypeScriptParser) Parse(filePath string, content []byte) ([]*graph.Node
 			
 			id := fmt.Sprintf("%s:%s", filePath, nodeName)
 			
+			properties := map[string]interface{}{
+				"name": nodeName,
+				"file": filePath,
+				"line": c.Node.StartPoint().Row + 1,
+			}
+			if defNode != nil {
+				setSourceSpan(properties, defNode, content)
+			}
+
 			n := &graph.Node{
 				ID:    id,
 				Label: label,
-				Properties: map[string]interface{}{
-					"name": nodeName,
-					"file": filePath,
-					"line": c.Node.StartPoint().Row + 1,
-				},
+				Properties: properties,
 			}
 			nodes = append(nodes, n)
 		}
@@ -23,6 +23,7 @@ func (p *VBNetParser) Parse(filePath string, content []byte) ([]*graph.Node, []*
 	classRegex := regexp.MustCompile(`(?i)(?:Class|Module)\s+(\w+)`)
 	funcRegex := regexp.MustCompile(`(?i)(?:Sub|Function)\s+(\w+)`)
 	endFuncRegex := regexp.MustCompile(`(?i)End\s+(?:Sub|Function)`)
+	endClassRegex := regexp.MustCompile(`(?i)End\s+(?:Class|Module)`)
 	callRegex := regexp.MustCompile(`(\w+)\(`)
 
 	lines := strings.Split(string(content), "\n")
@@ -42,6 +43,15 @@ func (p *VBNetParser) Parse(filePath string, content []byte) ([]*graph.Node, []*
 	currentFunction := ""
 	// currentClass := "" // Not strictly needed for call tracking unless we want fully qualified names
 
+	// Definitions still waiting for their End statement, used to fill in end_line and content
+	var openFunc *graph.Node
+	var openClasses []*graph.Node
+	closeDefinition := func(n *graph.Node, endLine int) {
+		startLine := n.Properties["start_line"].(int)
+		n.Properties["end_line"] = endLine
+		n.Properties["content"] = strings.Join(lines[startLine-1:endLine], "\n")
+	}
+
 	for i, line := range lines {
 		lineNumber := i + 1
 		trimmed := strings.TrimSpace(line)
@@ -56,12 +66,14 @@ func (p *VBNetParser) Parse(filePath string, content []byte) ([]*graph.Node, []*
 				ID:    classID,
 				Label: "Class",
 				Properties: map[string]interface{}{
-					"name": className,
-					"file": filePath,
-					"line": lineNumber,
+					"name":       className,
+					"file":       filePath,
+					"line":       lineNumber,
+					"start_line": lineNumber,
 				},
 			}
 			nodes = append(nodes, classNode)
+			openClasses = append(openClasses, classNode)
 		}
 
 		// 2. Check for Function/Sub Definition
@@ -74,14 +86,21 @@ func (p *VBNetParser) Parse(filePath string, content []byte) ([]*graph.Node, []*
 				ID:    funcID,
 				Label: "Function",
 				Properties: map[string]interface{}{
-					"name":      funcName,
-					"signature": trimmed, // Rough signature
-					"file":      filePath,
-					"line":      lineNumber,
+					"name":       funcName,
+					"signature":  trimmed, // Rough signature
+					"file":       filePath,
+					"line":       lineNumber,
+					"start_line": lineNumber,
 				},
 			}
 			nodes = append(nodes, funcNode)
 
+			// A new definition implicitly ends one that was never closed
+			if openFunc != nil {
+				closeDefinition(openFunc, lineNumber-1)
+			}
+			openFunc = funcNode
+
 			// Edge: DEFINED_IN File
 			edges = append(edges, &graph.Edge{
 				SourceID: funcID,
@@ -94,6 +113,14 @@ func (p *VBNetParser) Parse(filePath string, content []byte) ([]*graph.Node, []*
 		// 3. Check for End of Function/Sub
 		if endFuncRegex.MatchString(trimmed) {
 			currentFunction = ""
+			if openFunc != nil {
+				closeDefinition(openFunc, lineNumber)
+				openFunc = nil
+			}
+		}
+		if endClassRegex.MatchString(trimmed) && len(openClasses) > 0 {
+			closeDefinition(openClasses[len(openClasses)-1], lineNumber)
+			openClasses = openClasses[:len(openClasses)-1]
 		}
 
 		// 4. Check for Calls (only if inside a function)
@@ -126,5 +153,13 @@ func (p *VBNetParser) Parse(filePath string, content []byte) ([]*graph.Node, []*
 		}
 	}
 
+	// Anything left open runs to the end of the file
+	if openFunc != nil {
+		closeDefinition(openFunc, len(lines))
+	}
+	for _, n := range openClasses {
+		closeDefinition(n, len(lines))
+	}
+
 	return nodes, edges, nil
 }
@@ -15,6 +15,21 @@ type Config struct {
 	GoogleCloudProject   string
 	GoogleCloudLocation  string
 	GeminiEmbeddingModel string
+	VectorSimilarity     string
+	EmbeddingCache       string
+	EmbeddingCacheMaxMB  string
+	Embedder             string
+	EmbeddingDimensions  string
+	LLM                  string
+	OpenAIBaseURL        string
+	OpenAIAPIKey         string
+	OpenAIEmbeddingModel string
+	OpenAIChatModel      string
+	APIMaxRetries        string
+	APITimeout           string
+	APIConcurrency       string
+	APIRequestsPerMinute string
+	APITokensPerMinute   string
 }
 
 // LoadConfig loads the configuration from environment variables.
@@ -26,6 +41,21 @@ func LoadConfig() Config {
 		GoogleCloudProject:   os.Getenv("GOOGLE_CLOUD_PROJECT"),
 		GoogleCloudLocation:  os.Getenv("GOOGLE_CLOUD_LOCATION"),
 		GeminiEmbeddingModel: os.Getenv("GEMINI_EMBEDDING_MODEL"),
+		VectorSimilarity:     os.Getenv("NEO4J_VECTOR_SIMILARITY"),
+		EmbeddingCache:       os.Getenv("GRAPHDB_EMBEDDING_CACHE"),
+		EmbeddingCacheMaxMB:  os.Getenv("GRAPHDB_EMBEDDING_CACHE_MAX_MB"),
+		Embedder:             os.Getenv("GRAPHDB_EMBEDDER"),
+		EmbeddingDimensions:  os.Getenv("GRAPHDB_EMBEDDING_DIMENSIONS"),
+		LLM:                  os.Getenv("GRAPHDB_LLM"),
+		OpenAIBaseURL:        os.Getenv("OPENAI_BASE_URL"),
+		OpenAIAPIKey:         os.Getenv("OPENAI_API_KEY"),
+		OpenAIEmbeddingModel: os.Getenv("OPENAI_EMBEDDING_MODEL"),
+		OpenAIChatModel:      os.Getenv("OPENAI_CHAT_MODEL"),
+		APIMaxRetries:        os.Getenv("GRAPHDB_API_MAX_RETRIES"),
+		APITimeout:           os.Getenv("GRAPHDB_API_TIMEOUT"),
+		APIConcurrency:       os.Getenv("GRAPHDB_API_CONCURRENCY"),
+		APIRequestsPerMinute: os.Getenv("GRAPHDB_API_RPM"),
+		APITokensPerMinute:   os.Getenv("GRAPHDB_API_TPM"),
 	}
 }
 
@@ -0,0 +1,491 @@
+package embedding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	vectorsBucket = []byte("vectors") // sha256(model \x00 task \x00 text) -> cacheEntry
+	metaBucket    = []byte("meta")    // counter name -> uint64
+
+	hitsKey    = []byte("hits")
+	missesKey  = []byte("misses")
+	bytesKey   = []byte("bytes")
+	entriesKey = []byte("entries")
+)
+
+// maxDeferredAccesses bounds how many hits a Cache remembers before it writes
+// their access times.
+const maxDeferredAccesses = 4096
+
+// Cache is a persistent store of embedding vectors in a single bbolt file.
+// Entries are keyed by a hash of the model, task type and text, and the least
+// recently used ones are evicted once the cache outgrows MaxBytes.
+//
+// Lookups only read the file. The access times and counters they update are
+// kept in memory and written with the next store, prune or close.
+type Cache struct {
+	// MaxBytes bounds the stored vector data. Zero means no limit.
+	MaxBytes int64
+
+	db *bolt.DB
+
+	mu           sync.Mutex
+	accessed     map[string]time.Time // Key -> last hit not yet written
+	hits, misses uint64               // Not yet added to the stored counters
+}
+
+// CacheStats describes the contents and lifetime hit rate of a Cache.
+type CacheStats struct {
+	Entries  uint64
+	Bytes    uint64
+	Hits     uint64
+	Misses   uint64
+	ByModel  map[string]uint64 // "model/task" -> entries
+	Oldest   time.Time         // Least recent access
+	Newest   time.Time         // Most recent access
+	FileSize int64
+}
+
+// PruneOptions selects the entries Prune removes. Each set option applies.
+type PruneOptions struct {
+	MaxBytes  int64         // Evict least recently used entries down to this size
+	OlderThan time.Duration // Remove entries not used for this long
+	Model     string        // Remove entries of this model
+	All       bool          // Remove everything
+}
+
+// OpenCache opens (or creates) the cache file at path. It fails if another
+// process holds the file open.
+func OpenCache(path string) (*Cache, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedding cache %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{vectorsBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize embedding cache %s: %w", path, err)
+	}
+	return &Cache{db: db, accessed: make(map[string]time.Time)}, nil
+}
+
+// Close writes the deferred access times and counters and closes the cache
+// file.
+func (c *Cache) Close() error {
+	err := c.update(func(tx *bolt.Tx) error { return nil })
+	if closeErr := c.db.Close(); closeErr != nil {
+		return closeErr
+	}
+	return err
+}
+
+// cacheKey identifies the vector of text under a model and task type.
+func cacheKey(model, taskType, text string) []byte {
+	sum := sha256.Sum256([]byte(model + "\x00" + taskType + "\x00" + text))
+	return sum[:]
+}
+
+// A cacheEntry is the last access time (unix nanoseconds), the model and task
+// type (each prefixed with a one-byte length, so at most maxEntryName bytes)
+// and the vector as little-endian float32s.
+func encodeEntry(accessed time.Time, model, taskType string, vec []float32) []byte {
+	buf := make([]byte, 0, 8+2+len(model)+len(taskType)+4*len(vec))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(accessed.UnixNano()))
+	buf = append(buf, byte(len(model)))
+	buf = append(buf, model...)
+	buf = append(buf, byte(len(taskType)))
+	buf = append(buf, taskType...)
+	for _, f := range vec {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(f))
+	}
+	return buf
+}
+
+const maxEntryName = math.MaxUint8
+
+type cacheEntry struct {
+	accessed time.Time
+	model    string
+	taskType string
+	vector   []byte // Little-endian float32s
+}
+
+func decodeEntry(value []byte) (cacheEntry, error) {
+	if len(value) < 10 {
+		return cacheEntry{}, errors.New("truncated cache entry")
+	}
+	e := cacheEntry{accessed: time.Unix(0, int64(binary.LittleEndian.Uint64(value)))}
+	rest := value[8:]
+	for _, field := range []*string{&e.model, &e.taskType} {
+		if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+			return cacheEntry{}, errors.New("truncated cache entry")
+		}
+		*field = string(rest[1 : 1+rest[0]])
+		rest = rest[1+rest[0]:]
+	}
+	if len(rest)%4 != 0 {
+		return cacheEntry{}, errors.New("malformed cache vector")
+	}
+	e.vector = rest
+	return e, nil
+}
+
+func (e cacheEntry) floats() []float32 {
+	vec := make([]float32, len(e.vector)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(e.vector[4*i:]))
+	}
+	return vec
+}
+
+// lookup returns the cached vectors for keys (nil where missing) and marks
+// the hits as recently used.
+func (c *Cache) lookup(keys [][]byte) ([][]float32, error) {
+	vecs := make([][]float32, len(keys))
+	var hits, misses uint64
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(vectorsBucket)
+		for i, key := range keys {
+			value := b.Get(key)
+			if value == nil {
+				continue
+			}
+			if e, err := decodeEntry(value); err == nil {
+				vecs[i] = e.floats()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return vecs, err
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	for i, vec := range vecs {
+		if vec == nil {
+			misses++
+			continue
+		}
+		hits++
+		c.accessed[string(keys[i])] = now
+	}
+	c.hits += hits
+	c.misses += misses
+	full := len(c.accessed) >= maxDeferredAccesses
+	c.mu.Unlock()
+
+	if full {
+		return vecs, c.update(func(tx *bolt.Tx) error { return nil })
+	}
+	return vecs, nil
+}
+
+// update runs fn in a write transaction, after writing the access times and
+// counters deferred by lookups. If the transaction fails they stay deferred.
+func (c *Cache) update(fn func(tx *bolt.Tx) error) error {
+	c.mu.Lock()
+	accessed, hits, misses := c.accessed, c.hits, c.misses
+	c.accessed, c.hits, c.misses = make(map[string]time.Time), 0, 0
+	c.mu.Unlock()
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(vectorsBucket)
+		for key, at := range accessed {
+			value := b.Get([]byte(key))
+			if len(value) < 8 {
+				continue
+			}
+			touched := append([]byte(nil), value...)
+			binary.LittleEndian.PutUint64(touched, uint64(at.UnixNano()))
+			if err := b.Put([]byte(key), touched); err != nil {
+				return err
+			}
+		}
+		meta := tx.Bucket(metaBucket)
+		if err := addCounter(meta, hitsKey, int64(hits)); err != nil {
+			return err
+		}
+		if err := addCounter(meta, missesKey, int64(misses)); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+	if err != nil {
+		c.mu.Lock()
+		for key, at := range accessed {
+			if at.After(c.accessed[key]) {
+				c.accessed[key] = at
+			}
+		}
+		c.hits += hits
+		c.misses += misses
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// store saves vectors under keys, then evicts down to MaxBytes.
+func (c *Cache) store(keys [][]byte, model, taskType string, vecs [][]float32) error {
+	if len(model) > maxEntryName || len(taskType) > maxEntryName {
+		return fmt.Errorf("model %q and task type %q must be at most %d bytes to be cached", model, taskType, maxEntryName)
+	}
+	now := time.Now()
+	var size uint64
+	err := c.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(vectorsBucket)
+		meta := tx.Bucket(metaBucket)
+		for i, key := range keys {
+			value := encodeEntry(now, model, taskType, vecs[i])
+			if old := b.Get(key); old != nil {
+				addCounter(meta, bytesKey, -int64(len(old)))
+				addCounter(meta, entriesKey, -1)
+			}
+			if err := b.Put(key, value); err != nil {
+				return err
+			}
+			addCounter(meta, bytesKey, int64(len(value)))
+			addCounter(meta, entriesKey, 1)
+		}
+		size = counter(meta, bytesKey)
+		return nil
+	})
+	if err != nil || c.MaxBytes <= 0 || size <= uint64(c.MaxBytes) {
+		return err
+	}
+	// Leave some headroom so the next batches do not each trigger a scan
+	_, err = c.Prune(PruneOptions{MaxBytes: c.MaxBytes * 9 / 10})
+	return err
+}
+
+// Prune removes the entries selected by opts and returns how many it removed.
+func (c *Cache) Prune(opts PruneOptions) (int, error) {
+	removed := 0
+	err := c.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(vectorsBucket)
+
+		type candidate struct {
+			key      []byte
+			size     int
+			accessed time.Time
+		}
+		var remove [][]byte
+		var keep []candidate
+		cutoff := time.Now().Add(-opts.OlderThan)
+		err := b.ForEach(func(k, v []byte) error {
+			e, err := decodeEntry(v)
+			switch {
+			case err != nil, opts.All, opts.Model != "" && e.model == opts.Model, opts.OlderThan > 0 && e.accessed.Before(cutoff):
+				remove = append(remove, append([]byte(nil), k...))
+			default:
+				keep = append(keep, candidate{append([]byte(nil), k...), len(v), e.accessed})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Least recently used first, until the rest fits
+		var size int64
+		for _, cand := range keep {
+			size += int64(cand.size)
+		}
+		if opts.MaxBytes > 0 && size > opts.MaxBytes {
+			sort.Slice(keep, func(i, j int) bool { return keep[i].accessed.Before(keep[j].accessed) })
+			for _, cand := range keep {
+				if size <= opts.MaxBytes {
+					break
+				}
+				remove = append(remove, cand.key)
+				size -= int64(cand.size)
+			}
+		}
+
+		for _, key := range remove {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+		removed = len(remove)
+		return recount(tx)
+	})
+	return removed, err
+}
+
+// Stats summarizes the cache.
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{ByModel: make(map[string]uint64)}
+	err := c.db.View(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		c.mu.Lock()
+		stats.Hits = counter(meta, hitsKey) + c.hits
+		stats.Misses = counter(meta, missesKey) + c.misses
+		c.mu.Unlock()
+		stats.FileSize = tx.Size()
+		return tx.Bucket(vectorsBucket).ForEach(func(k, v []byte) error {
+			e, err := decodeEntry(v)
+			if err != nil {
+				return nil
+			}
+			stats.Entries++
+			stats.Bytes += uint64(len(v))
+			stats.ByModel[e.model+"/"+e.taskType]++
+			if stats.Oldest.IsZero() || e.accessed.Before(stats.Oldest) {
+				stats.Oldest = e.accessed
+			}
+			if e.accessed.After(stats.Newest) {
+				stats.Newest = e.accessed
+			}
+			return nil
+		})
+	})
+	return stats, err
+}
+
+// recount resets the size counters from the stored entries.
+func recount(tx *bolt.Tx) error {
+	var entries, size uint64
+	err := tx.Bucket(vectorsBucket).ForEach(func(k, v []byte) error {
+		entries++
+		size += uint64(len(v))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	meta := tx.Bucket(metaBucket)
+	if err := putCounter(meta, entriesKey, entries); err != nil {
+		return err
+	}
+	return putCounter(meta, bytesKey, size)
+}
+
+func counter(b *bolt.Bucket, key []byte) uint64 {
+	if v := b.Get(key); len(v) == 8 {
+		return binary.LittleEndian.Uint64(v)
+	}
+	return 0
+}
+
+func putCounter(b *bolt.Bucket, key []byte, value uint64) error {
+	return b.Put(key, binary.LittleEndian.AppendUint64(nil, value))
+}
+
+func addCounter(b *bolt.Bucket, key []byte, delta int64) error {
+	value := int64(counter(b, key)) + delta
+	if value < 0 {
+		value = 0
+	}
+	return putCounter(b, key, uint64(value))
+}
+
+// CachingEmbedder serves embeddings from a Cache and only passes the texts it
+// has not seen to the wrapped Embedder. Entries are keyed by model, the task
+// type of the purpose and text.
+type CachingEmbedder struct {
+	Embedder Embedder
+	Cache    *Cache
+	Model    string
+
+	hits, misses atomic.Int64
+}
+
+// NewCachingEmbedder wraps embedder, whose vectors come from model, in cache.
+func NewCachingEmbedder(embedder Embedder, cache *Cache, model string) *CachingEmbedder {
+	return &CachingEmbedder{Embedder: embedder, Cache: cache, Model: model}
+}
+
+// EmbedBatch returns cached vectors where it can and embeds the rest.
+func (e *CachingEmbedder) EmbedBatch(texts []string, purpose Purpose) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	taskType := purpose.TaskType()
+	keys := make([][]byte, len(texts))
+	for i, text := range texts {
+		keys[i] = cacheKey(e.Model, taskType, text)
+	}
+	vecs, err := e.Cache.lookup(keys)
+	if err != nil {
+		log.Printf("WARNING: embedding cache lookup failed: %v", err)
+		vecs = make([][]float32, len(texts))
+	}
+
+	// Embed each missing text once, even if it repeats within the batch
+	var missing []string
+	var missingKeys [][]byte
+	var misses int64
+	positions := make(map[string][]int)
+	for i, vec := range vecs {
+		if vec != nil {
+			continue
+		}
+		misses++
+		if _, ok := positions[texts[i]]; !ok {
+			missing = append(missing, texts[i])
+			missingKeys = append(missingKeys, keys[i])
+		}
+		positions[texts[i]] = append(positions[texts[i]], i)
+	}
+	e.hits.Add(int64(len(texts)) - misses)
+	e.misses.Add(misses)
+	if len(missing) == 0 {
+		return vecs, nil
+	}
+
+	embedded, err := e.Embedder.EmbedBatch(missing, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d", len(missing), len(embedded))
+	}
+
+	var storeKeys [][]byte
+	var storeVecs [][]float32
+	for i, text := range missing {
+		for _, pos := range positions[text] {
+			vecs[pos] = embedded[i]
+		}
+		// Empty vectors are failures of the service, not results
+		if len(embedded[i]) > 0 {
+			storeKeys = append(storeKeys, missingKeys[i])
+			storeVecs = append(storeVecs, embedded[i])
+		}
+	}
+	if err := e.Cache.store(storeKeys, e.Model, taskType, storeVecs); err != nil {
+		log.Printf("WARNING: failed to write embedding cache: %v", err)
+	}
+	return vecs, nil
+}
+
+// ModelName is the model the cached vectors belong to.
+func (e *CachingEmbedder) ModelName() string {
+	return e.Model
+}
+
+// Stats returns the hits and misses of this embedder.
+func (e *CachingEmbedder) Stats() (hits, misses int64) {
+	return e.hits.Load(), e.misses.Load()
+}
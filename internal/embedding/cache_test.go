@@ -0,0 +1,175 @@
+package embedding
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingEmbedder returns [len(text), call] and remembers what it was asked.
+type recordingEmbedder struct {
+	calls [][]string
+	err   error
+}
+
+func (r *recordingEmbedder) EmbedBatch(texts []string, purpose Purpose) ([][]float32, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.calls = append(r.calls, texts)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{float32(len(text)), float32(len(r.calls))}
+	}
+	return out, nil
+}
+
+func openTestCache(t *testing.T) (*Cache, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "embeddings.db")
+	cache, err := OpenCache(path)
+	if err != nil {
+		t.Fatalf("OpenCache failed: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+	return cache, path
+}
+
+func TestCachingEmbedder(t *testing.T) {
+	cache, path := openTestCache(t)
+	inner := &recordingEmbedder{}
+	embedder := NewCachingEmbedder(inner, cache, "model-a")
+
+	// 1. Everything is a miss; duplicates are embedded once
+	vecs, err := embedder.EmbedBatch([]string{"foo", "barbaz", "foo"}, PurposeDocument)
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if len(inner.calls) != 1 || len(inner.calls[0]) != 2 {
+		t.Fatalf("Expected one call with 2 texts, got %v", inner.calls)
+	}
+	if vecs[0][0] != 3 || vecs[1][0] != 6 || vecs[2][0] != 3 {
+		t.Errorf("Unexpected vectors %v", vecs)
+	}
+
+	// 2. Cached texts are not embedded again, and keep their original vector
+	vecs, _ = embedder.EmbedBatch([]string{"foo", "qux"}, PurposeDocument)
+	if len(inner.calls) != 2 || len(inner.calls[1]) != 1 || inner.calls[1][0] != "qux" {
+		t.Errorf("Expected only qux to be embedded, got %v", inner.calls)
+	}
+	if vecs[0][0] != 3 || vecs[0][1] != 1 {
+		t.Errorf("Expected cached vector for foo, got %v", vecs[0])
+	}
+	if hits, misses := embedder.Stats(); hits != 1 || misses != 4 {
+		t.Errorf("Expected 1 hit and 4 misses, got %d and %d", hits, misses)
+	}
+
+	// 3. Another model does not share entries
+	other := NewCachingEmbedder(inner, cache, "model-b")
+	other.EmbedBatch([]string{"foo"}, PurposeDocument)
+	if len(inner.calls) != 3 {
+		t.Errorf("Expected a miss for another model")
+	}
+
+	// 4. Nor does another purpose of the same model
+	embedder.EmbedBatch([]string{"foo"}, PurposeCodeQuery)
+	if len(inner.calls) != 4 {
+		t.Errorf("Expected a miss for a query embedding of a cached document")
+	}
+
+	// 5. Entries survive reopening
+	cache.Close()
+	reopened, err := OpenCache(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer reopened.Close()
+	stats, err := reopened.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Entries != 5 || stats.ByModel["model-a/"+TaskRetrievalDocument] != 3 || stats.ByModel["model-a/"+TaskCodeRetrievalQuery] != 1 || stats.ByModel["model-b/"+TaskRetrievalDocument] != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.Hits != 1 || stats.Misses != 6 {
+		t.Errorf("Expected persisted 1 hit and 6 misses, got %d and %d", stats.Hits, stats.Misses)
+	}
+}
+
+func TestCachingEmbedder_ErrorsAreNotCached(t *testing.T) {
+	cache, _ := openTestCache(t)
+	inner := &recordingEmbedder{err: errors.New("quota exceeded")}
+	embedder := NewCachingEmbedder(inner, cache, "m")
+
+	if _, err := embedder.EmbedBatch([]string{"foo"}, PurposeDocument); err == nil {
+		t.Fatal("Expected error from the wrapped embedder")
+	}
+	if stats, _ := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("Expected nothing cached, got %d entries", stats.Entries)
+	}
+}
+
+func TestCachingEmbedder_LongModelNamesAreNotCached(t *testing.T) {
+	cache, _ := openTestCache(t)
+	embedder := NewCachingEmbedder(&recordingEmbedder{}, cache, strings.Repeat("m", 256))
+
+	vecs, err := embedder.EmbedBatch([]string{"foo"}, PurposeDocument)
+	if err != nil || len(vecs) != 1 || vecs[0][0] != 3 {
+		t.Fatalf("Expected the vector despite the cache, got %v (%v)", vecs, err)
+	}
+	if stats, _ := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("Expected nothing cached, got %d entries", stats.Entries)
+	}
+}
+
+func TestCache_Prune(t *testing.T) {
+	cache, _ := openTestCache(t)
+	embedder := NewCachingEmbedder(&recordingEmbedder{}, cache, "m")
+	for _, text := range []string{"a", "b", "c", "d"} {
+		embedder.EmbedBatch([]string{text}, PurposeDocument)
+		time.Sleep(2 * time.Millisecond)
+	}
+	// Touch a, so b is now the least recently used
+	embedder.EmbedBatch([]string{"a"}, PurposeDocument)
+
+	stats, _ := cache.Stats()
+	entrySize := int64(stats.Bytes / stats.Entries)
+
+	removed, err := cache.Prune(PruneOptions{MaxBytes: 3 * entrySize})
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected 1 entry evicted, got %d (%v)", removed, err)
+	}
+	inner := &recordingEmbedder{}
+	embedder.Embedder = inner
+	embedder.EmbedBatch([]string{"a", "b", "c", "d"}, PurposeDocument)
+	if len(inner.calls) != 1 || len(inner.calls[0]) != 1 || inner.calls[0][0] != "b" {
+		t.Errorf("Expected only b to be evicted, got %v", inner.calls)
+	}
+
+	if removed, _ := cache.Prune(PruneOptions{Model: "other"}); removed != 0 {
+		t.Errorf("Expected no entries of another model, removed %d", removed)
+	}
+	if removed, _ := cache.Prune(PruneOptions{All: true}); removed != 4 {
+		t.Errorf("Expected all 4 entries removed, got %d", removed)
+	}
+	if stats, _ := cache.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("Expected empty cache, got %+v", stats)
+	}
+}
+
+func TestCache_EvictsOverMaxBytes(t *testing.T) {
+	cache, _ := openTestCache(t)
+	embedder := NewCachingEmbedder(&recordingEmbedder{}, cache, "m")
+	embedder.EmbedBatch([]string{"seed"}, PurposeDocument)
+	stats, _ := cache.Stats()
+
+	cache.MaxBytes = int64(stats.Bytes) * 10
+	for i := 0; i < 20; i++ {
+		embedder.EmbedBatch([]string{string(rune('a' + i))}, PurposeDocument)
+	}
+	if stats, _ := cache.Stats(); int64(stats.Bytes) > cache.MaxBytes || stats.Entries == 0 {
+		t.Errorf("Expected cache bounded by %d bytes, got %+v", cache.MaxBytes, stats)
+	}
+}
@@ -0,0 +1,153 @@
+package embedding
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultHashedDimensions is the vector size of a HashedEmbedder when none is set.
+const DefaultHashedDimensions = 256
+
+// HashedEmbedder is an offline Embedder. It hashes the words of a text (with
+// identifiers split on camelCase and snake_case), word bigrams and character
+// trigrams into a fixed number of signed buckets, so texts that share
+// vocabulary get similar vectors. It is deterministic and needs no network,
+// which makes it suitable for CI, air-gapped machines and tests.
+type HashedEmbedder struct {
+	Dimensions int
+}
+
+// NewHashedEmbedder returns a HashedEmbedder producing vectors of dims
+// dimensions (DefaultHashedDimensions if dims is zero or negative).
+func NewHashedEmbedder(dims int) *HashedEmbedder {
+	if dims <= 0 {
+		dims = DefaultHashedDimensions
+	}
+	return &HashedEmbedder{Dimensions: dims}
+}
+
+// ModelName identifies the vectors, e.g. "hashed-256".
+func (h *HashedEmbedder) ModelName() string {
+	return fmt.Sprintf("hashed-%d", h.dims())
+}
+
+func (h *HashedEmbedder) dims() int {
+	if h.Dimensions <= 0 {
+		return DefaultHashedDimensions
+	}
+	return h.Dimensions
+}
+
+// EmbedBatch embeds each text independently. The embedding is symmetric, so
+// the purpose is ignored.
+func (h *HashedEmbedder) EmbedBatch(texts []string, purpose Purpose) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = h.embed(text)
+	}
+	return out, nil
+}
+
+// Feature weights: whole words carry most of the meaning, trigrams make
+// related spellings (parse/parser) overlap.
+const (
+	wordWeight    = 1.0
+	bigramWeight  = 0.5
+	trigramWeight = 0.25
+)
+
+func (h *HashedEmbedder) embed(text string) []float32 {
+	counts := make(map[string]float64)
+	words := hashWords(text)
+	for i, w := range words {
+		counts["w:"+w] += wordWeight
+		if i > 0 {
+			counts["b:"+words[i-1]+" "+w] += bigramWeight
+		}
+		padded := "^" + w + "$"
+		for j := 0; j+3 <= len(padded); j++ {
+			counts["t:"+padded[j:j+3]] += trigramWeight
+		}
+	}
+
+	dims := h.dims()
+	vec := make([]float32, dims)
+	for feature, count := range counts {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(feature))
+		sum := hasher.Sum64()
+		// Sublinear term frequency, and a hash-derived sign so that
+		// collisions cancel out instead of piling up
+		weight := 1 + math.Log(count)
+		if count < 1 {
+			weight = count
+		}
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vec[sum%uint64(dims)] += float32(weight)
+	}
+
+	var norm float64
+	for _, x := range vec {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec
+}
+
+// hashStopWords are keywords common to most code, which would otherwise make
+// every function look alike.
+var hashStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "of": true, "to": true, "in": true, "is": true,
+	"func": true, "function": true, "def": true, "return": true, "self": true, "this": true,
+	"if": true, "else": true, "for": true, "var": true, "let": true, "const": true,
+	"public": true, "private": true, "static": true, "void": true, "new": true, "nil": true, "null": true,
+}
+
+// hashWords lowercases text and splits it into words, breaking identifiers
+// at underscores, digits and camelCase humps.
+func hashWords(text string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			if w := strings.ToLower(string(current)); !hashStopWords[w] {
+				words = append(words, w)
+			}
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(current) > 0 {
+			prev := runes[i-1]
+			// fooBar, HTTPServer (split before the last capital), v2api
+			lowerToUpper := unicode.IsLower(prev) && unicode.IsUpper(r)
+			acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			digitEdge := unicode.IsDigit(prev) != unicode.IsDigit(r)
+			if lowerToUpper || acronymEnd || digitEdge {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
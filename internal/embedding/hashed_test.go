@@ -0,0 +1,74 @@
+package embedding
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func cosineSim(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func TestHashWords(t *testing.T) {
+	got := hashWords("func (s *HTTPServer) parseJSONConfig(cfg_path string, v2api int) error")
+	want := []string{"s", "http", "server", "parse", "json", "config", "cfg", "path", "string", "v", "2", "api", "int", "error"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestHashedEmbedder(t *testing.T) {
+	e := NewHashedEmbedder(0)
+	if e.ModelName() != "hashed-256" {
+		t.Errorf("Unexpected model name %s", e.ModelName())
+	}
+
+	texts := []string{
+		"func ParseConfigFile(path string) (*Config, error)",
+		"def parse_config_file(path): load the config file",
+		"func SendHTTPRequest(url string) (*Response, error)",
+		"",
+	}
+	vecs, err := e.EmbedBatch(texts, PurposeDocument)
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	for i, v := range vecs[:3] {
+		if len(v) != 256 {
+			t.Fatalf("Vector %d has %d dimensions", i, len(v))
+		}
+		if n := cosineSim(v, v); math.Abs(n-1) > 1e-5 {
+			t.Errorf("Vector %d is not normalized", i)
+		}
+	}
+
+	// Same words across naming conventions beat a different concept
+	same := cosineSim(vecs[0], vecs[1])
+	different := cosineSim(vecs[0], vecs[2])
+	if same <= different || same < 0.5 {
+		t.Errorf("Expected config parsers to be closer (%.2f) than config vs HTTP (%.2f)", same, different)
+	}
+
+	// Empty text yields a zero vector rather than an error
+	for _, x := range vecs[3] {
+		if x != 0 {
+			t.Fatalf("Expected zero vector for empty text")
+		}
+	}
+
+	// Deterministic across instances
+	again, _ := NewHashedEmbedder(256).EmbedBatch(texts[:1], PurposeDocument)
+	if !reflect.DeepEqual(again[0], vecs[0]) {
+		t.Error("Expected identical vectors for identical text")
+	}
+}
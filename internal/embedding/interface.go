@@ -1,8 +1,41 @@
 package embedding
 
+// Purpose tells an embedding model what texts are for. Asymmetric models
+// embed indexed content and search text differently, and their vectors only
+// compare well in those roles.
+type Purpose int
+
+const (
+	// PurposeDocument is content stored for retrieval: code and descriptions.
+	PurposeDocument Purpose = iota
+	// PurposeQuery is search text matched against natural-language descriptions.
+	PurposeQuery
+	// PurposeCodeQuery is search text matched against code.
+	PurposeCodeQuery
+)
+
+// Vertex AI task types for each Purpose.
+const (
+	TaskRetrievalDocument  = "RETRIEVAL_DOCUMENT"
+	TaskRetrievalQuery     = "RETRIEVAL_QUERY"
+	TaskCodeRetrievalQuery = "CODE_RETRIEVAL_QUERY"
+)
+
+// TaskType returns the Vertex AI task type for the purpose.
+func (p Purpose) TaskType() string {
+	switch p {
+	case PurposeQuery:
+		return TaskRetrievalQuery
+	case PurposeCodeQuery:
+		return TaskCodeRetrievalQuery
+	}
+	return TaskRetrievalDocument
+}
+
 // Embedder defines the interface for generating vector embeddings from text.
 type Embedder interface {
-	// EmbedBatch generates embeddings for a batch of texts.
+	// EmbedBatch generates embeddings for a batch of texts that share a purpose.
 	// Returns a slice of float32 slices, where each inner slice is the embedding for the corresponding text.
-	EmbedBatch(texts []string) ([][]float32, error)
+	// Symmetric embedders may ignore the purpose.
+	EmbedBatch(texts []string, purpose Purpose) ([][]float32, error)
 }
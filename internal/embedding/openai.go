@@ -0,0 +1,73 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"graphdb/internal/openai"
+	"graphdb/internal/policy"
+)
+
+// OpenAIEmbedder implements the Embedder interface against an OpenAI-compatible
+// /v1/embeddings API, such as OpenAI, Ollama, vLLM or LM Studio.
+type OpenAIEmbedder struct {
+	Client     *openai.Client
+	Model      string
+	Dimensions int // Requested vector size; 0 leaves it to the model
+	Policy     *policy.Policy
+}
+
+// NewOpenAIEmbedder creates an OpenAIEmbedder for the API at baseURL.
+func NewOpenAIEmbedder(baseURL, apiKey, model string, dimensions int) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		Client:     openai.NewClient(baseURL, apiKey),
+		Model:      model,
+		Dimensions: dimensions,
+		Policy:     policy.Default(),
+	}
+}
+
+// ModelName identifies the vectors: the model, with the requested
+// dimensions if set (e.g. "text-embedding-3-small@512").
+func (e *OpenAIEmbedder) ModelName() string {
+	return modelName(e.Model, e.Dimensions)
+}
+
+// EmbedBatch generates embeddings for a batch of texts. The API has no notion
+// of purpose, so it is ignored.
+func (e *OpenAIEmbedder) EmbedBatch(texts []string, purpose Purpose) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+
+	// Servers limit the inputs per request; stay well below the usual limits
+	const batchSize = 100
+
+	allEmbeddings := make([][]float32, 0, len(texts))
+	for i := 0; i < len(texts); i += batchSize {
+		end := min(i+batchSize, len(texts))
+
+		// Some servers reject empty input
+		batch := make([]string, end-i)
+		for j, t := range texts[i:end] {
+			if t == "" {
+				t = " "
+			}
+			batch[j] = t
+		}
+
+		var embeddings [][]float32
+		err := e.Policy.Do(ctx, policy.EstimateTokens(batch...), func(ctx context.Context) error {
+			var err error
+			embeddings, err = e.Client.Embeddings(ctx, e.Model, batch, e.Dimensions)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed content batch (chunk %d-%d): %w", i, end, err)
+		}
+		allEmbeddings = append(allEmbeddings, embeddings...)
+	}
+
+	return allEmbeddings, nil
+}
@@ -0,0 +1,75 @@
+package embedding
+
+import (
+	"encoding/json"
+	"fmt"
+	"graphdb/internal/policy"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenAIEmbedder_EmbedBatch(t *testing.T) {
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		batches = append(batches, len(req.Input))
+
+		var data []map[string]interface{}
+		for i, text := range req.Input {
+			if text == "" {
+				t.Errorf("Expected empty text to be replaced")
+			}
+			data = append(data, map[string]interface{}{"index": i, "embedding": []float32{float32(len(batches)), float32(i)}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder(server.URL, "", "nomic-embed-text", 0)
+	texts := make([]string, 150)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+	texts[3] = ""
+
+	vecs, err := embedder.EmbedBatch(texts, PurposeDocument)
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if len(batches) != 2 || batches[0] != 100 || batches[1] != 50 {
+		t.Errorf("Expected batches of 100 and 50, got %v", batches)
+	}
+	if len(vecs) != 150 || vecs[120][0] != 2 || vecs[120][1] != 20 {
+		t.Errorf("Unexpected vectors, e.g. %v", vecs[120])
+	}
+	if embedder.ModelName() != "nomic-embed-text" {
+		t.Errorf("Unexpected model name %s", embedder.ModelName())
+	}
+}
+
+func TestOpenAIEmbedder_Error(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, `{"error": {"message": "model not loaded"}}`, http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder(server.URL, "", "m", 0)
+	embedder.Policy = &policy.Policy{MaxRetries: 1, InitialBackoff: time.Millisecond}
+	_, err := embedder.EmbedBatch([]string{"a"}, PurposeDocument)
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if requests != 2 {
+		t.Errorf("Expected the 503 to be retried once, got %d requests", requests)
+	}
+	if want := "failed to embed content batch (chunk 0-1): API error 503: model not loaded"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
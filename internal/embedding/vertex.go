@@ -3,6 +3,7 @@ package embedding
 import (
 	"context"
 	"fmt"
+	"graphdb/internal/policy"
 
 	"google.golang.org/genai"
 )
@@ -14,8 +15,10 @@ type ModelClient interface {
 
 // VertexEmbedder implements the Embedder interface using Google Cloud Vertex AI via the GenAI SDK.
 type VertexEmbedder struct {
-	Client ModelClient
-	Model  string
+	Client     ModelClient
+	Model      string
+	Dimensions int // Output dimensionality; 0 leaves it to the model
+	Policy     *policy.Policy
 }
 
 // NewVertexEmbedder creates a new VertexEmbedder.
@@ -34,11 +37,19 @@ func NewVertexEmbedder(ctx context.Context, projectID, location, modelName strin
 	return &VertexEmbedder{
 		Client: client.Models,
 		Model:  modelName,
+		Policy: policy.Default(),
 	}, nil
 }
 
-// EmbedBatch generates embeddings for a batch of texts.
-func (v *VertexEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
+// ModelName identifies the vectors: the model, with the output dimensionality
+// if set (e.g. "gemini-embedding-001@768").
+func (v *VertexEmbedder) ModelName() string {
+	return modelName(v.Model, v.Dimensions)
+}
+
+// EmbedBatch generates embeddings for a batch of texts, with the task type of
+// the purpose.
+func (v *VertexEmbedder) EmbedBatch(texts []string, purpose Purpose) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, nil
 	}
@@ -68,11 +79,19 @@ func (v *VertexEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
 		}
 
 		config := &genai.EmbedContentConfig{
-			TaskType:     "RETRIEVAL_DOCUMENT",
+			TaskType:     purpose.TaskType(),
 			AutoTruncate: true,
 		}
+		if v.Dimensions > 0 {
+			config.OutputDimensionality = genai.Ptr(int32(v.Dimensions))
+		}
 
-		resp, err := v.Client.EmbedContent(ctx, v.Model, batch, config)
+		var resp *genai.EmbedContentResponse
+		err := v.Policy.Do(ctx, policy.EstimateTokens(chunkTexts...), func(ctx context.Context) error {
+			var err error
+			resp, err = v.Client.EmbedContent(ctx, v.Model, batch, config)
+			return err
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to embed content batch (chunk %d-%d): %w", i, end, err)
 		}
@@ -96,3 +115,12 @@ func (v *VertexEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
 
 	return allEmbeddings, nil
 }
+
+// modelName qualifies model with the requested dimensions, as vectors of
+// different sizes from one model are not comparable.
+func modelName(model string, dimensions int) string {
+	if dimensions > 0 {
+		return fmt.Sprintf("%s@%d", model, dimensions)
+	}
+	return model
+}
@@ -3,7 +3,9 @@ package embedding
 import (
 	"context"
 	"fmt"
+	"graphdb/internal/policy"
 	"testing"
+	"time"
 
 	"google.golang.org/genai"
 )
@@ -42,7 +44,7 @@ func TestVertexEmbedder_EmbedBatch_Chunking(t *testing.T) {
 		texts[i] = fmt.Sprintf("text-%d", i)
 	}
 
-	res, err := embedder.EmbedBatch(texts)
+	res, err := embedder.EmbedBatch(texts, PurposeDocument)
 	if err != nil {
 		t.Fatalf("EmbedBatch failed: %v", err)
 	}
@@ -88,7 +90,7 @@ func TestVertexEmbedder_EmbedBatch_MismatchError(t *testing.T) {
 	}
 
 	texts := []string{"a", "b", "c"}
-	_, err := embedder.EmbedBatch(texts)
+	_, err := embedder.EmbedBatch(texts, PurposeDocument)
 	if err == nil {
 		t.Fatal("Expected error due to mismatch, got nil")
 	}
@@ -102,7 +104,7 @@ func TestVertexEmbedder_EmbedBatch_Empty(t *testing.T) {
 		Client: &mockModelClient{},
 	}
 
-	res, err := embedder.EmbedBatch(nil)
+	res, err := embedder.EmbedBatch(nil, PurposeDocument)
 	if err != nil {
 		t.Errorf("Expected nil error for nil input, got %v", err)
 	}
@@ -136,7 +138,7 @@ func TestVertexEmbedder_EmbedBatch_EmptyStrings(t *testing.T) {
 
 	// Mix of empty and non-empty strings
 	texts := []string{"hello", "", "world", ""}
-	res, err := embedder.EmbedBatch(texts)
+	res, err := embedder.EmbedBatch(texts, PurposeDocument)
 	if err != nil {
 		t.Fatalf("EmbedBatch failed: %v", err)
 	}
@@ -160,8 +162,75 @@ func TestVertexEmbedder_EmbedBatch_AutoTruncate(t *testing.T) {
 	}
 
 	embedder := &VertexEmbedder{Client: mock, Model: "test-model"}
-	_, err := embedder.EmbedBatch([]string{"test"})
+	_, err := embedder.EmbedBatch([]string{"test"}, PurposeDocument)
 	if err != nil {
 		t.Fatalf("EmbedBatch failed: %v", err)
 	}
 }
+
+func TestVertexEmbedder_EmbedBatch_RetriesRateLimit(t *testing.T) {
+	callCount := 0
+	mock := &mockModelClient{
+		embedFunc: func(ctx context.Context, model string, contents []*genai.Content, config *genai.EmbedContentConfig) (*genai.EmbedContentResponse, error) {
+			callCount++
+			if callCount == 1 {
+				return nil, genai.APIError{Code: 429, Message: "Resource exhausted"}
+			}
+			embeddings := make([]*genai.ContentEmbedding, len(contents))
+			for i := range contents {
+				embeddings[i] = &genai.ContentEmbedding{Values: []float32{1.0}}
+			}
+			return &genai.EmbedContentResponse{Embeddings: embeddings}, nil
+		},
+	}
+
+	embedder := &VertexEmbedder{
+		Client: mock,
+		Model:  "test-model",
+		Policy: &policy.Policy{MaxRetries: 2, InitialBackoff: time.Millisecond},
+	}
+	res, err := embedder.EmbedBatch([]string{"test"}, PurposeDocument)
+	if err != nil {
+		t.Fatalf("Expected the batch to succeed after a retry, got %v", err)
+	}
+	if callCount != 2 || len(res) != 1 {
+		t.Errorf("Expected 2 calls and 1 embedding, got %d calls and %d embeddings", callCount, len(res))
+	}
+}
+
+func TestVertexEmbedder_EmbedBatch_PurposeAndDimensions(t *testing.T) {
+	var taskTypes []string
+	var dims []*int32
+	mock := &mockModelClient{
+		embedFunc: func(ctx context.Context, model string, contents []*genai.Content, config *genai.EmbedContentConfig) (*genai.EmbedContentResponse, error) {
+			taskTypes = append(taskTypes, config.TaskType)
+			dims = append(dims, config.OutputDimensionality)
+			return &genai.EmbedContentResponse{Embeddings: []*genai.ContentEmbedding{{Values: []float32{1.0}}}}, nil
+		},
+	}
+
+	embedder := &VertexEmbedder{Client: mock, Model: "test-model"}
+	for _, purpose := range []Purpose{PurposeDocument, PurposeQuery, PurposeCodeQuery} {
+		if _, err := embedder.EmbedBatch([]string{"test"}, purpose); err != nil {
+			t.Fatalf("EmbedBatch failed: %v", err)
+		}
+	}
+	want := []string{"RETRIEVAL_DOCUMENT", "RETRIEVAL_QUERY", "CODE_RETRIEVAL_QUERY"}
+	for i := range want {
+		if taskTypes[i] != want[i] {
+			t.Errorf("Call %d: expected task type %s, got %s", i, want[i], taskTypes[i])
+		}
+	}
+	if dims[0] != nil || embedder.ModelName() != "test-model" {
+		t.Errorf("Expected no output dimensionality by default")
+	}
+
+	embedder.Dimensions = 768
+	embedder.EmbedBatch([]string{"test"}, PurposeDocument)
+	if d := dims[len(dims)-1]; d == nil || *d != 768 {
+		t.Errorf("Expected output dimensionality 768, got %v", d)
+	}
+	if embedder.ModelName() != "test-model@768" {
+		t.Errorf("Expected the dimensions in the model name, got %s", embedder.ModelName())
+	}
+}
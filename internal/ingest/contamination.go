@@ -0,0 +1,466 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"graphdb/internal/graph"
+)
+
+// Contamination kinds of the default rules. Each kind is written to functions
+// as "<kind>_contaminated".
+const (
+	ContaminationUI = "ui"
+	ContaminationDB = "db"
+	ContaminationIO = "io"
+)
+
+// ContaminationRule recognizes the functions that depend directly on one
+// layer (UI, database, I/O). A function matching any of the lists is a seed.
+type ContaminationRule struct {
+	Namespaces  []string `json:"namespaces,omitempty"`   // Its namespace, package or module, or one they are nested in
+	Imports     []string `json:"imports,omitempty"`      // Something its file imports, or a parent of it
+	Files       []string `json:"files,omitempty"`        // .gitignore-style globs of its file path
+	BaseClasses []string `json:"base_classes,omitempty"` // Fully qualified base classes of its class
+}
+
+// ContaminationRules maps contamination kinds to the rule finding their seeds.
+type ContaminationRules map[string]ContaminationRule
+
+// DefaultContaminationRules returns the built-in rules for the common UI,
+// database and I/O frameworks of the supported languages.
+func DefaultContaminationRules() ContaminationRules {
+	return ContaminationRules{
+		ContaminationUI: {
+			Namespaces: []string{"System.Web.UI", "System.Windows.Forms", "System.Windows.Controls"},
+			Imports: []string{
+				"System.Web.UI", "System.Windows.Forms", "System.Windows.Controls",
+				"react", "react-dom", "@angular/core", "vue", "javax.swing", "java.awt", "tkinter", "PyQt5", "PySide6",
+			},
+			Files: []string{"*.aspx", "*.ascx", "*.aspx.cs", "*.ascx.cs", "*.aspx.vb", "*.ascx.vb", "*.master.cs", "*.tsx", "*.jsx", "*.vue"},
+			BaseClasses: []string{
+				"System.Web.UI.Page", "System.Web.UI.UserControl", "System.Web.UI.MasterPage",
+				"System.Windows.Forms.Form", "System.Windows.Forms.UserControl", "React.Component", "javax.swing.JFrame",
+			},
+		},
+		ContaminationDB: {
+			Imports: []string{
+				"System.Data", "System.Data.Entity", "Microsoft.EntityFrameworkCore", "Dapper", "NHibernate",
+				"database/sql", "gorm.io", "github.com/jackc/pgx", "go.mongodb.org",
+				"java.sql", "javax.persistence", "jakarta.persistence",
+				"sqlite3", "sqlalchemy", "psycopg2", "pymongo", "django.db",
+				"typeorm", "mongoose", "pg", "mysql", "mysql2", "sequelize", "@prisma/client",
+			},
+			Files:       []string{"*.sql"},
+			BaseClasses: []string{"System.Data.Entity.DbContext", "Microsoft.EntityFrameworkCore.DbContext"},
+		},
+		ContaminationIO: {
+			Imports: []string{
+				"System.IO", "System.Net",
+				"os", "io/ioutil", "net", "net/http",
+				"java.io", "java.nio", "java.net",
+				"shutil", "socket", "requests", "urllib",
+				"fs", "http", "https", "axios",
+				"fstream", "filesystem",
+			},
+		},
+	}
+}
+
+// LoadContaminationRules reads rules from a JSON file, e.g.
+// {"ui": {"base_classes": ["MyApp.BasePage"]}}, over the defaults: each kind
+// in the file replaces the default rule of that kind, and an empty rule
+// disables it.
+func LoadContaminationRules(path string) (ContaminationRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contamination rules: %w", err)
+	}
+	var custom ContaminationRules
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("failed to parse contamination rules %s: %w", path, err)
+	}
+
+	rules := DefaultContaminationRules()
+	for kind, rule := range custom {
+		if !validKind.MatchString(kind) {
+			return nil, fmt.Errorf("invalid contamination kind %q in %s", kind, path)
+		}
+		rules[kind] = rule
+	}
+	return rules, nil
+}
+
+var validKind = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Kinds returns the contamination kinds, sorted.
+func (r ContaminationRules) Kinds() []string {
+	kinds := make([]string, 0, len(r))
+	for kind := range r {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Classify returns the seed kinds of each function of a parsed file, keyed by
+// function ID. Functions matching no rule are left out. An import only seeds
+// the functions that mention a name it binds, unless it binds none the code
+// can be searched for (C# using, #include), in which case it seeds the file.
+func (r ContaminationRules) Classify(path string, content []byte, nodes []*graph.Node, edges []*graph.Edge) map[string][]string {
+	slashed := strings.TrimPrefix(filepath.ToSlash(path), "/")
+	imports := fileImports(path, content)
+	names := importNames(imports)
+	lines := strings.Split(string(content), "\n")
+	classes := enclosingClasses(nodes, edges)
+	bases := baseClasses(edges)
+
+	seeds := make(map[string][]string)
+	for _, kind := range r.Kinds() {
+		rule := r[kind]
+		fileSeed := matchAny(rule.Files, slashed, false)
+		var locals []string // Names bound by the imports of this kind
+		for _, imp := range imports {
+			if !anyUnder([]string{imp.Name}, rule.Imports) {
+				continue
+			}
+			if len(imp.Locals) == 0 {
+				fileSeed = true
+			}
+			locals = append(locals, imp.Locals...)
+		}
+
+		for _, n := range nodes {
+			if !isCallable(n) {
+				continue
+			}
+			class := classes[n.ID]
+			seed := fileSeed ||
+				mentionsAny(n, lines, locals) ||
+				anyUnder(namespaces(n, class), rule.Namespaces) ||
+				(class != nil && inheritsAny(bases[class.ID], rule.BaseClasses, names, namespaces(class, nil)))
+			if seed {
+				seeds[n.ID] = append(seeds[n.ID], kind)
+			}
+		}
+	}
+	return seeds
+}
+
+// mentionsAny reports whether the source lines of a function contain one of
+// words as a whole identifier. A function without line numbers is assumed to.
+func mentionsAny(n *graph.Node, lines []string, words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	start, end := intProperty(n, "start_line"), intProperty(n, "end_line")
+	if start == 0 || end < start || start > len(lines) {
+		return true
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	body := strings.Join(lines[start-1:end], "\n")
+	for _, word := range words {
+		for i := 0; ; {
+			j := strings.Index(body[i:], word)
+			if j < 0 {
+				break
+			}
+			j += i
+			if !isIdentByte(body, j-1) && !isIdentByte(body, j+len(word)) {
+				return true
+			}
+			i = j + 1
+		}
+	}
+	return false
+}
+
+func isIdentByte(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func isCallable(n *graph.Node) bool {
+	return n.Label == "Function" || n.Label == "Method"
+}
+
+// namespaces returns the namespace, package or module of a node, falling
+// back to those of its class.
+func namespaces(n, class *graph.Node) []string {
+	var names []string
+	for _, key := range []string{"namespace", "package", "module"} {
+		if name, ok := n.Properties[key].(string); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 && class != nil {
+		return namespaces(class, nil)
+	}
+	return names
+}
+
+// enclosingClasses maps each function of a file to its class, from HAS_METHOD
+// edges or, as not every parser emits them, the innermost class whose lines
+// contain the function.
+func enclosingClasses(nodes []*graph.Node, edges []*graph.Edge) map[string]*graph.Node {
+	byID := make(map[string]*graph.Node)
+	var classes []*graph.Node
+	for _, n := range nodes {
+		byID[n.ID] = n
+		if n.Label == "Class" {
+			classes = append(classes, n)
+		}
+	}
+
+	enclosing := make(map[string]*graph.Node)
+	for _, e := range edges {
+		if e.Type != "HAS_METHOD" {
+			continue
+		}
+		if class, ok := byID[e.SourceID]; ok {
+			enclosing[e.TargetID] = class
+		}
+	}
+	for _, n := range nodes {
+		if !isCallable(n) || enclosing[n.ID] != nil {
+			continue
+		}
+		line := intProperty(n, "start_line")
+		if line == 0 {
+			continue
+		}
+		var best *graph.Node
+		for _, class := range classes {
+			start, end := intProperty(class, "start_line"), intProperty(class, "end_line")
+			if start <= line && line <= end && (best == nil || start > intProperty(best, "start_line")) {
+				best = class
+			}
+		}
+		if best != nil {
+			enclosing[n.ID] = best
+		}
+	}
+	return enclosing
+}
+
+// baseClasses maps each class of a file to the names of its base classes, as
+// written in the source (e.g. "Page" or "System.Web.UI.Page").
+func baseClasses(edges []*graph.Edge) map[string][]string {
+	bases := make(map[string][]string)
+	for _, e := range edges {
+		if e.Type != "INHERITS" && e.Type != "EXTENDS" && e.Type != "IMPLEMENTS" {
+			continue
+		}
+		name := e.TargetID
+		if i := strings.LastIndex(name, ":"); i != -1 {
+			name = name[i+1:]
+		}
+		if i := strings.Index(name, "<"); i != -1 {
+			name = name[:i]
+		}
+		bases[e.SourceID] = append(bases[e.SourceID], strings.TrimSpace(name))
+	}
+	return bases
+}
+
+// inheritsAny reports whether one of bases is one of the fully qualified
+// classes. A base written without its namespace matches if the file imports
+// that namespace or the class lives in it.
+func inheritsAny(bases, classes, imports, classNamespaces []string) bool {
+	for _, base := range bases {
+		for _, qualified := range classes {
+			if base == qualified {
+				return true
+			}
+			if !strings.HasSuffix(qualified, "."+base) {
+				continue
+			}
+			namespace := strings.TrimSuffix(qualified, "."+base)
+			if anyUnder([]string{namespace}, imports) || anyUnder(classNamespaces, []string{namespace}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// anyUnder reports whether one of names is one of parents or nested in one,
+// with "." or "/" separating the levels.
+func anyUnder(names, parents []string) bool {
+	for _, name := range names {
+		for _, parent := range parents {
+			if name == parent || strings.HasPrefix(name, parent+".") || strings.HasPrefix(name, parent+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Import statements, one line at a time
+var (
+	usingPattern   = regexp.MustCompile(`^\s*using\s+(?:static\s+)?(?:\w+\s*=\s*)?([\w.]+)\s*;`) // C#
+	vbImports      = regexp.MustCompile(`^\s*Imports\s+(?:\w+\s*=\s*)?([\w.]+)`)                 // VB.NET
+	fromPattern    = regexp.MustCompile(`^\s*from\s+([\w.]+)\s+import\b(.*)`)                    // Python
+	includePattern = regexp.MustCompile(`^\s*#\s*include\s*[<"]([^>"]+)[>"]`)                    // C, C++
+	esFromPattern  = regexp.MustCompile(`\bfrom\s+['"]([^'"]+)['"]`)                             // TypeScript, JavaScript
+	esImport       = regexp.MustCompile(`^\s*import\s+['"]([^'"]+)['"]`)
+	esClause       = regexp.MustCompile(`\bimport\s+(?:type\s+)?(.+?)\s+from\s+['"]([^'"]+)['"]`)
+	requirePattern = regexp.MustCompile(`\brequire\(\s*['"]([^'"]+)['"]\s*\)`)
+	requireBinding = regexp.MustCompile(`\b(?:const|let|var)\s+(\{[^}]*\}|[\w$]+)\s*=\s*require\(\s*['"]([^'"]+)['"]`)
+	goImport       = regexp.MustCompile(`^\s*(?:import\s+)?(?:([\w.]+)\s+)?"([^"]+)"`)
+
+	// Java and Python: "import a.b.C;", "import a.b.*;", "import a.b as c, d"
+	importPattern = regexp.MustCompile(`^\s*import\s+(?:static\s+)?([\w.*]+(?:\s+as\s+\w+)?(?:\s*,\s*[\w.]+(?:\s+as\s+\w+)?)*)\s*;?\s*$`)
+
+	identifier = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+)
+
+// fileImport is one thing a source file imports.
+type fileImport struct {
+	Name   string   // Module, package, namespace or header
+	Locals []string // Names it binds in the file; nil if they cannot be told
+}
+
+// importNames returns the names of imports.
+func importNames(imports []fileImport) []string {
+	names := make([]string, len(imports))
+	for i, imp := range imports {
+		names[i] = imp.Name
+	}
+	return names
+}
+
+// fileImports returns what a source file imports, from the import, using,
+// Imports, #include and require statements of the supported languages, with
+// the names each binds where the statement tells them.
+func fileImports(path string, content []byte) []fileImport {
+	var imports []fileImport
+	add := func(name string, locals ...string) {
+		name = strings.TrimSpace(name)
+		if strings.HasSuffix(name, ".*") {
+			name, locals = strings.TrimSuffix(name, ".*"), nil
+		}
+		if name != "" {
+			imports = append(imports, fileImport{Name: name, Locals: identifiers(locals)})
+		}
+	}
+
+	goFile := filepath.Ext(path) == ".go"
+	javaFile := filepath.Ext(path) == ".java"
+	inGoBlock := false
+	for _, line := range strings.Split(string(content), "\n") {
+		if goFile {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(trimmed, "import ("):
+				inGoBlock = true
+			case inGoBlock && trimmed == ")":
+				inGoBlock = false
+			case inGoBlock || strings.HasPrefix(trimmed, "import "):
+				if m := goImport.FindStringSubmatch(trimmed); m != nil {
+					add(m[2], goPackageName(m[1], m[2]))
+				}
+			}
+			continue
+		}
+
+		if m := importPattern.FindStringSubmatch(line); m != nil {
+			for _, name := range strings.Split(m[1], ",") {
+				fields := strings.Fields(name)
+				switch {
+				case len(fields) == 0:
+				case len(fields) == 3:
+					add(fields[0], fields[2]) // "import a.b as c" binds c
+				case javaFile:
+					add(fields[0], fields[0][strings.LastIndex(fields[0], ".")+1:])
+				default:
+					add(fields[0], strings.Split(fields[0], ".")[0]) // "import a.b" binds a
+				}
+			}
+			continue
+		}
+		if m := fromPattern.FindStringSubmatch(line); m != nil {
+			add(m[1], boundNames(strings.Trim(strings.TrimSpace(m[2]), "()\\"), " as ")...)
+			continue
+		}
+		for _, pattern := range []*regexp.Regexp{usingPattern, vbImports, includePattern, esImport} {
+			if m := pattern.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			}
+		}
+
+		// ES modules and CommonJS bind names only when the statement is on one line
+		bound := make(map[string][]string)
+		for _, m := range esClause.FindAllStringSubmatch(line, -1) {
+			bound[m[2]] = boundNames(strings.NewReplacer("{", ",", "}", ",").Replace(m[1]), " as ")
+		}
+		for _, m := range requireBinding.FindAllStringSubmatch(line, -1) {
+			bound[m[2]] = boundNames(strings.Trim(m[1], "{}"), ":")
+		}
+		for _, pattern := range []*regexp.Regexp{esFromPattern, requirePattern} {
+			for _, m := range pattern.FindAllStringSubmatch(line, -1) {
+				add(m[1], bound[m[1]]...)
+			}
+		}
+	}
+	return imports
+}
+
+// goPackageName returns the name a Go import binds: its alias, or the last
+// element of its path that is not a major version. Blank and dot imports
+// bind no name.
+func goPackageName(alias, path string) string {
+	if alias != "" {
+		if alias == "_" || alias == "." {
+			return ""
+		}
+		return alias
+	}
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
+
+// boundNames returns the local names of a comma-separated import list such
+// as "a, b as c" or "* as d", where sep separates a name from its alias. A
+// "*" without an alias binds nothing that can be searched for.
+func boundNames(list, sep string) []string {
+	var names []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if i := strings.LastIndex(item, sep); i != -1 {
+			item = strings.TrimSpace(item[i+len(sep):])
+		}
+		if item == "*" {
+			return nil
+		}
+		if item != "" {
+			names = append(names, item)
+		}
+	}
+	return names
+}
+
+// identifiers returns names if all of them are identifiers, nil otherwise.
+func identifiers(names []string) []string {
+	for _, name := range names {
+		if !identifier.MatchString(name) {
+			return nil
+		}
+	}
+	return names
+}
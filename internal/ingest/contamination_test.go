@@ -0,0 +1,167 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"graphdb/internal/analysis"
+	"graphdb/internal/graph"
+)
+
+func TestFileImports(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    []string
+	}{
+		{"Page.cs", "using System;\nusing static System.Math;\nusing Web = System.Web.UI;\nusing (var x = Open()) {}\n", []string{"System", "System.Math", "System.Web.UI"}},
+		{"Page.vb", "Imports System.Data.SqlClient\n", []string{"System.Data.SqlClient"}},
+		{"Repo.java", "import java.sql.Connection;\nimport static java.util.Objects.*;\n", []string{"java.sql.Connection", "java.util.Objects"}},
+		{"app.py", "import os, json as j\nfrom sqlalchemy.orm import Session\n", []string{"os", "json", "sqlalchemy.orm"}},
+		{"main.cpp", "#include <fstream>\n#include \"util.h\"\n", []string{"fstream", "util.h"}},
+		{"App.tsx", "import React from 'react';\nimport {\n  x,\n} from \"./x\";\nimport './styles.css';\nconst fs = require('fs');\n", []string{"react", "./x", "./styles.css", "fs"}},
+		{"main.go", "package main\n\nimport \"os\"\n\nimport (\n\t\"database/sql\"\n\tpgx \"github.com/jackc/pgx/v5\"\n)\n\nvar s = \"not/an/import\"\n", []string{"os", "database/sql", "github.com/jackc/pgx/v5"}},
+	}
+	for _, tt := range tests {
+		if got := importNames(fileImports(tt.path, []byte(tt.content))); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestFileImports_Locals(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    map[string][]string
+	}{
+		{"Page.cs", "using System.IO;\n", map[string][]string{"System.IO": nil}},
+		{"Repo.java", "import java.io.File;\nimport java.net.*;\n", map[string][]string{"java.io.File": {"File"}, "java.net": nil}},
+		{"app.py", "import os.path, json as j\nfrom requests import get, post as p\nfrom shutil import *\n", map[string][]string{
+			"os.path": {"os"}, "json": {"j"}, "requests": {"get", "p"}, "shutil": nil,
+		}},
+		{"app.ts", "import fs from 'fs';\nimport * as http from 'http';\nimport { get as g, request } from 'https';\nimport {\n  x,\n} from \"net\";\nconst { readFile: rf } = require('fs/promises');\n", map[string][]string{
+			"fs": {"fs"}, "http": {"http"}, "https": {"g", "request"}, "net": nil, "fs/promises": {"rf"},
+		}},
+		{"main.go", "import (\n\t\"os\"\n\tpgx \"github.com/jackc/pgx/v5\"\n\t\"github.com/jackc/pgx/v5\"\n\t_ \"github.com/lib/pq\"\n)\n", map[string][]string{
+			"os": {"os"}, "github.com/jackc/pgx/v5": {"pgx"}, "github.com/lib/pq": nil,
+		}},
+	}
+	for _, tt := range tests {
+		got := make(map[string][]string)
+		for _, imp := range fileImports(tt.path, []byte(tt.content)) {
+			got[imp.Name] = imp.Locals
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestContaminationRules_Classify(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		// A bare "Page" is only the framework class if its namespace is imported
+		"Default.cs": `namespace Shop.Web
+{
+    public class DefaultPage : System.Web.UI.Page
+    {
+        protected void Page_Load(object sender, EventArgs e)
+        {
+            Render();
+        }
+    }
+
+    public class Formatter : Page
+    {
+        public string Format(decimal price)
+        {
+            return price.ToString();
+        }
+    }
+}
+`,
+		"store.py": "import sqlite3\n\ndef load(id):\n    return sqlite3.connect('db').execute(id)\n",
+		"pure.py":  "def add(a, b):\n    return a + b\n",
+		// Only the function that uses os is an I/O seed
+		"paths.py": "import os\n\ndef home():\n    return os.environ['HOME']\n\ndef join(a, b):\n    return a + '/' + b\n",
+	}
+
+	rules := DefaultContaminationRules()
+	seeds := make(map[string][]string)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		parser, _ := analysis.GetParser(filepath.Ext(name))
+		nodes, edges, err := parser.Parse(path, []byte(content))
+		if err != nil {
+			t.Fatalf("Parse %s failed: %v", name, err)
+		}
+		for _, n := range nodes {
+			if isCallable(n) {
+				name, _ := n.Properties["name"].(string)
+				seeds[name] = rules.Classify(path, []byte(content), nodes, edges)[n.ID]
+			}
+		}
+	}
+
+	want := map[string][]string{
+		"Page_Load": {ContaminationUI},
+		"Format":    nil,
+		"load":      {ContaminationDB},
+		"add":       nil,
+		"home":      {ContaminationIO},
+		"join":      nil,
+	}
+	for name, kinds := range want {
+		if got, ok := seeds[name]; !ok || !reflect.DeepEqual(got, kinds) {
+			t.Errorf("%s: expected seeds %v, got %v (parsed: %v)", name, kinds, got, ok)
+		}
+	}
+}
+
+func TestContaminationRules_FilesAndNamespaces(t *testing.T) {
+	rules := ContaminationRules{
+		"ui": {Files: []string{"**/Views/**"}},
+		"db": {Namespaces: []string{"Shop.Data"}},
+	}
+	view := &graph.Node{ID: "Views/Cart.cs:Render", Label: "Function", Properties: map[string]interface{}{"name": "Render"}}
+	if seeds := rules.Classify("/src/Views/Cart.cs", nil, []*graph.Node{view}, nil); !reflect.DeepEqual(seeds[view.ID], []string{"ui"}) {
+		t.Errorf("Expected a UI seed from the file pattern, got %v", seeds)
+	}
+
+	// C# methods take the namespace of their class
+	class := &graph.Node{ID: "Repo.cs:Shop.Data.Orders.Repo", Label: "Class", Properties: map[string]interface{}{"namespace": "Shop.Data.Orders", "start_line": 1, "end_line": 9}}
+	method := &graph.Node{ID: "Repo.cs:Save", Label: "Function", Properties: map[string]interface{}{"start_line": 3, "end_line": 5}}
+	if seeds := rules.Classify("Repo.cs", nil, []*graph.Node{class, method}, nil); !reflect.DeepEqual(seeds[method.ID], []string{"db"}) {
+		t.Errorf("Expected a DB seed from the enclosing namespace, got %v", seeds)
+	}
+}
+
+func TestLoadContaminationRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(path, []byte(`{"ui": {"base_classes": ["MyApp.BasePage"]}, "io": {}, "cache": {"imports": ["StackExchange.Redis"]}}`), 0644)
+
+	rules, err := LoadContaminationRules(path)
+	if err != nil {
+		t.Fatalf("LoadContaminationRules failed: %v", err)
+	}
+	if !reflect.DeepEqual(rules.Kinds(), []string{"cache", "db", "io", "ui"}) {
+		t.Errorf("Expected the custom kind beside the defaults, got %v", rules.Kinds())
+	}
+	if got := rules["ui"].BaseClasses; !reflect.DeepEqual(got, []string{"MyApp.BasePage"}) {
+		t.Errorf("Expected the file to replace the UI rule, got %v", got)
+	}
+	if len(rules["db"].Imports) == 0 || len(rules["io"].Imports) != 0 {
+		t.Errorf("Expected the DB defaults kept and the IO rule emptied, got %+v", rules)
+	}
+
+	os.WriteFile(path, []byte(`{"UI Layer": {}}`), 0644)
+	if _, err := LoadContaminationRules(path); err == nil {
+		t.Error("Expected an error for a kind that is not a property name")
+	}
+}
@@ -0,0 +1,347 @@
+package ingest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"graphdb/internal/graph"
+)
+
+// Embedding document templates, from least to most text.
+const (
+	// TemplateName embeds the bare function name.
+	TemplateName = "name"
+	// TemplateSignature embeds the kind, enclosing scope, doc comment and signature.
+	TemplateSignature = "signature"
+	// TemplateBody embeds the signature document plus the (truncated) body.
+	TemplateBody = "body"
+)
+
+// Templates lists the valid DocumentBuilder templates.
+var Templates = []string{TemplateName, TemplateSignature, TemplateBody}
+
+const (
+	defaultMaxBodyChars = 6000
+	defaultChunkChars   = 2000
+	maxSignatureLines   = 6
+	maxDocLines         = 30
+)
+
+// DocumentBuilder turns a function node into the text that is embedded for it.
+type DocumentBuilder struct {
+	// Template is one of Templates; empty means TemplateBody.
+	Template string
+	// MaxBodyChars truncates bodies longer than this. Zero uses the default.
+	MaxBodyChars int
+	// ChunkChars splits bodies longer than this into several documents, each
+	// repeating the header, whose vectors are averaged. Zero uses the default.
+	ChunkChars int
+}
+
+// DocumentScope is what surrounds a function: its enclosing class and the
+// source of its file, used to find doc comments.
+type DocumentScope struct {
+	Enclosing string
+	Source    []byte
+}
+
+// template returns the template in effect.
+func (b *DocumentBuilder) template() string {
+	if b == nil || b.Template == "" {
+		return TemplateBody
+	}
+	return b.Template
+}
+
+// Validate reports an unknown template.
+func (b *DocumentBuilder) Validate() error {
+	for _, t := range Templates {
+		if b.template() == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown embedding template %q (expected one of %s)", b.Template, strings.Join(Templates, ", "))
+}
+
+// Documents returns the texts to embed for node: one, or one per chunk of a
+// long body. It returns nil for nodes without a name.
+func (b *DocumentBuilder) Documents(node *graph.Node, scope DocumentScope) []string {
+	name, _ := node.Properties["name"].(string)
+	if name == "" {
+		return nil
+	}
+	template := b.template()
+	if template == TemplateName {
+		return []string{name}
+	}
+
+	content := nodeContent(node, scope.Source)
+	signature := signatureOf(node, content)
+
+	var header strings.Builder
+	fmt.Fprintf(&header, "%s %s\n", strings.ToLower(node.Label), qualifiedName(name, scope.Enclosing))
+	for _, key := range []string{"package", "namespace"} {
+		if v, ok := node.Properties[key].(string); ok && v != "" {
+			fmt.Fprintf(&header, "%s %s\n", key, v)
+		}
+	}
+	if signature != "" {
+		header.WriteString(signature + "\n")
+	}
+
+	doc := docComment(node, scope.Source)
+	if template == TemplateSignature || content == "" {
+		return []string{joinDocument(header.String(), doc)}
+	}
+
+	body := strings.TrimPrefix(strings.TrimSpace(content), signature)
+	if limit := b.maxBodyChars(); len(body) > limit {
+		body = body[:limit]
+	}
+
+	var docs []string
+	for i, chunk := range chunkLines(body, b.chunkChars()) {
+		if i == 0 {
+			docs = append(docs, joinDocument(header.String(), doc, chunk))
+		} else {
+			docs = append(docs, joinDocument(header.String(), chunk))
+		}
+	}
+	if len(docs) == 0 {
+		docs = append(docs, joinDocument(header.String(), doc))
+	}
+	return docs
+}
+
+func (b *DocumentBuilder) maxBodyChars() int {
+	if b == nil || b.MaxBodyChars <= 0 {
+		return defaultMaxBodyChars
+	}
+	return b.MaxBodyChars
+}
+
+func (b *DocumentBuilder) chunkChars() int {
+	if b == nil || b.ChunkChars <= 0 {
+		return defaultChunkChars
+	}
+	return b.ChunkChars
+}
+
+func qualifiedName(name, enclosing string) string {
+	if enclosing == "" || strings.HasPrefix(name, enclosing+".") {
+		return name
+	}
+	return enclosing + "." + name
+}
+
+func joinDocument(parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// nodeContent returns the source of the definition, from its content property
+// or from its line span in the file.
+func nodeContent(node *graph.Node, source []byte) string {
+	if content, ok := node.Properties["content"].(string); ok {
+		return content
+	}
+	start, end := intProperty(node, "start_line"), intProperty(node, "end_line")
+	if start <= 0 || end < start || source == nil {
+		return ""
+	}
+	lines := strings.Split(string(source), "\n")
+	if start > len(lines) {
+		return ""
+	}
+	return strings.Join(lines[start-1:min(end, len(lines))], "\n")
+}
+
+// signatureOf returns the declaration header: the lines of the definition up
+// to the one that opens its body.
+func signatureOf(node *graph.Node, content string) string {
+	if sig, ok := node.Properties["signature"].(string); ok && sig != "" {
+		return sig
+	}
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	for i, line := range lines {
+		if i >= maxSignatureLines {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.Contains(trimmed, "{") || strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, "=>") {
+			return strings.TrimSpace(strings.Join(lines[:i+1], "\n"))
+		}
+	}
+	if len(lines) > 0 {
+		return strings.TrimSpace(lines[0])
+	}
+	return ""
+}
+
+// docComment returns the comment block directly above the definition, or a
+// Python docstring at the start of its body.
+func docComment(node *graph.Node, source []byte) string {
+	if doc := docstring(nodeContent(node, source)); doc != "" {
+		return doc
+	}
+	start := intProperty(node, "start_line")
+	if start <= 1 || source == nil {
+		return ""
+	}
+	lines := strings.Split(string(source), "\n")
+	if start > len(lines) {
+		return ""
+	}
+
+	var doc []string
+	for i := start - 2; i >= 0 && len(doc) < maxDocLines; i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		if !isCommentLine(trimmed) {
+			// Skip attributes and decorators between the comment and the definition
+			if len(doc) == 0 && (strings.HasPrefix(trimmed, "@") || strings.HasPrefix(trimmed, "[")) {
+				continue
+			}
+			break
+		}
+		doc = append([]string{trimmed}, doc...)
+	}
+	return strings.Join(doc, "\n")
+}
+
+func isCommentLine(line string) bool {
+	for _, prefix := range []string{"//", "#", "/*", "*", "--", "'"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// docstring returns a """ or ”' string literal at the start of a Python body.
+func docstring(content string) string {
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines) && i < maxSignatureLines; i++ {
+		if !strings.HasSuffix(strings.TrimSpace(lines[i]), ":") {
+			continue
+		}
+		rest := strings.TrimSpace(strings.Join(lines[i+1:], "\n"))
+		for _, quote := range []string{`"""`, `'''`} {
+			if !strings.HasPrefix(rest, quote) {
+				continue
+			}
+			if end := strings.Index(rest[len(quote):], quote); end >= 0 {
+				return strings.TrimSpace(rest[len(quote) : len(quote)+end])
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// chunkLines splits text at line boundaries into pieces of at most size
+// characters (a single longer line is cut).
+func chunkLines(text string, size int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var chunks []string
+	var current strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		for len(line) > size {
+			if current.Len() > 0 {
+				chunks = append(chunks, current.String())
+				current.Reset()
+			}
+			chunks = append(chunks, line[:size])
+			line = line[size:]
+		}
+		if current.Len() > 0 && current.Len()+1+len(line) > size {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteByte('\n')
+		}
+		current.WriteString(line)
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// enclosingScopes maps each method of a file to the name of its class, from
+// HAS_METHOD edges, falling back to a Go receiver type.
+func enclosingScopes(nodes []*graph.Node, edges []*graph.Edge) map[string]string {
+	names := make(map[string]string)
+	for _, n := range nodes {
+		if name, ok := n.Properties["name"].(string); ok {
+			names[n.ID] = name
+		}
+	}
+
+	scopes := make(map[string]string)
+	for _, e := range edges {
+		if e.Type != "HAS_METHOD" {
+			continue
+		}
+		if name, ok := names[e.SourceID]; ok {
+			scopes[e.TargetID] = name
+		}
+	}
+	for _, n := range nodes {
+		if _, ok := scopes[n.ID]; ok {
+			continue
+		}
+		if recv, ok := n.Properties["receiver"].(string); ok && recv != "" {
+			scopes[n.ID] = recv
+		}
+	}
+	return scopes
+}
+
+// meanVector averages the chunk vectors of one function and normalizes the
+// result, so it stays comparable by cosine similarity.
+func meanVector(vectors [][]float32) []float32 {
+	if len(vectors) == 1 {
+		return vectors[0]
+	}
+	mean := make([]float32, len(vectors[0]))
+	for _, v := range vectors {
+		for i := range mean {
+			if i < len(v) {
+				mean[i] += v[i]
+			}
+		}
+	}
+	var norm float64
+	for _, x := range mean {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return mean
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range mean {
+		mean[i] *= scale
+	}
+	return mean
+}
+
+func intProperty(node *graph.Node, key string) int {
+	switch v := node.Properties[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package ingest
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"graphdb/internal/graph"
+)
+
+func TestDocumentBuilder_GoMethod(t *testing.T) {
+	source := `package store
+
+// Save writes the record to disk.
+// It overwrites existing records.
+func (r *Repo) Save(rec Record) error {
+	return r.db.Put(rec.ID, rec)
+}
+`
+	node := &graph.Node{
+		ID:    "store:Repo.Save",
+		Label: "Function",
+		Properties: map[string]interface{}{
+			"name":       "Save",
+			"receiver":   "Repo",
+			"package":    "example.com/store",
+			"start_line": 5,
+			"end_line":   7,
+			"content":    "func (r *Repo) Save(rec Record) error {\n\treturn r.db.Put(rec.ID, rec)\n}",
+		},
+	}
+	scope := DocumentScope{Enclosing: "Repo", Source: []byte(source)}
+
+	docs := (&DocumentBuilder{}).Documents(node, scope)
+	want := "function Repo.Save\n" +
+		"package example.com/store\n" +
+		"func (r *Repo) Save(rec Record) error {\n" +
+		"// Save writes the record to disk.\n// It overwrites existing records.\n" +
+		"return r.db.Put(rec.ID, rec)\n}"
+	if len(docs) != 1 || docs[0] != want {
+		t.Errorf("Unexpected body document:\n%q\nwant\n%q", docs, want)
+	}
+
+	docs = (&DocumentBuilder{Template: TemplateSignature}).Documents(node, scope)
+	if len(docs) != 1 || strings.Contains(docs[0], "r.db.Put") || !strings.Contains(docs[0], "It overwrites") {
+		t.Errorf("Expected signature and doc comment only, got %q", docs)
+	}
+
+	docs = (&DocumentBuilder{Template: TemplateName}).Documents(node, scope)
+	if len(docs) != 1 || docs[0] != "Save" {
+		t.Errorf("Expected bare name, got %q", docs)
+	}
+}
+
+func TestDocumentBuilder_PythonDocstring(t *testing.T) {
+	node := &graph.Node{
+		ID:    "app.py:Cart.total",
+		Label: "Function",
+		Properties: map[string]interface{}{
+			"name":    "total",
+			"content": "def total(self,\n          tax=0):\n    \"\"\"Sum of line items.\"\"\"\n    return sum(self.items) * (1 + tax)",
+		},
+	}
+
+	docs := (&DocumentBuilder{Template: TemplateSignature}).Documents(node, DocumentScope{Enclosing: "Cart"})
+	want := "function Cart.total\ndef total(self,\n          tax=0):\nSum of line items."
+	if len(docs) != 1 || docs[0] != want {
+		t.Errorf("Unexpected document:\n%q\nwant\n%q", docs, want)
+	}
+}
+
+func TestDocumentBuilder_Chunks(t *testing.T) {
+	var body strings.Builder
+	body.WriteString("function process(items) {\n")
+	for i := 0; i < 100; i++ {
+		body.WriteString("    handle(items[" + strings.Repeat("x", 20) + "]);\n")
+	}
+	body.WriteString("}")
+	node := &graph.Node{
+		ID:         "a.ts:process",
+		Label:      "Function",
+		Properties: map[string]interface{}{"name": "process", "content": body.String()},
+	}
+
+	b := &DocumentBuilder{MaxBodyChars: 1000, ChunkChars: 400}
+	docs := b.Documents(node, DocumentScope{})
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 chunks of a 1000 character body, got %d", len(docs))
+	}
+	for i, doc := range docs {
+		if !strings.HasPrefix(doc, "function process\nfunction process(items) {\n") {
+			t.Errorf("Chunk %d does not repeat the header: %q", i, doc)
+		}
+		if body := strings.SplitN(doc, "\n", 3)[2]; len(body) > 400 {
+			t.Errorf("Chunk %d is %d characters", i, len(body))
+		}
+	}
+
+	if err := (&DocumentBuilder{Template: "everything"}).Validate(); err == nil {
+		t.Error("Expected unknown template to be rejected")
+	}
+	if err := (&DocumentBuilder{}).Validate(); err != nil {
+		t.Errorf("Expected default template to be valid: %v", err)
+	}
+}
+
+func TestMeanVector(t *testing.T) {
+	v := meanVector([][]float32{{1, 0}, {0, 1}})
+	if math.Abs(float64(v[0])-math.Sqrt2/2) > 1e-6 || math.Abs(float64(v[1])-math.Sqrt2/2) > 1e-6 {
+		t.Errorf("Expected normalized mean, got %v", v)
+	}
+	single := []float32{3, 4}
+	if v := meanVector([][]float32{single}); &v[0] != &single[0] {
+		t.Errorf("Expected a single vector to be returned as is")
+	}
+}
+
+func TestWorkerPool_EmbeddingProvenance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.py")
+	os.WriteFile(path, []byte("class Cart:\n    def total(self):\n        return 1\n"), 0644)
+
+	embedder := &countingEmbedder{}
+	emitter := &MockEmitter{}
+	wp := NewWorkerPool(1, embedder, emitter)
+	wp.EmbeddingModel = "test-model"
+	wp.Start()
+	wp.Submit(path)
+	wp.Stop()
+
+	if len(embedder.texts) != 1 || !strings.HasPrefix(embedder.texts[0], "function Cart.total\n") {
+		t.Fatalf("Expected the method document to be embedded, got %q", embedder.texts)
+	}
+	for _, n := range emitter.Nodes {
+		if n.Label != "Function" {
+			continue
+		}
+		if n.Properties["embedding_template"] != TemplateBody || n.Properties["embedding_model"] != "test-model" {
+			t.Errorf("Expected provenance on %s, got %v", n.ID, n.Properties)
+		}
+		if _, ok := n.Properties["embedding"].([]float32); !ok {
+			t.Errorf("Expected embedding on %s", n.ID)
+		}
+	}
+}
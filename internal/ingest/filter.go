@@ -0,0 +1,261 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IgnoreFiles are read in every directory of a walk, in this order, so a
+// .graphdbignore can re-include (!pattern) what .gitignore excludes.
+var IgnoreFiles = []string{".gitignore", ".graphdbignore"}
+
+// DefaultExcludes are never walked, whatever the ignore files say.
+var DefaultExcludes = []string{".git/", ".hg/", ".svn/", "node_modules/"}
+
+// DefaultMaxFileSize is the size above which files are skipped.
+const DefaultMaxFileSize = 1 << 20
+
+// binarySniffLen is how much of a file is checked for NUL bytes.
+const binarySniffLen = 8000
+
+// Filter decides which files under a root directory are ingested. Patterns
+// use .gitignore syntax and are relative to the root.
+type Filter struct {
+	// Include, if set, limits ingestion to files matching one of the patterns.
+	Include []string
+	// Exclude skips matching files and directories, on top of the ignore files.
+	Exclude []string
+	// MaxFileSize skips files larger than this many bytes. Zero disables it.
+	MaxFileSize int64
+	// NoIgnoreFiles disables reading .gitignore and .graphdbignore.
+	NoIgnoreFiles bool
+
+	root string
+
+	mu      sync.Mutex
+	ignores map[string][]ignorePattern // Directory -> patterns of its ignore files
+}
+
+// NewFilter returns a Filter for root with the default size limit.
+func NewFilter(root string) *Filter {
+	return &Filter{
+		MaxFileSize: DefaultMaxFileSize,
+		root:        filepath.Clean(root),
+		ignores:     make(map[string][]ignorePattern),
+	}
+}
+
+// SkipDir reports whether the walk should not descend into dir.
+func (f *Filter) SkipDir(dir string) bool {
+	rel, ok := f.rel(dir)
+	if !ok || rel == "." {
+		return false
+	}
+	return f.excluded(rel, true)
+}
+
+// SkipFile reports whether file should not be ingested. Unlike SkipDir it
+// also checks the directories above file, so it can be used on its own for
+// paths that did not come from a walk.
+func (f *Filter) SkipFile(file string) bool {
+	rel, ok := f.rel(file)
+	if !ok {
+		return false
+	}
+
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if f.excluded(dir, true) {
+			return true
+		}
+	}
+	if f.excluded(rel, false) {
+		return true
+	}
+	if len(f.Include) > 0 && !f.included(rel) {
+		return true
+	}
+
+	info, err := os.Stat(file)
+	if err != nil || !info.Mode().IsRegular() {
+		return true
+	}
+	if f.MaxFileSize > 0 && info.Size() > f.MaxFileSize {
+		return true
+	}
+	return isBinary(file)
+}
+
+// rel returns p relative to the root, slash-separated.
+func (f *Filter) rel(p string) (string, bool) {
+	rel, err := filepath.Rel(f.root, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// excluded applies the default excludes, Exclude and the ignore files of
+// every directory from the root down to rel.
+func (f *Filter) excluded(rel string, isDir bool) bool {
+	if matchAny(DefaultExcludes, rel, isDir) || matchAny(f.Exclude, rel, isDir) {
+		return true
+	}
+	if f.NoIgnoreFiles {
+		return false
+	}
+
+	// The last matching pattern wins, and deeper ignore files come later
+	ignored := false
+	dir := f.root
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		for _, pattern := range f.patterns(dir) {
+			if pattern.match(strings.Join(parts[i:], "/"), isDir) {
+				ignored = !pattern.negate
+			}
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return ignored
+}
+
+// included reports whether rel, or one of the directories above it,
+// matches Include, so "src" and "src/" take in everything under src.
+func (f *Filter) included(rel string) bool {
+	if matchAny(f.Include, rel, false) {
+		return true
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if matchAny(f.Include, dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns returns the parsed ignore files of dir, reading them once.
+func (f *Filter) patterns(dir string) []ignorePattern {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if patterns, ok := f.ignores[dir]; ok {
+		return patterns
+	}
+
+	var patterns []ignorePattern
+	for _, name := range IgnoreFiles {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		patterns = append(patterns, parseIgnore(file)...)
+		file.Close()
+	}
+	f.ignores[dir] = patterns
+	return patterns
+}
+
+// ignorePattern is one line of a .gitignore file.
+type ignorePattern struct {
+	segments []string // Slash-separated glob segments; "**" matches any number
+	negate   bool     // "!pattern" re-includes
+	dirOnly  bool     // "pattern/" only matches directories
+}
+
+func parseIgnore(r io.Reader) []ignorePattern {
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if pattern, ok := parsePattern(scanner.Text()); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+func parsePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // \# and \! escape the first character
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to its directory;
+	// otherwise it matches at any depth
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	p.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+	return p, true
+}
+
+func (p ignorePattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(p.segments, strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// matchAny reports whether rel matches one of the .gitignore-style globs.
+func matchAny(globs []string, rel string, isDir bool) bool {
+	for _, glob := range globs {
+		if pattern, ok := parsePattern(glob); ok && !pattern.negate && pattern.match(rel, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinary reports whether the start of the file contains a NUL byte, the
+// same heuristic git uses.
+func isBinary(file string) bool {
+	fh, err := os.Open(file)
+	if err != nil {
+		return true
+	}
+	defer fh.Close()
+
+	buf := make([]byte, binarySniffLen)
+	n, _ := io.ReadFull(fh, buf)
+	return bytes.IndexByte(buf[:n], 0) >= 0
+}
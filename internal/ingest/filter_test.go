@@ -0,0 +1,172 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIgnorePattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"bin/", "bin", true, true},
+		{"bin/", "bin", false, false},
+		{"bin/", "src/bin", true, true},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "docs/sub/a.md", false, false},
+		{"docs/**/*.md", "docs/sub/deep/a.md", false, true},
+		{"**/gen/*.go", "a/b/gen/x.go", false, true},
+		{"*_generated.go", "pkg/api_generated.go", false, true},
+		{"*_generated.go", "pkg/api.go", false, false},
+	}
+
+	for _, tt := range tests {
+		p, ok := parsePattern(tt.pattern)
+		if !ok {
+			t.Fatalf("parsePattern(%q) failed", tt.pattern)
+		}
+		if got := p.match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q match %q (dir=%v): expected %v, got %v", tt.pattern, tt.path, tt.isDir, tt.want, got)
+		}
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "/"} {
+		if _, ok := parsePattern(line); ok {
+			t.Errorf("Expected %q to be skipped", line)
+		}
+	}
+	if p, _ := parsePattern("!keep.go"); !p.negate {
+		t.Error("Expected negated pattern")
+	}
+	if p, _ := parsePattern(`\#file`); p.negate || !p.match("#file", false) {
+		t.Error("Expected escaped # to match literally")
+	}
+}
+
+func TestWalker_Filter(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":            "*.gen.go\nbuild/\n/tmp.go\n",
+		".graphdbignore":        "!keep.gen.go\n",
+		"main.go":               "package main\n",
+		"tmp.go":                "package main\n",
+		"api.gen.go":            "package main\n",
+		"keep.gen.go":           "package main\n",
+		"build/out.go":          "package build\n",
+		"node_modules/lib/x.js": "function x() {}\n",
+		".git/hooks/h.py":       "def h(): pass\n",
+		"pkg/tmp.go":            "package pkg\n",
+		"pkg/.gitignore":        "local.go\n",
+		"pkg/local.go":          "package pkg\n",
+		"pkg/big.go":            "package pkg\n" + strings.Repeat("// padding\n", 200),
+		"pkg/vendor/dep/dep.go": "package dep\n",
+		"web/app.ts":            "function app() {}\n",
+		"assets/logo.ts":        "\x00\x01\x02",
+	})
+
+	emitter := &MockEmitter{}
+	walker := NewWalker(1, &countingEmbedder{}, emitter)
+	walker.Filter = NewFilter(root)
+	walker.Filter.Exclude = []string{"vendor/"}
+	walker.Filter.MaxFileSize = 1000
+
+	if err := walker.Run(context.Background(), root); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	files := make(map[string]bool)
+	for _, n := range emitter.Nodes {
+		if n.Label == "File" {
+			rel, _ := filepath.Rel(root, n.ID)
+			files[filepath.ToSlash(rel)] = true
+		}
+	}
+	var got []string
+	for f := range files {
+		got = append(got, f)
+	}
+	sort.Strings(got)
+	want := []string{"keep.gen.go", "main.go", "pkg/tmp.go", "web/app.ts"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected files %v, got %v", want, got)
+	}
+	if walker.Skipped == 0 {
+		t.Error("Expected skipped files to be counted")
+	}
+}
+
+func TestFilter_Include(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"src/a.go":      "package src\n",
+		"src/a_test.go": "package src\n",
+		"src/b.py":      "def b(): pass\n",
+		"tools/c.go":    "package tools\n",
+	})
+
+	f := NewFilter(root)
+	f.Include = []string{"src/**/*.go"}
+	f.Exclude = []string{"*_test.go"}
+
+	for name, want := range map[string]bool{
+		"src/a.go":      false,
+		"src/a_test.go": true,
+		"src/b.py":      true,
+		"tools/c.go":    true,
+	} {
+		if got := f.SkipFile(filepath.Join(root, name)); got != want {
+			t.Errorf("SkipFile(%s): expected %v, got %v", name, want, got)
+		}
+	}
+
+	// Directory patterns include everything below the directory
+	for _, include := range []string{"src", "src/", "/src/"} {
+		f = NewFilter(root)
+		f.Include = []string{include}
+		for name, want := range map[string]bool{
+			"src/a.go":   false,
+			"src/b.py":   false,
+			"tools/c.go": true,
+		} {
+			if got := f.SkipFile(filepath.Join(root, name)); got != want {
+				t.Errorf("Include %q, SkipFile(%s): expected %v, got %v", include, name, want, got)
+			}
+		}
+	}
+
+	// Ignored directories also hide files checked on their own
+	writeTree(t, root, map[string]string{".gitignore": "src/\n"})
+	f = NewFilter(root)
+	if !f.SkipFile(filepath.Join(root, "src/a.go")) {
+		t.Error("Expected file under an ignored directory to be skipped")
+	}
+	f = NewFilter(root)
+	f.NoIgnoreFiles = true
+	if f.SkipFile(filepath.Join(root, "src/a.go")) {
+		t.Error("Expected -no-ignore to disable .gitignore")
+	}
+}
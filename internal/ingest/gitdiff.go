@@ -0,0 +1,81 @@
+package ingest
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// FileChange is one entry of `git diff --name-status`.
+type FileChange struct {
+	Status  byte   // 'A'dded, 'M'odified, 'D'eleted, 'R'enamed, 'C'opied or 'T'ype changed
+	Path    string // Current path (the new path for renames and copies)
+	OldPath string // Previous path of a rename or copy
+}
+
+// GitHead returns the commit checked out in dir.
+func GitHead(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// GitDiff lists the files that changed between two commits, relative to dir
+// and with paths joined onto it so they match what a walk of dir produces.
+func GitDiff(dir, from, to string) ([]FileChange, error) {
+	cmd := exec.Command("git", "-C", dir, "diff", "--name-status", "-M", "--relative", "-z", from, to)
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git diff %s..%s failed: %s", from, to, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git diff %s..%s failed: %w", from, to, err)
+	}
+
+	changes, err := parseNameStatus(string(out))
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		changes[i].Path = filepath.Join(dir, changes[i].Path)
+		if changes[i].OldPath != "" {
+			changes[i].OldPath = filepath.Join(dir, changes[i].OldPath)
+		}
+	}
+	return changes, nil
+}
+
+// parseNameStatus parses NUL-separated `git diff --name-status -z` output:
+// "M\x00path\x00" or, for renames and copies, "R100\x00old\x00new\x00".
+func parseNameStatus(out string) ([]FileChange, error) {
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	if len(fields) == 1 && fields[0] == "" {
+		return nil, nil
+	}
+
+	var changes []FileChange
+	for i := 0; i < len(fields); {
+		status := fields[i]
+		if status == "" {
+			return nil, fmt.Errorf("malformed git diff output")
+		}
+		switch status[0] {
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("malformed git diff entry %q", status)
+			}
+			changes = append(changes, FileChange{Status: status[0], OldPath: fields[i+1], Path: fields[i+2]})
+			i += 3
+		default:
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("malformed git diff entry %q", status)
+			}
+			changes = append(changes, FileChange{Status: status[0], Path: fields[i+1]})
+			i += 2
+		}
+	}
+	return changes, nil
+}
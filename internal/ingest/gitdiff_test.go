@@ -0,0 +1,103 @@
+package ingest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	out := "M\x00a.go\x00R087\x00old/b.go\x00new/b.go\x00D\x00c.py\x00A\x00d.ts\x00"
+	changes, err := parseNameStatus(out)
+	if err != nil {
+		t.Fatalf("parseNameStatus failed: %v", err)
+	}
+
+	want := []FileChange{
+		{Status: 'M', Path: "a.go"},
+		{Status: 'R', Path: "new/b.go", OldPath: "old/b.go"},
+		{Status: 'D', Path: "c.py"},
+		{Status: 'A', Path: "d.ts"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Expected %d changes, got %v", len(want), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, want[i], changes[i])
+		}
+	}
+
+	if changes, _ := parseNameStatus(""); len(changes) != 0 {
+		t.Errorf("Expected no changes for empty output, got %v", changes)
+	}
+	if _, err := parseNameStatus("R100\x00only-one\x00"); err == nil {
+		t.Error("Expected error for truncated rename")
+	}
+}
+
+func TestGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("src/keep.go", "package src\n")
+	write("src/edit.go", "package src\n")
+	write("src/gone.go", "package src\n\nconst Gone = true\n")
+	write("src/move.go", "package src\n\nfunc Moved() {}\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	base, err := GitHead(dir)
+	if err != nil {
+		t.Fatalf("GitHead failed: %v", err)
+	}
+
+	write("src/edit.go", "package src\n\nfunc Edited() {}\n")
+	write("src/new.go", "package src\n\nvar New = 1\n")
+	os.Remove(filepath.Join(dir, "src/gone.go"))
+	git("mv", "src/move.go", "src/moved.go")
+	git("add", "-A")
+	git("commit", "-q", "-m", "change")
+	head, _ := GitHead(dir)
+
+	// Paths are relative to the directory passed in, joined onto it
+	sub := filepath.Join(dir, "src")
+	changes, err := GitDiff(sub, base, head)
+	if err != nil {
+		t.Fatalf("GitDiff failed: %v", err)
+	}
+
+	got := make(map[string]FileChange)
+	for _, c := range changes {
+		got[c.Path] = c
+	}
+	if len(got) != 4 {
+		t.Fatalf("Expected 4 changes, got %+v", changes)
+	}
+	if got[filepath.Join(sub, "edit.go")].Status != 'M' || got[filepath.Join(sub, "new.go")].Status != 'A' || got[filepath.Join(sub, "gone.go")].Status != 'D' {
+		t.Errorf("Unexpected statuses: %+v", changes)
+	}
+	if moved := got[filepath.Join(sub, "moved.go")]; moved.Status != 'R' || moved.OldPath != filepath.Join(sub, "move.go") {
+		t.Errorf("Expected rename from move.go, got %+v", moved)
+	}
+}
@@ -0,0 +1,176 @@
+package ingest
+
+import (
+	"log"
+	"os"
+
+	"graphdb/internal/graph"
+	"graphdb/internal/storage"
+)
+
+// unchanged reports whether path hashes to the same content as in the
+// manifest, and was classified with the same contamination rules, recording
+// that the file was seen this run either way.
+func (wp *WorkerPool) unchanged(path, hash string) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.seen[path] = true
+	if wp.Contamination != nil && wp.Manifest.Contamination != wp.contaminationHash() {
+		return false
+	}
+	if previous, ok := wp.Manifest.Files[path]; ok && previous.Hash == hash {
+		wp.stats.Unchanged++
+		return true
+	}
+	return false
+}
+
+// reuseEmbedding fills in the embedding of a function whose embedded text is
+// unchanged since the last run.
+func (wp *WorkerPool) reuseEmbedding(path string, node *graph.Node, textHash string) bool {
+	previous, ok := wp.Manifest.Files[path]
+	if !ok {
+		return false
+	}
+	vec, ok := previous.embedding(node.ID, textHash)
+	if !ok {
+		return false
+	}
+	node.Properties["embedding"] = vec
+
+	wp.mu.Lock()
+	wp.stats.ReusedEmbedding++
+	wp.mu.Unlock()
+	return true
+}
+
+// record stores what a changed file produced. The manifest itself is only
+// updated in Stop, once calls are linked.
+func (wp *WorkerPool) record(path, hash string, nodes []*graph.Node, edges []*graph.Edge, textHashes map[string]string, facts map[string]FunctionFacts) {
+	rec := &FileRecord{Hash: hash}
+	seenNodes := make(map[string]bool)
+	for _, n := range nodes {
+		if seenNodes[n.ID] {
+			continue
+		}
+		seenNodes[n.ID] = true
+		mn := ManifestNode{ID: n.ID, Label: n.Label, TextHash: textHashes[n.ID]}
+		if f, ok := facts[n.ID]; ok {
+			mn.Seeds, mn.Lines = f.Seeds, f.Lines
+		}
+		if vec, ok := n.Properties["embedding"].([]float32); ok && mn.TextHash != "" {
+			mn.Embedding = encodeEmbedding(vec)
+		}
+		rec.Nodes = append(rec.Nodes, mn)
+	}
+	for _, e := range edges {
+		rec.Edges = append(rec.Edges, *e)
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.records[path] = rec
+	for id := range seenNodes {
+		wp.nodeFiles[id] = path
+	}
+	wp.stats.Changed++
+}
+
+// recordLinkedCall attributes a resolved call to the changed file it came from.
+func (wp *WorkerPool) recordLinkedCall(edge *graph.Edge) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if rec, ok := wp.records[wp.nodeFiles[edge.SourceID]]; ok {
+		rec.Edges = append(rec.Edges, *edge)
+	}
+}
+
+// seedLinker registers the definitions of files that were not parsed this run,
+// so calls from changed files into them still resolve.
+func (wp *WorkerPool) seedLinker() {
+	for path, rec := range wp.Manifest.Files {
+		if _, changed := wp.records[path]; !changed {
+			wp.Linker.AddNodes(rec.graphNodes(path))
+		}
+	}
+}
+
+// applyManifest emits tombstones for nodes and edges that changed or deleted
+// files no longer produce, then updates the manifest.
+func (wp *WorkerPool) applyManifest() {
+	var stale []*FileRecord
+	for path, rec := range wp.records {
+		if previous, ok := wp.Manifest.Files[path]; ok {
+			stale = append(stale, previous)
+		}
+		wp.Manifest.Files[path] = rec
+	}
+	for path, previous := range wp.Manifest.Files {
+		if wp.seen[path] {
+			continue
+		}
+		// Files that still exist were just not submitted this run (e.g. -file-list)
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			continue
+		}
+		stale = append(stale, previous)
+		delete(wp.Manifest.Files, path)
+		wp.stats.Deleted++
+	}
+
+	// Anything still produced by some file survives, even if it moved
+	liveNodes := make(map[string]bool)
+	liveEdges := make(map[graph.Edge]bool)
+	for _, rec := range wp.Manifest.Files {
+		for _, n := range rec.Nodes {
+			liveNodes[n.ID] = true
+		}
+		for _, e := range rec.Edges {
+			liveEdges[e] = true
+		}
+	}
+
+	deleter, ok := wp.emitter.(storage.Deleter)
+	if !ok && len(stale) > 0 {
+		log.Printf("WARNING: emitter cannot record deletions; removed nodes and edges will remain in the graph")
+		return
+	}
+
+	deletedNodes := make(map[string]bool)
+	for _, rec := range stale {
+		for _, n := range rec.Nodes {
+			if liveNodes[n.ID] || deletedNodes[n.ID] {
+				continue
+			}
+			deletedNodes[n.ID] = true
+			if err := deleter.DeleteNode(n.ID); err != nil {
+				log.Printf("Error emitting tombstone for node %s: %v", n.ID, err)
+				continue
+			}
+			wp.stats.NodeTombstones++
+		}
+	}
+	deletedEdges := make(map[graph.Edge]bool)
+	for _, rec := range stale {
+		for _, e := range rec.Edges {
+			// Deleting a node already removes its relationships
+			if liveEdges[e] || deletedEdges[e] || deletedNodes[e.SourceID] || deletedNodes[e.TargetID] {
+				continue
+			}
+			deletedEdges[e] = true
+			edge := e
+			if err := deleter.DeleteEdge(&edge); err != nil {
+				log.Printf("Error emitting tombstone for edge %s -> %s: %v", e.SourceID, e.TargetID, err)
+				continue
+			}
+			wp.stats.EdgeTombstones++
+		}
+	}
+}
+
+// IncrementalStats returns the results of an incremental run, after Stop.
+func (wp *WorkerPool) IncrementalStats() IncrementalStats {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.stats
+}
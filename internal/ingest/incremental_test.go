@@ -0,0 +1,222 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"graphdb/internal/embedding"
+	"graphdb/internal/graph"
+)
+
+// countingEmbedder returns a fixed vector per text and remembers what it embedded.
+type countingEmbedder struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (e *countingEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.texts = append(e.texts, texts...)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{float32(len(text)), 1}
+	}
+	return out, nil
+}
+
+// deltaEmitter records upserts, partial updates and tombstones.
+type deltaEmitter struct {
+	MockEmitter
+	updates      []*graph.Node
+	deletedNodes []string
+	deletedEdges []graph.Edge
+}
+
+func (d *deltaEmitter) UpdateNode(node *graph.Node) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.updates = append(d.updates, node)
+	return nil
+}
+
+func (d *deltaEmitter) DeleteNode(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deletedNodes = append(d.deletedNodes, id)
+	return nil
+}
+
+func (d *deltaEmitter) DeleteEdge(edge *graph.Edge) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deletedEdges = append(d.deletedEdges, *edge)
+	return nil
+}
+
+func runIncremental(t *testing.T, manifestPath string, files []string) (*deltaEmitter, *countingEmbedder, IncrementalStats) {
+	t.Helper()
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+
+	embedder := &countingEmbedder{}
+	emitter := &deltaEmitter{}
+	wp := NewWorkerPool(2, embedder, emitter)
+	wp.Manifest = manifest
+	// Signature documents stay the same when only a body changes
+	wp.Documents = &DocumentBuilder{Template: TemplateSignature}
+	wp.Start()
+	for _, f := range files {
+		wp.Submit(f)
+	}
+	wp.Stop()
+
+	if err := manifest.Save(manifestPath); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	return emitter, embedder, wp.IncrementalStats()
+}
+
+func TestWorkerPool_IncrementalIngest(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := ManifestPath(filepath.Join(dir, "graph.jsonl"))
+	a := filepath.Join(dir, "a.py")
+	b := filepath.Join(dir, "b.py")
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(a, "def foo():\n    bar()\n\ndef bar():\n    pass\n")
+	write(b, "def baz():\n    pass\n")
+
+	// 1. First run ingests everything
+	emitter, embedder, stats := runIncremental(t, manifestPath, []string{a, b})
+	if stats.Changed != 2 || stats.Unchanged != 0 || len(embedder.texts) != 3 {
+		t.Fatalf("Unexpected first run: %+v, embedded %v", stats, embedder.texts)
+	}
+	if len(emitter.deletedNodes) != 0 || len(emitter.deletedEdges) != 0 {
+		t.Errorf("Expected no tombstones on first run")
+	}
+
+	// 2. Nothing changed: nothing is parsed, embedded or emitted
+	emitter, embedder, stats = runIncremental(t, manifestPath, []string{a, b})
+	if stats.Unchanged != 2 || stats.Changed != 0 {
+		t.Errorf("Expected both files unchanged, got %+v", stats)
+	}
+	if len(emitter.Nodes) != 0 || len(emitter.Edges) != 0 || len(emitter.updates) != 0 || len(embedder.texts) != 0 {
+		t.Errorf("Expected empty delta, got %d nodes, %d edges, %d updates, %d embeddings", len(emitter.Nodes), len(emitter.Edges), len(emitter.updates), len(embedder.texts))
+	}
+
+	// 3. a.py drops bar and gains qux (which calls baz in the unchanged b.py)
+	write(a, "def foo():\n    qux()\n\ndef qux():\n    baz()\n")
+	emitter, embedder, stats = runIncremental(t, manifestPath, []string{a, b})
+	if stats.Changed != 1 || stats.Unchanged != 1 || stats.ReusedEmbedding != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if len(embedder.texts) != 1 || embedder.texts[0] != "function qux\ndef qux():" {
+		t.Errorf("Expected only qux to be embedded, got %v", embedder.texts)
+	}
+	for _, n := range emitter.Nodes {
+		if n.ID == a+":foo" {
+			if _, ok := n.Properties["embedding"].([]float32); !ok {
+				t.Errorf("Expected foo to carry its reused embedding")
+			}
+		}
+	}
+	if len(emitter.deletedNodes) != 1 || emitter.deletedNodes[0] != a+":bar" {
+		t.Errorf("Expected a tombstone for bar only, got %v", emitter.deletedNodes)
+	}
+	// foo -> bar went away with bar itself
+	if len(emitter.deletedEdges) != 0 {
+		t.Errorf("Expected no edge tombstones, got %v", emitter.deletedEdges)
+	}
+	var linked bool
+	for _, e := range emitter.Edges {
+		if e.Type == "CALLS" && e.SourceID == a+":qux" && e.TargetID == b+":baz" {
+			linked = true
+		}
+	}
+	if !linked {
+		t.Errorf("Expected call into the unchanged file to be linked, got %v", emitter.Edges)
+	}
+
+	// 4. b.py is deleted: its nodes are tombstoned, and qux -> baz goes with baz
+	os.Remove(b)
+	emitter, _, stats = runIncremental(t, manifestPath, []string{a})
+	if stats.Deleted != 1 || stats.Unchanged != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	sort.Strings(emitter.deletedNodes)
+	if len(emitter.deletedNodes) != 2 || emitter.deletedNodes[0] != b || emitter.deletedNodes[1] != b+":baz" {
+		t.Errorf("Expected tombstones for b.py and baz, got %v", emitter.deletedNodes)
+	}
+
+	manifest, _ := LoadManifest(manifestPath)
+	if _, ok := manifest.Files[b]; ok || len(manifest.Files) != 1 {
+		t.Errorf("Expected only a.py in the manifest, got %d files", len(manifest.Files))
+	}
+}
+
+func TestWorkerPool_RetriesFailedEmbeddings(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := ManifestPath(filepath.Join(dir, "graph.jsonl"))
+	a := filepath.Join(dir, "a.py")
+	if err := os.WriteFile(a, []byte("def foo():\n    pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. Embedding fails: the file is ingested without embeddings
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	wp := NewWorkerPool(1, &MockFailingEmbedder{}, &deltaEmitter{})
+	wp.Manifest = manifest
+	wp.Start()
+	wp.Submit(a)
+	wp.Stop()
+	if err := manifest.Save(manifestPath); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// 2. The unchanged file is processed again and embedded
+	emitter, embedder, stats := runIncremental(t, manifestPath, []string{a})
+	if stats.Changed != 1 || stats.Unchanged != 0 || len(embedder.texts) != 1 {
+		t.Errorf("Expected the file to be retried, got %+v, embedded %v", stats, embedder.texts)
+	}
+	if len(emitter.deletedNodes) != 0 {
+		t.Errorf("Expected no tombstones, got %v", emitter.deletedNodes)
+	}
+
+	// 3. Once embedded, it is skipped
+	if _, _, stats = runIncremental(t, manifestPath, []string{a}); stats.Unchanged != 1 {
+		t.Errorf("Expected the file to be unchanged, got %+v", stats)
+	}
+}
+
+func TestWorkerPool_IncrementalEdgeTombstones(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := ManifestPath(filepath.Join(dir, "graph.db"))
+	a := filepath.Join(dir, "a.py")
+
+	os.WriteFile(a, []byte("def foo():\n    bar()\n\ndef bar():\n    pass\n"), 0644)
+	runIncremental(t, manifestPath, []string{a})
+
+	// foo stops calling bar, but both still exist
+	os.WriteFile(a, []byte("def foo():\n    pass\n\ndef bar():\n    pass\n"), 0644)
+	emitter, _, _ := runIncremental(t, manifestPath, []string{a})
+
+	if len(emitter.deletedNodes) != 0 {
+		t.Errorf("Expected no node tombstones, got %v", emitter.deletedNodes)
+	}
+	want := graph.Edge{SourceID: a + ":foo", TargetID: a + ":bar", Type: "CALLS"}
+	if len(emitter.deletedEdges) != 1 || emitter.deletedEdges[0] != want {
+		t.Errorf("Expected a tombstone for %v, got %v", want, emitter.deletedEdges)
+	}
+}
@@ -0,0 +1,251 @@
+package ingest
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"graphdb/internal/analysis"
+	"graphdb/internal/graph"
+)
+
+// linkableLabels are the node labels a CALLS edge may point at.
+var linkableLabels = map[string]bool{
+	"Function":  true,
+	"Method":    true,
+	"Class":     true,
+	"Interface": true,
+	"Enum":      true,
+}
+
+// LinkStats summarizes a linking pass.
+type LinkStats struct {
+	Resolved   int // CALLS edges emitted
+	Rewritten  int // Resolved edges whose target differed from the parser's guess
+	Unresolved int // Calls with no matching definition, or no single best one
+}
+
+type symbol struct {
+	id   string
+	file string
+	// qualified is the ID without its file prefix (e.g. "Math::Add" or "NS.Class")
+	qualified string
+}
+
+// Linker resolves CALLS edges across files once every file has been parsed.
+// Parsers only see one file at a time, so their call targets are guesses
+// (candidate namespaces, header names, "same file" assumptions). The linker
+// builds a global symbol table and rewrites each call to a real definition.
+type Linker struct {
+	mu      sync.Mutex
+	symbols map[string]*symbol   // ID -> symbol
+	byName  map[string][]*symbol // Short name -> symbols
+	files   map[string]string    // Any node ID -> file
+	calls   []*graph.Edge
+	stats   LinkStats
+}
+
+func NewLinker() *Linker {
+	return &Linker{
+		symbols: make(map[string]*symbol),
+		byName:  make(map[string][]*symbol),
+		files:   make(map[string]string),
+	}
+}
+
+// AddNodes registers parsed definitions in the symbol table.
+func (l *Linker) AddNodes(nodes []*graph.Node) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, n := range nodes {
+		file, _ := n.Properties["file"].(string)
+		l.files[n.ID] = file
+
+		if !linkableLabels[n.Label] {
+			continue
+		}
+		if _, exists := l.symbols[n.ID]; exists {
+			continue
+		}
+		sym := &symbol{id: n.ID, file: file, qualified: n.ID}
+		if file != "" && strings.HasPrefix(n.ID, file+":") {
+			sym.qualified = n.ID[len(file)+1:]
+		}
+		l.symbols[n.ID] = sym
+		name := shortName(n.ID)
+		l.byName[name] = append(l.byName[name], sym)
+	}
+}
+
+// AddCall queues a CALLS edge for resolution.
+func (l *Linker) AddCall(edge *graph.Edge) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, edge)
+}
+
+// Link resolves all queued calls and returns only the edges whose target is a
+// known definition. Candidate edges for the same call (same source and short
+// name) are resolved together: targets that already exist are kept as-is,
+// otherwise the single best-scoring definition with that name is chosen.
+func (l *Linker) Link() []*graph.Edge {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	type callKey struct {
+		source string
+		name   string
+	}
+	var order []callKey
+	groups := make(map[callKey][]string)
+	for _, e := range l.calls {
+		key := callKey{source: e.SourceID, name: shortName(e.TargetID)}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], e.TargetID)
+	}
+
+	var resolved []*graph.Edge
+	seen := make(map[string]bool)
+	emit := func(source, target string) {
+		if seen[source+"|"+target] {
+			return
+		}
+		seen[source+"|"+target] = true
+		resolved = append(resolved, &graph.Edge{SourceID: source, TargetID: target, Type: "CALLS"})
+	}
+
+	for _, key := range order {
+		candidates := groups[key]
+
+		exact := false
+		for _, cand := range candidates {
+			if _, ok := l.symbols[cand]; ok {
+				emit(key.source, cand)
+				exact = true
+			}
+		}
+		if exact {
+			continue
+		}
+
+		if best := l.bestMatch(key.source, key.name, candidates); best != nil {
+			emit(key.source, best.id)
+			l.stats.Rewritten++
+			continue
+		}
+		l.stats.Unresolved++
+	}
+
+	l.stats.Resolved = len(resolved)
+	l.calls = nil
+	return resolved
+}
+
+// Stats returns the results of the last Link call.
+func (l *Linker) Stats() LinkStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// bestMatch scores every definition with the given short name and returns the
+// unique highest scorer, or nil if there is none, the top score is tied or
+// nothing ties the call to it. Definitions outside the package a candidate
+// names (e.g. "fmt" in "fmt:Println") are never considered.
+func (l *Linker) bestMatch(source, name string, candidates []string) *symbol {
+	sourceFile := l.files[source]
+
+	var best *symbol
+	bestScore, tied := 0, false
+	for _, sym := range l.byName[name] {
+		if !inCandidatePackage(sym, candidates) {
+			continue
+		}
+		score := 0
+		for _, cand := range candidates {
+			// Qualified match, e.g. "NS.Class" or "math.h:Math::Add" against "math.cpp:Math::Add"
+			fileQualified := strings.HasSuffix(cand, ":"+sym.qualified) && !strings.HasSuffix(cand, "::"+sym.qualified)
+			if cand == sym.qualified || fileQualified {
+				score += 4
+				break
+			}
+		}
+		if sourceFile != "" {
+			if sym.file == sourceFile {
+				score += 2
+			} else if filepath.Dir(sym.file) == filepath.Dir(sourceFile) {
+				score++
+			}
+		}
+
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = sym, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+
+	if tied {
+		return nil
+	}
+	return best
+}
+
+// inCandidatePackage reports whether one of the candidates may refer to sym:
+// one naming no package, or naming the package sym is declared in, or the end
+// of it ("Worker:run" for "com.example.Worker:run").
+func inCandidatePackage(sym *symbol, candidates []string) bool {
+	symPackage := qualifier(sym.id)
+	for _, cand := range candidates {
+		pkg := qualifier(cand)
+		if pkg == "" || isSourceFile(pkg) || pkg == symPackage || strings.HasSuffix(symPackage, "."+pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifier returns what an ID names before its last single ":" (the file,
+// package or class), or "" if it has none: "fmt" for "fmt:Println" and
+// "lib/math.h" for "lib/math.h:Math::Add".
+func qualifier(id string) string {
+	for i := len(id) - 1; i >= 0; i-- {
+		if id[i] != ':' {
+			continue
+		}
+		if (i > 0 && id[i-1] == ':') || (i+1 < len(id) && id[i+1] == ':') {
+			continue
+		}
+		return id[:i]
+	}
+	return ""
+}
+
+// isSourceFile reports whether a qualifier is a file a parser reads rather
+// than a package. Parsers qualify their guesses with files (the calling file,
+// a header), which the linker may rewrite to the file defining the callee.
+func isSourceFile(qualifier string) bool {
+	ext := filepath.Ext(qualifier)
+	if ext == "" {
+		return false
+	}
+	_, ok := analysis.GetParser(ext)
+	return ok
+}
+
+// shortName strips file, namespace and class qualifiers from an ID:
+// "a/b.cpp:Math::Add", "NS.Util.Add" and "pkg.Type:Add" all become "Add".
+func shortName(id string) string {
+	name := id
+	if idx := strings.LastIndex(name, ":"); idx != -1 {
+		name = name[idx+1:]
+	}
+	if idx := strings.LastIndex(name, "."); idx != -1 {
+		name = name[idx+1:]
+	}
+	return name
+}
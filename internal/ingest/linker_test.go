@@ -0,0 +1,131 @@
+package ingest
+
+import (
+	"testing"
+
+	"graphdb/internal/graph"
+)
+
+func fnNode(id, file string) *graph.Node {
+	return &graph.Node{ID: id, Label: "Function", Properties: map[string]interface{}{"file": file}}
+}
+
+func TestLinker_ResolvesCandidates(t *testing.T) {
+	l := NewLinker()
+	l.AddNodes([]*graph.Node{
+		fnNode("app/main.cs:Main", "app/main.cs"),
+		fnNode("app/util.cs:Helper", "app/util.cs"),
+		{ID: "app/widget.cs:App.Widget", Label: "Class", Properties: map[string]interface{}{"file": "app/widget.cs"}},
+		fnNode("lib/math.cpp:Math::Add", "lib/math.cpp"),
+		fnNode("other/add.cpp:Add", "other/add.cpp"),
+		fnNode("app/main.cs:Local", "app/main.cs"),
+	})
+
+	calls := []*graph.Edge{
+		// C#: one speculative candidate per using namespace
+		{SourceID: "app/main.cs:Main", TargetID: "App.Helper", Type: "CALLS"},
+		{SourceID: "app/main.cs:Main", TargetID: "System.Helper", Type: "CALLS"},
+		{SourceID: "app/main.cs:Main", TargetID: "App.Widget", Type: "CALLS"},
+		// C++: guessed header path, qualified name beats the bare "Add"
+		{SourceID: "app/main.cs:Main", TargetID: "lib/math.h:Math::Add", Type: "CALLS"},
+		// Already correct
+		{SourceID: "app/main.cs:Main", TargetID: "app/main.cs:Local", Type: "CALLS"},
+		// External
+		{SourceID: "app/main.cs:Main", TargetID: "UNKNOWN:printf", Type: "CALLS"},
+	}
+	for _, c := range calls {
+		l.AddCall(c)
+	}
+
+	edges := l.Link()
+
+	want := map[string]bool{
+		"app/util.cs:Helper":       true,
+		"app/widget.cs:App.Widget": true,
+		"lib/math.cpp:Math::Add":   true,
+		"app/main.cs:Local":        true,
+	}
+	if len(edges) != len(want) {
+		t.Errorf("Expected %d edges, got %d: %v", len(want), len(edges), edges)
+	}
+	for _, e := range edges {
+		if !want[e.TargetID] {
+			t.Errorf("Unexpected CALLS target %s", e.TargetID)
+		}
+		if e.SourceID != "app/main.cs:Main" || e.Type != "CALLS" {
+			t.Errorf("Unexpected edge %+v", e)
+		}
+	}
+
+	stats := l.Stats()
+	if stats.Resolved != 4 || stats.Rewritten != 3 || stats.Unresolved != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestLinker_PrefersLocalAndRejectsTies(t *testing.T) {
+	l := NewLinker()
+	l.AddNodes([]*graph.Node{
+		fnNode("a/one.vb:Caller", "a/one.vb"),
+		fnNode("a/one.vb:Run", "a/one.vb"),
+		fnNode("b/two.vb:Run", "b/two.vb"),
+		fnNode("c/x.vb:Start", "c/x.vb"),
+		fnNode("d/y.vb:Start", "d/y.vb"),
+	})
+	// VB.NET assumes every call is local to the calling file
+	l.AddCall(&graph.Edge{SourceID: "a/one.vb:Caller", TargetID: "a/one.vb:Run", Type: "CALLS"})
+	l.AddCall(&graph.Edge{SourceID: "a/one.vb:Caller", TargetID: "a/one.vb:Start", Type: "CALLS"})
+
+	edges := l.Link()
+
+	if len(edges) != 1 || edges[0].TargetID != "a/one.vb:Run" {
+		t.Errorf("Expected only the local Run call to resolve, got %v", edges)
+	}
+	if stats := l.Stats(); stats.Unresolved != 1 {
+		t.Errorf("Expected the ambiguous Start call to be unresolved, got %+v", stats)
+	}
+}
+
+func TestLinker_LeavesExternalCallsUnlinked(t *testing.T) {
+	l := NewLinker()
+	l.AddNodes([]*graph.Node{
+		fnNode("example.com/app:main", "app/main.go"),
+		fnNode("example.com/app/log:Println", "app/log/log.go"),
+		fnNode("com.example.Worker:run", "app/Worker.java"),
+		fnNode("z/far.cs:Other.Helper", "z/far.cs"),
+	})
+	calls := []*graph.Edge{
+		// Standard library: same name as a repo function, different package
+		{SourceID: "example.com/app:main", TargetID: "fmt:Println", Type: "CALLS"},
+		// Nothing ties the only Helper to the call
+		{SourceID: "example.com/app:main", TargetID: "System.Helper", Type: "CALLS"},
+		// The class name alone still names the package
+		{SourceID: "example.com/app:main", TargetID: "Worker:run", Type: "CALLS"},
+	}
+	for _, c := range calls {
+		l.AddCall(c)
+	}
+
+	edges := l.Link()
+	if len(edges) != 1 || edges[0].TargetID != "com.example.Worker:run" {
+		t.Errorf("Expected only the Worker call to resolve, got %v", edges)
+	}
+	if stats := l.Stats(); stats.Unresolved != 2 || stats.Rewritten != 1 {
+		t.Errorf("Expected fmt:Println and Helper to stay unresolved, got %+v", stats)
+	}
+}
+
+func TestShortName(t *testing.T) {
+	tests := map[string]string{
+		"a/b.cpp:Math::Add":   "Add",
+		"NS.Util.Add":         "Add",
+		"pkg.Type:Add":        "Add",
+		"shop/order.py:Order": "Order",
+		"Add":                 "Add",
+	}
+	for in, want := range tests {
+		if got := shortName(in); got != want {
+			t.Errorf("shortName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
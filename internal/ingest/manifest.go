@@ -0,0 +1,159 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+
+	"graphdb/internal/graph"
+)
+
+// ManifestVersion is bumped whenever the manifest format or the parser output
+// it describes changes incompatibly; manifests of another version are ignored.
+const ManifestVersion = 2
+
+// Manifest records what each ingested file produced, keyed by path, so that
+// the next run can skip unchanged files, emit tombstones for whatever changed
+// files no longer produce, and reuse embeddings of unchanged functions.
+type Manifest struct {
+	Version int                    `json:"version"`
+	Files   map[string]*FileRecord `json:"files"`
+
+	// Contamination hashes the contamination rules the files were classified
+	// with; files are re-parsed when the rules change.
+	Contamination string `json:"contamination,omitempty"`
+}
+
+// FileRecord is the output of a single file as of the last ingest.
+type FileRecord struct {
+	Hash  string         `json:"hash"`
+	Nodes []ManifestNode `json:"nodes"`
+	Edges []graph.Edge   `json:"edges,omitempty"` // Edges whose source is one of Nodes
+}
+
+// ManifestNode keeps just enough of a node to link calls into it, to reuse
+// its embedding and to rerun the risk analysis without re-parsing it.
+type ManifestNode struct {
+	ID        string `json:"id"`
+	Label     string `json:"label"`
+	TextHash  string `json:"text_hash,omitempty"` // Hash of the text that was embedded
+	Embedding []byte `json:"embedding,omitempty"` // Little-endian float32s
+
+	Seeds    []string `json:"seeds,omitempty"`    // Contamination kinds of the function itself
+	Lines    int      `json:"lines,omitempty"`    // Size of the function
+	Analysis string   `json:"analysis,omitempty"` // Hash of the risk properties last emitted
+}
+
+// IncrementalStats summarizes an incremental ingest.
+type IncrementalStats struct {
+	Changed         int
+	Unchanged       int
+	Deleted         int
+	ReusedEmbedding int
+	NodeTombstones  int
+	EdgeTombstones  int
+}
+
+// ManifestPath returns where the manifest for an output file or store lives.
+func ManifestPath(output string) string {
+	return output + ".manifest.json"
+}
+
+// NewManifest returns an empty manifest.
+func NewManifest() *Manifest {
+	return &Manifest{Version: ManifestVersion, Files: make(map[string]*FileRecord)}
+}
+
+// LoadManifest reads the manifest at path. A missing or outdated manifest
+// yields an empty one, so every file is treated as new.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewManifest(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if m.Version != ManifestVersion {
+		log.Printf("Ignoring manifest %s with version %d (expected %d)", path, m.Version, ManifestVersion)
+		return NewManifest(), nil
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]*FileRecord)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest atomically.
+func (m *Manifest) Save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// graphNodes returns the record's nodes for seeding the linker.
+func (r *FileRecord) graphNodes(path string) []*graph.Node {
+	nodes := make([]*graph.Node, 0, len(r.Nodes))
+	for _, n := range r.Nodes {
+		nodes = append(nodes, &graph.Node{ID: n.ID, Label: n.Label, Properties: map[string]interface{}{"file": path}})
+	}
+	return nodes
+}
+
+// embedding returns the stored embedding of a node if it was computed from
+// the same text.
+func (r *FileRecord) embedding(id, textHash string) ([]float32, bool) {
+	for _, n := range r.Nodes {
+		if n.ID == id && n.TextHash == textHash && len(n.Embedding) > 0 {
+			return decodeEmbedding(n.Embedding), true
+		}
+	}
+	return nil, false
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func encodeEmbedding(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, f := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeEmbedding(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vec
+}
@@ -0,0 +1,248 @@
+package ingest
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"graphdb/internal/graph"
+	"graphdb/internal/storage"
+)
+
+// FunctionFacts is what the risk analysis knows of a function.
+type FunctionFacts struct {
+	Label string
+	Seeds []string // Contamination kinds it depends on directly
+	Lines int
+}
+
+// RiskResult is the outcome of the risk analysis for one function.
+type RiskResult struct {
+	Contamination []string // Kinds it depends on, directly or through its callees
+	FanIn         int      // Distinct callers
+	Score         float64
+}
+
+// Properties returns the node properties recording r: a
+// "<kind>_contaminated" flag for every kind, "fan_in" and "risk_score".
+func (r RiskResult) Properties(kinds []string) map[string]interface{} {
+	props := map[string]interface{}{
+		"fan_in":     r.FanIn,
+		"risk_score": r.Score,
+	}
+	for _, kind := range kinds {
+		props[kind+"_contaminated"] = false
+	}
+	for _, kind := range r.Contamination {
+		props[kind+"_contaminated"] = true
+	}
+	return props
+}
+
+// AnalyzeRisk propagates the contamination of functions to their callers,
+// transitively along calls, and scores each function's risk in [0, 1]. Half
+// of the score comes from fan-in, three tenths from size and a fifth from
+// the share of kinds it is contaminated with; fan-in and size saturate, at
+// 5 callers and 50 lines for half their weight.
+func AnalyzeRisk(functions map[string]FunctionFacts, calls []graph.Edge, kinds []string) map[string]RiskResult {
+	callers := make(map[string]map[string]bool)
+	for _, e := range calls {
+		if e.Type != "CALLS" || e.SourceID == e.TargetID {
+			continue
+		}
+		if callers[e.TargetID] == nil {
+			callers[e.TargetID] = make(map[string]bool)
+		}
+		callers[e.TargetID][e.SourceID] = true
+	}
+
+	contaminated := make(map[string]map[string]bool)
+	for _, kind := range kinds {
+		var queue []string
+		for id, facts := range functions {
+			for _, seed := range facts.Seeds {
+				if seed == kind {
+					queue = append(queue, id)
+				}
+			}
+		}
+		marked := make(map[string]bool)
+		for _, id := range queue {
+			marked[id] = true
+		}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for caller := range callers[id] {
+				if _, ok := functions[caller]; ok && !marked[caller] {
+					marked[caller] = true
+					queue = append(queue, caller)
+				}
+			}
+		}
+		for id := range marked {
+			if contaminated[id] == nil {
+				contaminated[id] = make(map[string]bool)
+			}
+			contaminated[id][kind] = true
+		}
+	}
+
+	results := make(map[string]RiskResult, len(functions))
+	for id, facts := range functions {
+		result := RiskResult{FanIn: len(callers[id])}
+		for _, kind := range kinds {
+			if contaminated[id][kind] {
+				result.Contamination = append(result.Contamination, kind)
+			}
+		}
+
+		score := 0.5*saturate(result.FanIn, 5) + 0.3*saturate(facts.Lines, 50)
+		if len(kinds) > 0 {
+			score += 0.2 * float64(len(result.Contamination)) / float64(len(kinds))
+		}
+		result.Score = math.Round(score*1000) / 1000
+		results[id] = result
+	}
+	return results
+}
+
+// saturate maps n >= 0 into [0, 1), reaching a half at half.
+func saturate(n, half int) float64 {
+	if n <= 0 {
+		return 0
+	}
+	return float64(n) / float64(n+half)
+}
+
+// functionLines is the size of a function in lines, from its span or its
+// content.
+func functionLines(n *graph.Node) int {
+	start, end := intProperty(n, "start_line"), intProperty(n, "end_line")
+	if start > 0 && end >= start {
+		return end - start + 1
+	}
+	if content, ok := n.Properties["content"].(string); ok && content != "" {
+		return strings.Count(content, "\n") + 1
+	}
+	return 0
+}
+
+// classify finds the contamination seeds and sizes of the functions of a
+// parsed file and keeps them for the analysis in Stop.
+func (wp *WorkerPool) classify(path string, content []byte, nodes []*graph.Node, edges []*graph.Edge) map[string]FunctionFacts {
+	if wp.Contamination == nil {
+		return nil
+	}
+	seeds := wp.Contamination.Classify(path, content, nodes, edges)
+	facts := make(map[string]FunctionFacts)
+	for _, n := range nodes {
+		if isCallable(n) {
+			facts[n.ID] = FunctionFacts{Label: n.Label, Seeds: seeds[n.ID], Lines: functionLines(n)}
+		}
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	for id, f := range facts {
+		wp.functions[id] = f
+	}
+	return facts
+}
+
+// addCall keeps an emitted call for the analysis in Stop.
+func (wp *WorkerPool) addCall(edge *graph.Edge) {
+	if wp.Contamination == nil {
+		return
+	}
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.calls = append(wp.calls, *edge)
+}
+
+// analyzeRisk runs the risk analysis over the functions and calls of this run
+// or, in incremental mode, over the whole manifest, and emits the results as
+// partial updates. Incremental runs only emit the functions whose results
+// changed since the last run.
+func (wp *WorkerPool) analyzeRisk() {
+	functions, calls := wp.functions, wp.calls
+	if wp.Manifest != nil {
+		functions = make(map[string]FunctionFacts)
+		calls = nil
+		for _, rec := range wp.Manifest.Files {
+			for _, n := range rec.Nodes {
+				if n.Label == "Function" || n.Label == "Method" {
+					functions[n.ID] = FunctionFacts{Label: n.Label, Seeds: n.Seeds, Lines: n.Lines}
+				}
+			}
+			for _, e := range rec.Edges {
+				if e.Type == "CALLS" {
+					calls = append(calls, e)
+				}
+			}
+		}
+	}
+	if len(functions) == 0 {
+		return
+	}
+
+	analyzed := make(map[string]*ManifestNode)
+	if wp.Manifest != nil {
+		for _, rec := range wp.Manifest.Files {
+			for i := range rec.Nodes {
+				analyzed[rec.Nodes[i].ID] = &rec.Nodes[i]
+			}
+		}
+		wp.Manifest.Contamination = wp.contaminationHash()
+	}
+
+	updater, ok := wp.emitter.(storage.Updater)
+	if !ok {
+		log.Printf("WARNING: emitter cannot record partial updates; contamination and risk scores are not written")
+		return
+	}
+
+	kinds := wp.Contamination.Kinds()
+	results := AnalyzeRisk(functions, calls, kinds)
+
+	ids := make([]string, 0, len(results))
+	for id := range results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	updated, contaminated := 0, 0
+	for _, id := range ids {
+		result := results[id]
+		if len(result.Contamination) > 0 {
+			contaminated++
+		}
+		props := result.Properties(kinds)
+		if mn := analyzed[id]; mn != nil {
+			data, _ := json.Marshal(props)
+			hash := hashBytes(data)
+			if mn.Analysis == hash {
+				continue
+			}
+			mn.Analysis = hash
+		}
+
+		if err := updater.UpdateNode(&graph.Node{ID: id, Label: functions[id].Label, Properties: props}); err != nil {
+			log.Printf("Error emitting risk analysis of %s: %v", id, err)
+			continue
+		}
+		updated++
+	}
+	log.Printf("Risk analysis: %d of %d functions contaminated; %d updated", contaminated, len(results), updated)
+}
+
+// contaminationHash identifies the contamination rules in the manifest.
+func (wp *WorkerPool) contaminationHash() string {
+	if wp.rulesHash == "" {
+		data, _ := json.Marshal(wp.Contamination)
+		wp.rulesHash = hashBytes(data)
+	}
+	return wp.rulesHash
+}
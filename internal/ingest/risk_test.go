@@ -0,0 +1,116 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"graphdb/internal/graph"
+)
+
+func TestAnalyzeRisk(t *testing.T) {
+	functions := map[string]FunctionFacts{
+		"query":   {Seeds: []string{"db"}, Lines: 10},
+		"service": {Lines: 40},
+		"page":    {Seeds: []string{"ui"}, Lines: 5},
+		"pure":    {Lines: 2},
+	}
+	calls := []graph.Edge{
+		{SourceID: "service", TargetID: "query", Type: "CALLS"},
+		{SourceID: "page", TargetID: "service", Type: "CALLS"},
+		{SourceID: "page", TargetID: "pure", Type: "CALLS"},
+		{SourceID: "service", TargetID: "pure", Type: "CALLS"},
+		{SourceID: "unparsed", TargetID: "pure", Type: "CALLS"},
+		{SourceID: "pure", TargetID: "pure", Type: "CALLS"},
+	}
+	results := AnalyzeRisk(functions, calls, []string{"db", "io", "ui"})
+
+	contamination := map[string][]string{
+		"query":   {"db"},
+		"service": {"db"},       // Calls query
+		"page":    {"db", "ui"}, // Calls service, and is a UI seed itself
+		"pure":    nil,          // Callers do not contaminate their callees
+	}
+	for id, want := range contamination {
+		if got := results[id].Contamination; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected contamination %v, got %v", id, want, got)
+		}
+	}
+
+	// Distinct callers, including ones outside the analysis, but not itself
+	if fanIn := results["pure"].FanIn; fanIn != 3 {
+		t.Errorf("Expected pure to have 3 callers, got %d", fanIn)
+	}
+	// 0.5 * 1/(1+5) + 0.3 * 10/(10+50) + 0.2 * 1/3
+	if score := results["query"].Score; score != 0.2 {
+		t.Errorf("Expected query to score 0.2, got %v", score)
+	}
+	for id, r := range results {
+		if r.Score < 0 || r.Score >= 1 {
+			t.Errorf("%s: expected a score in [0, 1), got %v", id, r.Score)
+		}
+	}
+
+	props := results["service"].Properties([]string{"db", "io", "ui"})
+	want := map[string]interface{}{
+		"db_contaminated": true, "io_contaminated": false, "ui_contaminated": false,
+		"fan_in": 1, "risk_score": results["service"].Score,
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("Expected %v, got %v", want, props)
+	}
+}
+
+func TestWorkerPool_RiskAnalysis(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := ManifestPath(filepath.Join(dir, "graph.jsonl"))
+	store := filepath.Join(dir, "store.py")
+	handler := filepath.Join(dir, "handler.py")
+	os.WriteFile(store, []byte("import sqlite3\n\ndef load(id):\n    return sqlite3.connect('db').execute(id)\n"), 0644)
+	os.WriteFile(handler, []byte("def handle(id):\n    return load(id)\n\ndef helper():\n    return 1\n"), 0644)
+
+	updates := func(emitter *deltaEmitter) map[string]map[string]interface{} {
+		byID := make(map[string]map[string]interface{})
+		for _, n := range emitter.updates {
+			if n.Label != "Function" {
+				t.Errorf("Expected updates of Function nodes, got %s", n.Label)
+			}
+			byID[n.ID] = n.Properties
+		}
+		return byID
+	}
+
+	// 1. Every function gets its flags and score
+	emitter, _, _ := runIncremental(t, manifestPath, []string{store, handler})
+	got := updates(emitter)
+	if len(got) != 3 {
+		t.Fatalf("Expected updates for 3 functions, got %v", got)
+	}
+	if got[store+":load"]["db_contaminated"] != true || got[store+":load"]["fan_in"] != 1 {
+		t.Errorf("Expected load to be a DB seed with one caller, got %v", got[store+":load"])
+	}
+	if got[handler+":handle"]["db_contaminated"] != true || got[handler+":handle"]["ui_contaminated"] != false {
+		t.Errorf("Expected handle to be DB contaminated through load, got %v", got[handler+":handle"])
+	}
+	if got[handler+":helper"]["db_contaminated"] != false {
+		t.Errorf("Expected helper to stay clean, got %v", got[handler+":helper"])
+	}
+
+	// 2. Nothing changed, nothing to update
+	emitter, _, _ = runIncremental(t, manifestPath, []string{store, handler})
+	if len(emitter.updates) != 0 {
+		t.Errorf("Expected no updates, got %d", len(emitter.updates))
+	}
+
+	// 3. handle stops calling load: load, in the unchanged file, loses its caller
+	os.WriteFile(handler, []byte("def handle(id):\n    return id\n\ndef helper():\n    return 1\n"), 0644)
+	emitter, _, _ = runIncremental(t, manifestPath, []string{store, handler})
+	got = updates(emitter)
+	if got[handler+":handle"]["db_contaminated"] != false {
+		t.Errorf("Expected handle to be clean now, got %v", got[handler+":handle"])
+	}
+	if got[store+":load"]["fan_in"] != 0 {
+		t.Errorf("Expected load to be updated with no callers, got %v", got[store+":load"])
+	}
+}
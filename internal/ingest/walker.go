@@ -10,6 +10,13 @@ import (
 
 type Walker struct {
 	WorkerPool *WorkerPool
+
+	// Filter decides which files are submitted. If nil, Run uses NewFilter
+	// on the walked directory.
+	Filter *Filter
+
+	// Skipped counts the files the filter rejected during Run.
+	Skipped int
 }
 
 func NewWalker(workers int, embedder embedding.Embedder, emitter storage.Emitter) *Walker {
@@ -19,6 +26,14 @@ func NewWalker(workers int, embedder embedding.Embedder, emitter storage.Emitter
 }
 
 func (w *Walker) Run(ctx context.Context, dirPath string) error {
+	filter := w.Filter
+	if filter == nil {
+		filter = NewFilter(dirPath)
+	}
+
+	if w.WorkerPool.Root == "" {
+		w.WorkerPool.Root = dirPath
+	}
 	w.WorkerPool.Start()
 	defer w.WorkerPool.Stop()
 
@@ -29,9 +44,17 @@ func (w *Walker) Run(ctx context.Context, dirPath string) error {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
-		if !d.IsDir() {
-			w.WorkerPool.Submit(path)
+		if d.IsDir() {
+			if filter.SkipDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filter.SkipFile(path) {
+			w.Skipped++
+			return nil
 		}
+		w.WorkerPool.Submit(path)
 		return nil
 	})
 }
@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -18,6 +19,41 @@ type WorkerPool struct {
 	emitter  storage.Emitter
 	jobChan  chan string
 	wg       sync.WaitGroup
+
+	// Linker holds back CALLS edges until all files are parsed and emits only
+	// those it can resolve. Set to nil to emit parser output unchanged.
+	Linker *Linker
+
+	// Documents builds the text embedded for each function, and
+	// EmbeddingModel names the model behind the embedder. Both are recorded on
+	// every embedded node.
+	Documents      *DocumentBuilder
+	EmbeddingModel string
+
+	// Root is the directory being ingested. Parsers that resolve imports to
+	// files on disk look no further up than it; empty bounds nothing.
+	Root string
+
+	// Manifest enables incremental ingest: files whose content hash is
+	// unchanged are skipped, and changed or deleted files emit tombstones for
+	// what they no longer produce. Stop updates it; the caller saves it.
+	Manifest *Manifest
+
+	// Contamination classifies functions as depending on the UI, database or
+	// I/O. Once calls are linked, Stop propagates the contamination to their
+	// callers and scores their risk, recording both on the functions as
+	// partial updates. Set to nil to skip the analysis.
+	Contamination ContaminationRules
+
+	mu        sync.Mutex
+	seen      map[string]bool        // Files submitted this run
+	records   map[string]*FileRecord // New manifest records of changed files
+	nodeFiles map[string]string      // Node ID -> changed file, for attributing linked calls
+	stats     IncrementalStats
+
+	functions map[string]FunctionFacts // Functions parsed this run, for the risk analysis
+	calls     []graph.Edge             // Calls emitted this run
+	rulesHash string
 }
 
 func NewWorkerPool(workers int, embedder embedding.Embedder, emitter storage.Emitter) *WorkerPool {
@@ -26,6 +62,15 @@ func NewWorkerPool(workers int, embedder embedding.Embedder, emitter storage.Emi
 		embedder: embedder,
 		emitter:  emitter,
 		jobChan:  make(chan string, 100),
+		Linker:   NewLinker(),
+
+		Documents:     &DocumentBuilder{},
+		Contamination: DefaultContaminationRules(),
+
+		seen:      make(map[string]bool),
+		records:   make(map[string]*FileRecord),
+		nodeFiles: make(map[string]string),
+		functions: make(map[string]FunctionFacts),
 	}
 }
 
@@ -57,11 +102,27 @@ func (wp *WorkerPool) processFile(path string) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	nodes, edges, err := parser.Parse(path, content)
+	var hash string
+	if wp.Manifest != nil {
+		hash = hashBytes(content)
+		if wp.unchanged(path, hash) {
+			return nil
+		}
+	}
+
+	var nodes []*graph.Node
+	var edges []*graph.Edge
+	if rooted, ok := parser.(analysis.RootedParser); ok && wp.Root != "" {
+		nodes, edges, err = rooted.ParseInRoot(wp.Root, path, content)
+	} else {
+		nodes, edges, err = parser.Parse(path, content)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to parse file: %w", err)
 	}
 
+	facts := wp.classify(path, content, nodes, edges)
+
 	// Create File Node
 	fileNode := &graph.Node{
 		ID:    path,
@@ -87,30 +148,53 @@ func (wp *WorkerPool) processFile(path string) error {
 	// Filter functions for embedding
 	var functionNodes []*graph.Node
 	var functionTexts []string
+	var chunkCounts []int
+	textHashes := make(map[string]string)
+	scopes := enclosingScopes(nodes, edges)
+	template := wp.Documents.template()
 
 	for _, node := range nodes {
 		if node.Label == "Function" || node.Label == "Method" {
-			if name, ok := node.Properties["name"].(string); ok {
-				functionNodes = append(functionNodes, node)
-				functionTexts = append(functionTexts, name)
+			docs := wp.Documents.Documents(node, DocumentScope{Enclosing: scopes[node.ID], Source: content})
+			if len(docs) == 0 {
+				continue
 			}
+			if wp.Manifest != nil {
+				textHashes[node.ID] = hashBytes([]byte(wp.EmbeddingModel + "\x00" + template + "\x00" + strings.Join(docs, "\x00")))
+				if wp.reuseEmbedding(path, node, textHashes[node.ID]) {
+					wp.markEmbedding(node)
+					continue
+				}
+			}
+			functionNodes = append(functionNodes, node)
+			functionTexts = append(functionTexts, docs...)
+			chunkCounts = append(chunkCounts, len(docs))
 		}
 	}
 
 	if len(functionTexts) > 0 {
-		embeddings, err := wp.embedder.EmbedBatch(functionTexts)
-		if err != nil {
-			log.Printf("WARNING: failed to embed batch for %s: %v. Continuing without embeddings.", path, err)
-		} else if len(embeddings) != len(functionNodes) {
-			log.Printf("WARNING: embedding count mismatch for %s", path)
+		embeddings, err := wp.embedder.EmbedBatch(functionTexts, embedding.PurposeDocument)
+		if err != nil || len(embeddings) != len(functionTexts) {
+			if err != nil {
+				log.Printf("WARNING: failed to embed batch for %s: %v. Continuing without embeddings.", path, err)
+			} else {
+				log.Printf("WARNING: embedding count mismatch for %s", path)
+			}
+			// Record the file without its hash, so the next run retries it
+			hash = ""
 		} else {
+			// Long functions were embedded in chunks; average them back into one vector
+			offset := 0
 			for i, node := range functionNodes {
-				node.Properties["embedding"] = embeddings[i]
+				node.Properties["embedding"] = meanVector(embeddings[offset : offset+chunkCounts[i]])
+				wp.markEmbedding(node)
+				offset += chunkCounts[i]
 			}
 		}
 	}
 
 	// Emit
+	emitted := definedInEdges
 	if err := wp.emitter.EmitNode(fileNode); err != nil {
 		return fmt.Errorf("failed to emit file node: %w", err)
 	}
@@ -124,15 +208,38 @@ func (wp *WorkerPool) processFile(path string) error {
 			return fmt.Errorf("failed to emit node: %w", err)
 		}
 	}
+	if wp.Linker != nil {
+		wp.Linker.AddNodes(nodes)
+	}
 	for _, edge := range edges {
+		if wp.Linker != nil && edge.Type == "CALLS" {
+			wp.Linker.AddCall(edge)
+			continue
+		}
 		if err := wp.emitter.EmitEdge(edge); err != nil {
 			return fmt.Errorf("failed to emit edge: %w", err)
 		}
+		emitted = append(emitted, edge)
+		if edge.Type == "CALLS" {
+			wp.addCall(edge)
+		}
+	}
+
+	if wp.Manifest != nil {
+		wp.record(path, hash, append([]*graph.Node{fileNode}, nodes...), emitted, textHashes, facts)
 	}
 
 	return nil
 }
 
+// markEmbedding records how the embedding of node was produced.
+func (wp *WorkerPool) markEmbedding(node *graph.Node) {
+	node.Properties["embedding_template"] = wp.Documents.template()
+	if wp.EmbeddingModel != "" {
+		node.Properties["embedding_model"] = wp.EmbeddingModel
+	}
+}
+
 func (wp *WorkerPool) Submit(filePath string) {
 	wp.jobChan <- filePath
 }
@@ -140,4 +247,36 @@ func (wp *WorkerPool) Submit(filePath string) {
 func (wp *WorkerPool) Stop() {
 	close(wp.jobChan)
 	wp.wg.Wait()
+
+	if wp.Linker != nil {
+		if wp.Manifest != nil {
+			wp.seedLinker()
+		}
+		wp.link()
+	}
+	if wp.Manifest != nil {
+		wp.applyManifest()
+		stats := wp.stats
+		log.Printf("Incremental ingest: %d changed, %d unchanged, %d deleted files; %d embeddings reused; %d node and %d edge tombstones",
+			stats.Changed, stats.Unchanged, stats.Deleted, stats.ReusedEmbedding, stats.NodeTombstones, stats.EdgeTombstones)
+	}
+	if wp.Contamination != nil {
+		wp.analyzeRisk()
+	}
+}
+
+// link resolves the queued CALLS edges against every parsed file and emits them.
+func (wp *WorkerPool) link() {
+	for _, edge := range wp.Linker.Link() {
+		if err := wp.emitter.EmitEdge(edge); err != nil {
+			log.Printf("Error emitting linked edge %s -> %s: %v", edge.SourceID, edge.TargetID, err)
+			continue
+		}
+		wp.addCall(edge)
+		if wp.Manifest != nil {
+			wp.recordLinkedCall(edge)
+		}
+	}
+	stats := wp.Linker.Stats()
+	log.Printf("Linked %d calls (%d rewritten), %d unresolved", stats.Resolved, stats.Rewritten, stats.Unresolved)
 }
@@ -2,6 +2,7 @@ package ingest
 
 import (
 	"errors"
+	"graphdb/internal/embedding"
 	"graphdb/internal/graph"
 	"testing"
 	"sync"
@@ -12,7 +13,7 @@ import (
 // MockEmbedder always fails
 type MockFailingEmbedder struct{}
 
-func (m *MockFailingEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
+func (m *MockFailingEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
 	return nil, errors.New("simulated embedding failure")
 }
 
@@ -0,0 +1,140 @@
+package loader
+
+import (
+	"context"
+	"sync"
+
+	"graphdb/internal/graph"
+)
+
+// Emitter is a storage.Emitter that writes ingest output straight into Neo4j.
+// Everything is buffered and loaded on Close, nodes before updates and edges,
+// so that each update finds its node and each edge both of its endpoints.
+type Emitter struct {
+	loader    *Neo4jLoader
+	ctx       context.Context
+	batchSize int
+
+	mu       sync.Mutex
+	nodes    []graph.Node
+	updates  []graph.Node
+	edges    []graph.Edge
+	replaces *FileGraph
+}
+
+// NewEmitter returns an Emitter loading through l in batches of batchSize.
+func (l *Neo4jLoader) NewEmitter(ctx context.Context, batchSize int) *Emitter {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &Emitter{loader: l, ctx: ctx, batchSize: batchSize}
+}
+
+func (e *Emitter) EmitNode(node *graph.Node) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nodes = append(e.nodes, *node)
+	return nil
+}
+
+func (e *Emitter) EmitEdge(edge *graph.Edge) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.edges = append(e.edges, *edge)
+	return nil
+}
+
+// UpdateNode buffers a partial update, merged into the existing node on Close.
+func (e *Emitter) UpdateNode(node *graph.Node) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.updates = append(e.updates, *node)
+	return nil
+}
+
+// Replace makes Close delete whatever of previous is not emitted again, so
+// files re-ingested in place lose the nodes and relationships they no longer
+// produce but keep the properties and relationships others gave them.
+func (e *Emitter) Replace(previous *FileGraph) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.replaces = previous
+}
+
+// Counts returns how many nodes and edges have been emitted.
+func (e *Emitter) Counts() (int, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.nodes), len(e.edges)
+}
+
+// Close loads the buffered nodes, applies the buffered updates, then loads the
+// buffered edges and deletes what they replace.
+func (e *Emitter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for start := 0; start < len(e.nodes); start += e.batchSize {
+		end := min(start+e.batchSize, len(e.nodes))
+		if err := e.loader.BatchLoadNodes(e.ctx, e.nodes[start:end]); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(e.updates); start += e.batchSize {
+		end := min(start+e.batchSize, len(e.updates))
+		if err := e.loader.BatchUpdateNodes(e.ctx, e.updates[start:end]); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(e.edges); start += e.batchSize {
+		end := min(start+e.batchSize, len(e.edges))
+		if err := e.loader.BatchLoadEdges(e.ctx, e.edges[start:end]); err != nil {
+			return err
+		}
+	}
+
+	if e.replaces != nil {
+		ids, edges := e.replaces.stale(e.nodes, e.edges)
+		for start := 0; start < len(edges); start += e.batchSize {
+			end := min(start+e.batchSize, len(edges))
+			if err := e.loader.BatchDeleteEdges(e.ctx, edges[start:end]); err != nil {
+				return err
+			}
+		}
+		for start := 0; start < len(ids); start += e.batchSize {
+			end := min(start+e.batchSize, len(ids))
+			if err := e.loader.BatchDeleteNodes(e.ctx, ids[start:end]); err != nil {
+				return err
+			}
+		}
+	}
+
+	e.nodes, e.updates, e.edges, e.replaces = nil, nil, nil, nil
+	return nil
+}
+
+// stale returns the nodes and relationships of fg missing from nodes and edges.
+func (fg *FileGraph) stale(nodes []graph.Node, edges []graph.Edge) ([]string, []graph.Edge) {
+	emittedNodes := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		emittedNodes[n.ID] = true
+	}
+	emittedEdges := make(map[graph.Edge]bool, len(edges))
+	for _, e := range edges {
+		emittedEdges[graph.Edge{SourceID: e.SourceID, TargetID: e.TargetID, Type: edgeType(e.Type)}] = true
+	}
+
+	var ids []string
+	for _, id := range fg.NodeIDs {
+		if !emittedNodes[id] {
+			ids = append(ids, id)
+		}
+	}
+	var stale []graph.Edge
+	for _, e := range fg.Edges {
+		if !emittedEdges[graph.Edge{SourceID: e.SourceID, TargetID: e.TargetID, Type: edgeType(e.Type)}] {
+			stale = append(stale, e)
+		}
+	}
+	return ids, stale
+}
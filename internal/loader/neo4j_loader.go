@@ -4,22 +4,52 @@ import (
 	"context"
 	"fmt"
 	"graphdb/internal/graph"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
+// VectorIndex describes a vector index over a node embedding property.
+type VectorIndex struct {
+	Name     string
+	Label    string
+	Property string
+}
+
+// VectorIndexes are the indexes queried by SearchSimilarFunctions and SearchFeatures.
+var VectorIndexes = []VectorIndex{
+	{Name: "function_embeddings", Label: "Function", Property: "embedding"},
+	{Name: "feature_embeddings", Label: "Feature", Property: "embedding"},
+}
+
+// nodeLabels are the labels ingest and enrichment give nodes. ApplyConstraints
+// indexes each of them on id and file, and the queries below match nodes per
+// label, as Neo4j cannot use those indexes for a node matched without one.
+var nodeLabels = []string{"File", "Function", "Method", "Class", "Interface", "Enum", "Field", "Global", "Feature", "Generic"}
+
+// DefaultVectorSimilarity is used when no similarity function is configured.
+const DefaultVectorSimilarity = "cosine"
+
 // Neo4jLoader handles batch loading of graph data into Neo4j.
 type Neo4jLoader struct {
 	Driver neo4j.DriverWithContext
 	DBName string
+
+	// Similarity is the vector similarity function ("cosine" or "euclidean").
+	Similarity string
+	// IndexTimeout bounds how long to wait for new indexes to come online.
+	IndexTimeout time.Duration
 }
 
 // NewNeo4jLoader creates a new loader instance.
 func NewNeo4jLoader(driver neo4j.DriverWithContext, dbName string) *Neo4jLoader {
 	return &Neo4jLoader{
-		Driver: driver,
-		DBName: dbName,
+		Driver:       driver,
+		DBName:       dbName,
+		Similarity:   DefaultVectorSimilarity,
+		IndexTimeout: 5 * time.Minute,
 	}
 }
 
@@ -47,6 +77,31 @@ func (l *Neo4jLoader) BatchLoadNodes(ctx context.Context, nodes []graph.Node) er
 	return nil
 }
 
+// BatchUpdateNodes merges the properties of each node into the existing node
+// with its label and ID. Nodes that do not exist are not created.
+func (l *Neo4jLoader) BatchUpdateNodes(ctx context.Context, nodes []graph.Node) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	batches := groupNodesByLabel(nodes)
+
+	session := l.Driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: l.DBName})
+	defer session.Close(ctx)
+
+	for label, batch := range batches {
+		query := buildUpdateNodesQuery(label)
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return tx.Run(ctx, query, map[string]any{"batch": batch})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update nodes for label %s: %w", label, err)
+		}
+	}
+
+	return nil
+}
+
 // BatchLoadEdges loads a batch of edges using UNWIND.
 func (l *Neo4jLoader) BatchLoadEdges(ctx context.Context, edges []graph.Edge) error {
 	if len(edges) == 0 {
@@ -71,6 +126,153 @@ func (l *Neo4jLoader) BatchLoadEdges(ctx context.Context, edges []graph.Edge) er
 	return nil
 }
 
+// BatchDeleteNodes removes nodes, and their relationships, by ID.
+func (l *Neo4jLoader) BatchDeleteNodes(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	session := l.Driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: l.DBName})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return tx.Run(ctx, buildDeleteNodesQuery(), map[string]any{"ids": ids})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete nodes: %w", err)
+	}
+	return nil
+}
+
+// BatchDeleteEdges removes relationships matching the given edges.
+func (l *Neo4jLoader) BatchDeleteEdges(ctx context.Context, edges []graph.Edge) error {
+	if len(edges) == 0 {
+		return nil
+	}
+
+	batches := groupEdgesByType(edges)
+
+	session := l.Driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: l.DBName})
+	defer session.Close(ctx)
+
+	for relType, batch := range batches {
+		query := buildDeleteEdgesQuery(relType)
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return tx.Run(ctx, query, map[string]any{"batch": batch})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete edges for type %s: %w", relType, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteFileSubgraphs removes the File nodes of the given paths and every node
+// defined in them, together with their relationships.
+func (l *Neo4jLoader) DeleteFileSubgraphs(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	session := l.Driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: l.DBName})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return tx.Run(ctx, buildDeleteFilesQuery(), map[string]any{"paths": paths})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file subgraphs: %w", err)
+	}
+	return nil
+}
+
+// InboundCalls returns the CALLS edges from nodes outside the given files to
+// nodes inside them, i.e. the calls lost when those files are deleted.
+func (l *Neo4jLoader) InboundCalls(ctx context.Context, paths []string) ([]graph.Edge, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	result, err := neo4j.ExecuteQuery(ctx, l.Driver, buildInboundCallsQuery(), map[string]any{"paths": paths},
+		neo4j.EagerResultTransformer, neo4j.ExecuteQueryWithDatabase(l.DBName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inbound calls: %w", err)
+	}
+
+	edges := make([]graph.Edge, 0, len(result.Records))
+	for _, record := range result.Records {
+		source, _, _ := neo4j.GetRecordValue[string](record, "source")
+		target, _, _ := neo4j.GetRecordValue[string](record, "target")
+		if source != "" && target != "" {
+			edges = append(edges, graph.Edge{SourceID: source, TargetID: target, Type: "CALLS"})
+		}
+	}
+	return edges, nil
+}
+
+// FileGraph is what ingest put in the graph for some files: the nodes defined
+// in them and the relationships out of those nodes.
+type FileGraph struct {
+	NodeIDs []string
+	Edges   []graph.Edge
+}
+
+// FileGraph returns the nodes of the given files and the relationships they
+// start, leaving out the relationships into Features, which enrichment owns.
+func (l *Neo4jLoader) FileGraph(ctx context.Context, paths []string) (*FileGraph, error) {
+	fg := &FileGraph{}
+	if len(paths) == 0 {
+		return fg, nil
+	}
+
+	result, err := neo4j.ExecuteQuery(ctx, l.Driver, buildFileGraphQuery(), map[string]any{"paths": paths},
+		neo4j.EagerResultTransformer, neo4j.ExecuteQueryWithDatabase(l.DBName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file subgraphs: %w", err)
+	}
+
+	for _, record := range result.Records {
+		id, _, _ := neo4j.GetRecordValue[string](record, "id")
+		if id == "" {
+			continue
+		}
+		fg.NodeIDs = append(fg.NodeIDs, id)
+		edges, _, _ := neo4j.GetRecordValue[[]any](record, "edges")
+		for _, raw := range edges {
+			e, _ := raw.(map[string]any)
+			relType, _ := e["type"].(string)
+			target, _ := e["target"].(string)
+			if relType != "" && target != "" {
+				fg.Edges = append(fg.Edges, graph.Edge{SourceID: id, TargetID: target, Type: relType})
+			}
+		}
+	}
+	return fg, nil
+}
+
+// Symbols returns every node a CALLS edge may point at, with its file, for
+// seeding the ingest linker.
+func (l *Neo4jLoader) Symbols(ctx context.Context) ([]*graph.Node, error) {
+	result, err := neo4j.ExecuteQuery(ctx, l.Driver, buildSymbolsQuery(), nil,
+		neo4j.EagerResultTransformer, neo4j.ExecuteQueryWithDatabase(l.DBName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query symbols: %w", err)
+	}
+
+	nodes := make([]*graph.Node, 0, len(result.Records))
+	for _, record := range result.Records {
+		id, _, _ := neo4j.GetRecordValue[string](record, "id")
+		label, _, _ := neo4j.GetRecordValue[string](record, "label")
+		file, _, _ := neo4j.GetRecordValue[string](record, "file")
+		if id == "" {
+			continue
+		}
+		nodes = append(nodes, &graph.Node{ID: id, Label: label, Properties: map[string]any{"file": file}})
+	}
+	return nodes, nil
+}
+
 // Wipe deletes all data from the database.
 func (l *Neo4jLoader) Wipe(ctx context.Context) error {
 	session := l.Driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: l.DBName})
@@ -96,7 +298,19 @@ func (l *Neo4jLoader) ApplyConstraints(ctx context.Context) error {
 		"CREATE CONSTRAINT IF NOT EXISTS FOR (n:Function) REQUIRE n.id IS UNIQUE",
 		"CREATE CONSTRAINT IF NOT EXISTS FOR (n:Class) REQUIRE n.id IS UNIQUE",
 		"CREATE INDEX IF NOT EXISTS FOR (n:Function) ON (n.name)",
-		"CREATE INDEX IF NOT EXISTS FOR (n:File) ON (n.file)",
+		"CREATE INDEX IF NOT EXISTS FOR (n:Function) ON (n.feature_id)",
+		// Query targets are resolved by id or name among these labels
+		"CREATE INDEX IF NOT EXISTS FOR (n:File) ON (n.name)",
+		"CREATE INDEX IF NOT EXISTS FOR (n:Class) ON (n.name)",
+		"CREATE INDEX IF NOT EXISTS FOR (n:Method) ON (n.name)",
+		"CREATE INDEX IF NOT EXISTS FOR (n:Global) ON (n.name)",
+		"CREATE INDEX IF NOT EXISTS FOR (n:Feature) ON (n.name)",
+	}
+	for _, label := range nodeLabels {
+		if label != "File" && label != "Function" && label != "Class" {
+			constraints = append(constraints, fmt.Sprintf("CREATE INDEX IF NOT EXISTS FOR (n:%s) ON (n.id)", label))
+		}
+		constraints = append(constraints, fmt.Sprintf("CREATE INDEX IF NOT EXISTS FOR (n:%s) ON (n.file)", label))
 	}
 
 	for _, query := range constraints {
@@ -107,6 +321,136 @@ func (l *Neo4jLoader) ApplyConstraints(ctx context.Context) error {
 			return fmt.Errorf("failed to apply constraint '%s': %w", query, err)
 		}
 	}
+
+	return l.ApplyVectorIndexes(ctx)
+}
+
+// ApplyVectorIndexes creates the vector indexes used for semantic search.
+// Dimensions are taken from the embeddings already stored on each label, so
+// labels without embeddings are skipped; call this again after importing nodes.
+// It waits for new indexes to come online and returns an error describing any
+// mismatch between an index and the stored embeddings.
+func (l *Neo4jLoader) ApplyVectorIndexes(ctx context.Context) error {
+	similarity, err := normalizeSimilarity(l.Similarity)
+	if err != nil {
+		return err
+	}
+
+	session := l.Driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: l.DBName})
+	defer session.Close(ctx)
+
+	var created []string
+	var mismatches []string
+
+	for _, idx := range VectorIndexes {
+		dims, outliers, err := l.detectDimensions(ctx, session, idx)
+		if err != nil {
+			return err
+		}
+		if dims == 0 {
+			continue // Nothing to index yet
+		}
+		if outliers > 0 {
+			mismatches = append(mismatches, fmt.Sprintf("%d %s nodes have embeddings that are not %d-dimensional", outliers, idx.Label, dims))
+		}
+
+		existing, found, err := l.indexDimensions(ctx, session, idx.Name)
+		if err != nil {
+			return err
+		}
+		if found {
+			if existing != dims {
+				mismatches = append(mismatches, fmt.Sprintf("index %s has %d dimensions but stored %s embeddings have %d", idx.Name, existing, idx.Label, dims))
+			}
+			continue
+		}
+
+		query := buildVectorIndexQuery(idx, dims, similarity)
+		_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return tx.Run(ctx, query, nil)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create vector index %s: %w", idx.Name, err)
+		}
+		log.Printf("Created vector index %s (%d dimensions, %s)", idx.Name, dims, similarity)
+		created = append(created, idx.Name)
+	}
+
+	for _, name := range created {
+		if err := l.awaitIndex(ctx, session, name); err != nil {
+			return err
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("embedding dimension mismatch: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// detectDimensions returns the most common embedding size for a label and the
+// number of embeddings of any other size.
+func (l *Neo4jLoader) detectDimensions(ctx context.Context, session neo4j.SessionWithContext, idx VectorIndex) (int, int, error) {
+	res, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, buildEmbeddingDimensionsQuery(idx), nil)
+		if err != nil {
+			return nil, err
+		}
+		return result.Collect(ctx)
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to detect embedding dimensions for %s: %w", idx.Label, err)
+	}
+
+	dims, outliers := 0, 0
+	for i, record := range res.([]*neo4j.Record) {
+		size, _, _ := neo4j.GetRecordValue[int64](record, "dims")
+		count, _, _ := neo4j.GetRecordValue[int64](record, "count")
+		if i == 0 {
+			dims = int(size)
+		} else {
+			outliers += int(count)
+		}
+	}
+	return dims, outliers, nil
+}
+
+// indexDimensions returns the configured dimensions of an existing index.
+func (l *Neo4jLoader) indexDimensions(ctx context.Context, session neo4j.SessionWithContext, name string) (int, bool, error) {
+	res, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, "SHOW INDEXES YIELD name, options WHERE name = $name RETURN options", map[string]any{"name": name})
+		if err != nil {
+			return nil, err
+		}
+		return result.Collect(ctx)
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to inspect index %s: %w", name, err)
+	}
+
+	records := res.([]*neo4j.Record)
+	if len(records) == 0 {
+		return 0, false, nil
+	}
+	options, _, _ := neo4j.GetRecordValue[map[string]any](records[0], "options")
+	return parseIndexDimensions(options), true, nil
+}
+
+// awaitIndex blocks until the named index is online or the timeout expires.
+func (l *Neo4jLoader) awaitIndex(ctx context.Context, session neo4j.SessionWithContext, name string) error {
+	_, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, "CALL db.awaitIndex($name, $timeout)", map[string]any{
+			"name":    name,
+			"timeout": int64(l.IndexTimeout / time.Second),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("vector index %s did not come online: %w", name, err)
+	}
 	return nil
 }
 
@@ -122,6 +466,20 @@ func (l *Neo4jLoader) UpdateGraphState(ctx context.Context, commit string) error
 	return err
 }
 
+// GraphState returns the commit recorded by UpdateGraphState, or "" if none.
+func (l *Neo4jLoader) GraphState(ctx context.Context) (string, error) {
+	result, err := neo4j.ExecuteQuery(ctx, l.Driver, "MATCH (s:GraphState) RETURN s.commit AS commit LIMIT 1", nil,
+		neo4j.EagerResultTransformer, neo4j.ExecuteQueryWithDatabase(l.DBName))
+	if err != nil {
+		return "", fmt.Errorf("failed to read graph state: %w", err)
+	}
+	if len(result.Records) == 0 {
+		return "", nil
+	}
+	commit, _, _ := neo4j.GetRecordValue[string](result.Records[0], "commit")
+	return commit, nil
+}
+
 // Helpers extracted for testing
 func groupNodesByLabel(nodes []graph.Node) map[string][]map[string]any {
 	batches := make(map[string][]map[string]any)
@@ -150,14 +508,19 @@ func buildNodeQuery(label string) string {
 		`, sanitizeLabel(label))
 }
 
+func buildUpdateNodesQuery(label string) string {
+	return fmt.Sprintf(`
+			UNWIND $batch AS row
+			MATCH (n:%s {id: row.id})
+			SET n += row
+		`, sanitizeLabel(label))
+}
+
 func groupEdgesByType(edges []graph.Edge) map[string][]map[string]any {
 	batches := make(map[string][]map[string]any)
 	for _, e := range edges {
-		relType := e.Type
-		if relType == "" {
-			relType = "RELATED_TO"
-		}
-		
+		relType := edgeType(e.Type)
+
 		row := map[string]any{
 			"sourceId": e.SourceID,
 			"targetId": e.TargetID,
@@ -167,13 +530,92 @@ func groupEdgesByType(edges []graph.Edge) map[string][]map[string]any {
 	return batches
 }
 
+// matchPerLabel is a subquery binding variable to the nodes of any of
+// nodeLabels that match where, which may refer to the imported variables.
+func matchPerLabel(imports, variable, where string) string {
+	parts := make([]string, len(nodeLabels))
+	for i, label := range nodeLabels {
+		parts[i] = fmt.Sprintf("WITH %s MATCH (%s:%s) WHERE %s RETURN %s", imports, variable, label, where, variable)
+	}
+	return "CALL {\n\t\t\t\t" + strings.Join(parts, "\n\t\t\t\tUNION\n\t\t\t\t") + "\n\t\t\t}"
+}
+
+// matchFileNodes is a subquery binding n to the File node of path and every
+// node defined in it.
+func matchFileNodes() string {
+	return matchPerLabel("path", "n", "n.file = path OR (n:File AND n.id = path)")
+}
+
+// edgeType is the relationship type of an edge, RELATED_TO if it has none.
+func edgeType(relType string) string {
+	if relType == "" {
+		return "RELATED_TO"
+	}
+	return relType
+}
+
 func buildEdgeQuery(relType string) string {
 	return fmt.Sprintf(`
 			UNWIND $batch AS row
-			MATCH (source {id: row.sourceId})
-			MATCH (target {id: row.targetId})
+			%s
+			%s
 			MERGE (source)-[r:%s]->(target)
-		`, sanitizeLabel(relType))
+		`, matchPerLabel("row", "source", "source.id = row.sourceId"), matchPerLabel("row", "target", "target.id = row.targetId"), sanitizeLabel(relType))
+}
+
+func buildDeleteNodesQuery() string {
+	return fmt.Sprintf(`
+			UNWIND $ids AS id
+			%s
+			DETACH DELETE n
+		`, matchPerLabel("id", "n", "n.id = id"))
+}
+
+func buildDeleteEdgesQuery(relType string) string {
+	return fmt.Sprintf(`
+			UNWIND $batch AS row
+			%s
+			MATCH (source)-[r:%s]->(target {id: row.targetId})
+			DELETE r
+		`, matchPerLabel("row", "source", "source.id = row.sourceId"), sanitizeLabel(relType))
+}
+
+func buildDeleteFilesQuery() string {
+	return fmt.Sprintf(`
+			UNWIND $paths AS path
+			%s
+			DETACH DELETE n
+		`, matchFileNodes())
+}
+
+func buildInboundCallsQuery() string {
+	return fmt.Sprintf(`
+			UNWIND $paths AS path
+			%s
+			MATCH (caller)-[:CALLS]->(n)
+			WHERE NOT coalesce(caller.file, '') IN $paths
+			RETURN DISTINCT caller.id AS source, n.id AS target
+		`, matchFileNodes())
+}
+
+// buildFileGraphQuery returns the nodes of the files and the relationships
+// out of them, except those into Features, which enrichment owns.
+func buildFileGraphQuery() string {
+	return fmt.Sprintf(`
+			UNWIND $paths AS path
+			%s
+			OPTIONAL MATCH (n)-[r]->(m)
+			WHERE NOT m:Feature
+			RETURN DISTINCT n.id AS id, collect(CASE WHEN r IS NULL THEN null ELSE {type: type(r), target: m.id} END) AS edges
+		`, matchFileNodes())
+}
+
+func buildSymbolsQuery() string {
+	return `
+			MATCH (n)
+			WHERE n:Function OR n:Method OR n:Class OR n:Interface OR n:Enum
+			RETURN n.id AS id, labels(n)[0] AS label, coalesce(n.file, '') AS file
+		`
 }
 
 func buildWipeQuery() string {
@@ -187,6 +629,50 @@ func buildGraphStateQuery() string {
 	`
 }
 
+func buildVectorIndexQuery(idx VectorIndex, dims int, similarity string) string {
+	return fmt.Sprintf(`
+			CREATE VECTOR INDEX %s IF NOT EXISTS
+			FOR (n:%s) ON (n.%s)
+			OPTIONS {indexConfig: {`+"`vector.dimensions`"+`: %d, `+"`vector.similarity_function`"+`: '%s'}}
+		`, sanitizeLabel(idx.Name), sanitizeLabel(idx.Label), sanitizeLabel(idx.Property), dims, similarity)
+}
+
+func buildEmbeddingDimensionsQuery(idx VectorIndex) string {
+	return fmt.Sprintf(`
+			MATCH (n:%s) WHERE n.%s IS NOT NULL
+			RETURN size(n.%s) AS dims, count(*) AS count
+			ORDER BY count DESC
+		`, sanitizeLabel(idx.Label), sanitizeLabel(idx.Property), sanitizeLabel(idx.Property))
+}
+
+// parseIndexDimensions reads vector.dimensions from SHOW INDEXES options.
+func parseIndexDimensions(options map[string]any) int {
+	config, ok := options["indexConfig"].(map[string]any)
+	if !ok {
+		return 0
+	}
+	switch v := config["vector.dimensions"].(type) {
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func normalizeSimilarity(similarity string) (string, error) {
+	switch s := strings.ToLower(strings.TrimSpace(similarity)); s {
+	case "":
+		return DefaultVectorSimilarity, nil
+	case "cosine", "euclidean":
+		return s, nil
+	default:
+		return "", fmt.Errorf("unsupported vector similarity function %q (expected cosine or euclidean)", similarity)
+	}
+}
+
 func sanitizeLabel(label string) string {
 	return strings.ReplaceAll(label, "`", "")
 }
@@ -16,6 +16,16 @@ func TestBuildNodeQuery(t *testing.T) {
 	}
 }
 
+func TestBuildUpdateNodesQuery(t *testing.T) {
+	query := buildUpdateNodesQuery("Function")
+	if !strings.Contains(query, "MATCH (n:Function {id: row.id})") || !strings.Contains(query, "SET n += row") {
+		t.Errorf("Expected properties merged into matched nodes: %s", query)
+	}
+	if strings.Contains(query, "MERGE") {
+		t.Errorf("Expected an update never to create nodes: %s", query)
+	}
+}
+
 func TestBuildEdgeQuery(t *testing.T) {
 	query := buildEdgeQuery("CALLS")
 	if !strings.Contains(query, "UNWIND $batch AS row") {
@@ -45,6 +55,90 @@ func TestGroupNodesByLabel(t *testing.T) {
 	}
 }
 
+func TestBuildDeleteQueries(t *testing.T) {
+	query := buildDeleteNodesQuery()
+	if !strings.Contains(query, "UNWIND $ids AS id") || !strings.Contains(query, "DETACH DELETE n") {
+		t.Errorf("Unexpected node delete query: %s", query)
+	}
+
+	if !strings.Contains(query, "MATCH (n:Function) WHERE n.id = id") {
+		t.Errorf("Expected nodes matched per label: %s", query)
+	}
+
+	query = buildDeleteEdgesQuery("CALLS")
+	if !strings.Contains(query, "MATCH (source:Function) WHERE source.id = row.sourceId") || !strings.Contains(query, "MATCH (source)-[r:CALLS]->(target {id: row.targetId})") {
+		t.Errorf("Missing typed relationship match: %s", query)
+	}
+	if !strings.Contains(query, "DELETE r") || strings.Contains(query, "DETACH") {
+		t.Errorf("Expected only the relationship to be deleted: %s", query)
+	}
+}
+
+func TestBuildSyncQueries(t *testing.T) {
+	query := buildDeleteFilesQuery()
+	if !strings.Contains(query, "MATCH (n:File) WHERE n.file = path OR (n:File AND n.id = path)") || !strings.Contains(query, "DETACH DELETE n") {
+		t.Errorf("Unexpected file delete query: %s", query)
+	}
+
+	query = buildInboundCallsQuery()
+	if !strings.Contains(query, "MATCH (caller)-[:CALLS]->(n)") || !strings.Contains(query, "WHERE NOT coalesce(caller.file, '') IN $paths") {
+		t.Errorf("Expected only calls from outside the files: %s", query)
+	}
+
+	query = buildFileGraphQuery()
+	if !strings.Contains(query, "OPTIONAL MATCH (n)-[r]->(m)") || !strings.Contains(query, "WHERE NOT m:Feature") {
+		t.Errorf("Expected the relationships out of the files, except into Features: %s", query)
+	}
+
+	query = buildSymbolsQuery()
+	for _, label := range []string{"n:Function", "n:Method", "n:Class", "n:Interface", "n:Enum"} {
+		if !strings.Contains(query, label) {
+			t.Errorf("Symbols query missing %s: %s", label, query)
+		}
+	}
+}
+
+func TestQueriesMatchNodesPerLabel(t *testing.T) {
+	for _, query := range []string{
+		buildEdgeQuery("CALLS"),
+		buildDeleteNodesQuery(),
+		buildDeleteEdgesQuery("CALLS"),
+		buildDeleteFilesQuery(),
+		buildInboundCallsQuery(),
+		buildFileGraphQuery(),
+	} {
+		for _, unlabelled := range []string{"MATCH (n)\n", "MATCH (n {", "MATCH (source {", "MATCH (n) WHERE"} {
+			if strings.Contains(query, unlabelled) {
+				t.Errorf("Expected no unlabelled node scan (%q) in: %s", unlabelled, query)
+			}
+		}
+		if strings.Count(query, "UNION") < len(nodeLabels)-1 {
+			t.Errorf("Expected a match per label in: %s", query)
+		}
+	}
+}
+
+func TestFileGraph_Stale(t *testing.T) {
+	previous := &FileGraph{
+		NodeIDs: []string{"a.go", "a.go:Run", "a.go:Old"},
+		Edges: []graph.Edge{
+			{SourceID: "a.go:Run", TargetID: "b.go:Helper", Type: "CALLS"},
+			{SourceID: "a.go:Run", TargetID: "g.go:Config", Type: "USES_GLOBAL"},
+			{SourceID: "a.go:Old", TargetID: "a.go", Type: "DEFINED_IN"},
+		},
+	}
+	nodes := []graph.Node{{ID: "a.go"}, {ID: "a.go:Run"}, {ID: "a.go:New"}}
+	edges := []graph.Edge{{SourceID: "a.go:Run", TargetID: "b.go:Helper", Type: "CALLS"}}
+
+	ids, stale := previous.stale(nodes, edges)
+	if len(ids) != 1 || ids[0] != "a.go:Old" {
+		t.Errorf("Expected only Old to be deleted, got %v", ids)
+	}
+	if len(stale) != 2 || stale[0].Type != "USES_GLOBAL" || stale[1].Type != "DEFINED_IN" {
+		t.Errorf("Expected the dropped global use and Old's edge, got %v", stale)
+	}
+}
+
 func TestBuildWipeQuery(t *testing.T) {
 	query := buildWipeQuery()
 	if !strings.Contains(query, "MATCH (n) DETACH DELETE n") {
@@ -62,3 +156,63 @@ func TestBuildGraphStateQuery(t *testing.T) {
 	}
 }
 
+
+func TestBuildVectorIndexQuery(t *testing.T) {
+	idx := VectorIndex{Name: "function_embeddings", Label: "Function", Property: "embedding"}
+	query := buildVectorIndexQuery(idx, 768, "cosine")
+	if !strings.Contains(query, "CREATE VECTOR INDEX function_embeddings IF NOT EXISTS") {
+		t.Error("Missing CREATE VECTOR INDEX clause")
+	}
+	if !strings.Contains(query, "FOR (n:Function) ON (n.embedding)") {
+		t.Error("Missing label/property target")
+	}
+	if !strings.Contains(query, "`vector.dimensions`: 768") {
+		t.Error("Missing dimensions option")
+	}
+	if !strings.Contains(query, "`vector.similarity_function`: 'cosine'") {
+		t.Error("Missing similarity option")
+	}
+}
+
+func TestBuildEmbeddingDimensionsQuery(t *testing.T) {
+	query := buildEmbeddingDimensionsQuery(VectorIndex{Name: "feature_embeddings", Label: "Feature", Property: "embedding"})
+	if !strings.Contains(query, "MATCH (n:Feature) WHERE n.embedding IS NOT NULL") {
+		t.Error("Missing MATCH clause")
+	}
+	if !strings.Contains(query, "ORDER BY count DESC") {
+		t.Error("Dimensions must be ordered by frequency")
+	}
+}
+
+func TestParseIndexDimensions(t *testing.T) {
+	options := map[string]any{
+		"indexProvider": "vector-2.0",
+		"indexConfig": map[string]any{
+			"vector.dimensions":          int64(768),
+			"vector.similarity_function": "COSINE",
+		},
+	}
+	if got := parseIndexDimensions(options); got != 768 {
+		t.Errorf("Expected 768 dimensions, got %d", got)
+	}
+	if got := parseIndexDimensions(map[string]any{}); got != 0 {
+		t.Errorf("Expected 0 dimensions for non-vector index, got %d", got)
+	}
+}
+
+func TestNormalizeSimilarity(t *testing.T) {
+	tests := map[string]string{
+		"":          "cosine",
+		"COSINE":    "cosine",
+		"euclidean": "euclidean",
+	}
+	for in, want := range tests {
+		got, err := normalizeSimilarity(in)
+		if err != nil || got != want {
+			t.Errorf("normalizeSimilarity(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := normalizeSimilarity("dot"); err == nil {
+		t.Error("Expected error for unsupported similarity function")
+	}
+}
@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema returns the JSON schema of the arguments struct v: one property per
+// json-tagged field, described by its desc tag. Fields without omitempty are
+// required.
+func Schema(v any) map[string]any {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	properties := make(map[string]any)
+	required := make([]string, 0)
+	addFields(t, properties, &required)
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// addFields adds the properties of the fields of struct type t, including
+// those of embedded structs, as encoding/json flattens them.
+func addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+			addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		property := typeSchema(field.Type)
+		if desc := field.Tag.Get("desc"); desc != "" {
+			property["description"] = desc
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			property["enum"] = strings.Split(enum, ",")
+		}
+		properties[name] = property
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	}
+	return map[string]any{}
+}
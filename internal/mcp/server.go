@@ -0,0 +1,277 @@
+// Package mcp serves the graph to agents over the Model Context Protocol: a
+// JSON-RPC 2.0 session on stdio whose tools are the GraphProvider queries and
+// whose resources describe the graph.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+)
+
+// ProtocolVersions are the MCP revisions the server speaks, newest first.
+var ProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Tool describes a tool to the client.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// Resource describes a resource to the client.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// Content is one block of a tool result or resource.
+type Content struct {
+	Type     string `json:"type,omitempty"`
+	URI      string `json:"uri,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// ToolResult is the result of tools/call. Failures of the tool itself, such
+// as an unknown node, are results with IsError set, so the model sees them.
+type ToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// ToolHandler runs a tool with its JSON arguments and returns a value to be
+// sent back as JSON text.
+type ToolHandler func(ctx context.Context, args json.RawMessage) (any, error)
+
+// ResourceReader returns the current value of a resource, sent back as JSON.
+type ResourceReader func(ctx context.Context) (any, error)
+
+// Server is an MCP server. Register tools and resources, then Serve.
+type Server struct {
+	Name    string
+	Version string
+
+	tools     map[string]Tool
+	handlers  map[string]ToolHandler
+	resources map[string]Resource
+	readers   map[string]ResourceReader
+}
+
+// NewServer returns a server without tools or resources.
+func NewServer(name, version string) *Server {
+	return &Server{
+		Name:      name,
+		Version:   version,
+		tools:     make(map[string]Tool),
+		handlers:  make(map[string]ToolHandler),
+		resources: make(map[string]Resource),
+		readers:   make(map[string]ResourceReader),
+	}
+}
+
+// AddTool registers a tool, replacing any of the same name.
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	s.tools[tool.Name] = tool
+	s.handlers[tool.Name] = handler
+}
+
+// AddResource registers a resource, replacing any with the same URI.
+func (s *Server) AddResource(resource Resource, reader ResourceReader) {
+	s.resources[resource.URI] = resource
+	s.readers[resource.URI] = reader
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes the
+// responses to w until r ends or ctx is done. Requests are answered in order;
+// notifications get no response.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	send := func(resp *response) error {
+		return enc.Encode(resp)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := send(&response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.ID == nil {
+			// Notifications (initialized, cancelled, ...) need no answer
+			continue
+		}
+
+		resp := &response{JSONRPC: "2.0", ID: req.ID}
+		result, err := s.handle(ctx, &req)
+		if err != nil {
+			var rpcErr *rpcError
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &rpcError{Code: codeInternalError, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+		} else {
+			resp.Result = result
+		}
+		if err := send(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handle(ctx context.Context, req *request) (any, error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "not a JSON-RPC 2.0 request"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := ProtocolVersions[0]
+		for _, v := range ProtocolVersions {
+			if v == params.ProtocolVersion {
+				version = v
+			}
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities": map[string]any{
+				"tools":     map[string]any{"listChanged": false},
+				"resources": map[string]any{"subscribe": false, "listChanged": false},
+			},
+			"serverInfo": map[string]any{"name": s.Name, "version": s.Version},
+		}, nil
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		tools := make([]Tool, 0, len(s.tools))
+		for _, tool := range s.tools {
+			tools = append(tools, tool)
+		}
+		sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+		return map[string]any{"tools": tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		handler, ok := s.handlers[params.Name]
+		if !ok {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+		}
+		if len(params.Arguments) == 0 || string(params.Arguments) == "null" {
+			params.Arguments = json.RawMessage("{}")
+		}
+		return s.callTool(ctx, params.Name, handler, params.Arguments), nil
+
+	case "resources/list":
+		resources := make([]Resource, 0, len(s.resources))
+		for _, resource := range s.resources {
+			resources = append(resources, resource)
+		}
+		sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
+		return map[string]any{"resources": resources}, nil
+
+	case "resources/read":
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		reader, ok := s.readers[params.URI]
+		if !ok {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown resource: %s", params.URI)}
+		}
+		value, err := reader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		text, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		content := Content{URI: params.URI, MimeType: s.resources[params.URI].MimeType, Text: string(text)}
+		return map[string]any{"contents": []Content{content}}, nil
+	}
+
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+func (s *Server) callTool(ctx context.Context, name string, handler ToolHandler, args json.RawMessage) *ToolResult {
+	value, err := handler(ctx, args)
+	if err != nil {
+		log.Printf("Tool %s failed: %v", name, err)
+		return &ToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	if text, ok := value.(string); ok {
+		// Source code and the like go out as is
+		return &ToolResult{Content: []Content{{Type: "text", Text: text}}}
+	}
+	text, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return &ToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	return &ToolResult{Content: []Content{{Type: "text", Text: string(text)}}}
+}
@@ -0,0 +1,266 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"graphdb/internal/embedding"
+	"graphdb/internal/query"
+)
+
+// client is a minimal MCP client talking to a Server in the same process.
+type client struct {
+	t    *testing.T
+	enc  *json.Encoder
+	dec  *json.Decoder
+	next int
+}
+
+func newClient(t *testing.T, s *Server) *client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(context.Background(), serverIn, serverOut)
+		serverOut.Close()
+	}()
+	t.Cleanup(func() {
+		clientOut.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	})
+	return &client{t: t, enc: json.NewEncoder(clientOut), dec: json.NewDecoder(clientIn)}
+}
+
+// call sends a request and decodes its result into result, returning the
+// JSON-RPC error if there is one.
+func (c *client) call(method string, params any, result any) *rpcError {
+	c.t.Helper()
+	c.next++
+	if err := c.enc.Encode(map[string]any{"jsonrpc": "2.0", "id": c.next, "method": method, "params": params}); err != nil {
+		c.t.Fatalf("Failed to send %s: %v", method, err)
+	}
+	var resp struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := c.dec.Decode(&resp); err != nil {
+		c.t.Fatalf("Failed to read the response to %s: %v", method, err)
+	}
+	if resp.ID != c.next {
+		c.t.Fatalf("Expected the response to request %d, got %d", c.next, resp.ID)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			c.t.Fatalf("Failed to decode the result of %s: %v", method, err)
+		}
+	}
+	return nil
+}
+
+func (c *client) notify(method string) {
+	c.t.Helper()
+	if err := c.enc.Encode(map[string]any{"jsonrpc": "2.0", "method": method}); err != nil {
+		c.t.Fatalf("Failed to send %s: %v", method, err)
+	}
+}
+
+// tool calls a tool and returns the text of its result and whether it failed.
+func (c *client) tool(name string, args map[string]any) (string, bool) {
+	c.t.Helper()
+	var result ToolResult
+	if err := c.call("tools/call", map[string]any{"name": name, "arguments": args}, &result); err != nil {
+		c.t.Fatalf("tools/call %s failed: %v", name, err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Type != "text" {
+		c.t.Fatalf("Expected one text block from %s, got %+v", name, result.Content)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+// fixedEmbedder embeds every text as the same vector.
+type fixedEmbedder []float32
+
+func (e fixedEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = e
+	}
+	return vectors, nil
+}
+
+// testServer serves a small graph:
+//
+//	Main -CALLS-> Handler -CALLS-> Save; util.Save is a second Save
+//	Handler -IMPLEMENTS-> feat-auth
+func testServer(t *testing.T) *client {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app.ts")
+	if err := os.WriteFile(src, []byte("function Save() {\n  return 1;\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	records := []string{
+		`{"id": "app:Main", "type": "Function", "name": "Main", "file": "` + src + `", "start_line": 10}`,
+		`{"id": "app:Handler", "type": "Function", "name": "Handler", "file": "` + src + `", "start_line": 5}`,
+		`{"id": "app:Save", "type": "Function", "name": "Save", "file": "` + src + `", "start_line": 1, "end_line": 3}`,
+		`{"id": "util:Save", "type": "Function", "name": "Save", "file": "util.ts", "start_line": 7}`,
+		`{"id": "feat-auth", "type": "Feature", "name": "Auth", "embedding": [0, 1]}`,
+		`{"id": "feat-billing", "type": "Feature", "name": "Billing", "embedding": [1, 0]}`,
+		`{"id": "state", "type": "GraphState", "commit": "abc123"}`,
+		`{"source": "app:Main", "target": "app:Handler", "type": "CALLS"}`,
+		`{"source": "app:Handler", "target": "app:Save", "type": "CALLS"}`,
+		`{"source": "app:Handler", "target": "feat-auth", "type": "IMPLEMENTS"}`,
+	}
+	path := filepath.Join(dir, "graph.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(records, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := query.NewJSONLProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.Close() })
+
+	c := newClient(t, NewGraphServer(provider, fixedEmbedder{0, 1}, "test"))
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	if err := c.call("initialize", map[string]any{"protocolVersion": "2025-03-26", "capabilities": map[string]any{}}, &init); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	if init.ProtocolVersion != "2025-03-26" || init.ServerInfo.Name != "graphdb" {
+		t.Fatalf("Unexpected initialize result %+v", init)
+	}
+	c.notify("notifications/initialized")
+	return c
+}
+
+func TestServer_Protocol(t *testing.T) {
+	c := testServer(t)
+
+	if err := c.call("ping", nil, nil); err != nil {
+		t.Errorf("ping failed: %v", err)
+	}
+	if err := c.call("prompts/list", nil, nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("Expected method not found, got %v", err)
+	}
+	if err := c.call("tools/call", map[string]any{"name": "missing"}, nil); err == nil || err.Code != codeInvalidParams {
+		t.Errorf("Expected invalid params for an unknown tool, got %v", err)
+	}
+
+	var list struct {
+		Tools []Tool `json:"tools"`
+	}
+	if err := c.call("tools/list", nil, &list); err != nil {
+		t.Fatalf("tools/list failed: %v", err)
+	}
+	var names []string
+	var impact Tool
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+		if tool.Name == "impact" {
+			impact = tool
+		}
+	}
+	want := []string{"callers", "explore_domain", "fetch_source", "find_node", "globals", "impact", "locate_usage",
+		"neighbors", "resolve_node", "seams", "search_features", "search_similar", "status", "traverse"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected tools %v, got %v", want, names)
+	}
+	properties, _ := impact.InputSchema["properties"].(map[string]any)
+	groupBy, _ := properties["group_by"].(map[string]any)
+	if len(properties) != 5 || !reflect.DeepEqual(impact.InputSchema["required"], []any{"target"}) || groupBy["type"] != "string" {
+		t.Errorf("Unexpected impact schema %+v", impact.InputSchema)
+	}
+
+	var resources struct {
+		Resources []Resource `json:"resources"`
+	}
+	if err := c.call("resources/list", nil, &resources); err != nil || len(resources.Resources) != 1 || resources.Resources[0].URI != StatusURI {
+		t.Fatalf("Expected the status resource, got %+v, %v", resources, err)
+	}
+	var read struct {
+		Contents []Content `json:"contents"`
+	}
+	if err := c.call("resources/read", map[string]any{"uri": StatusURI}, &read); err != nil {
+		t.Fatalf("resources/read failed: %v", err)
+	}
+	if len(read.Contents) != 1 || !strings.Contains(read.Contents[0].Text, `"commit": "abc123"`) {
+		t.Errorf("Expected the commit, got %+v", read.Contents)
+	}
+}
+
+func TestServer_GraphTools(t *testing.T) {
+	c := testServer(t)
+
+	text, isError := c.tool("impact", map[string]any{"target": "app:Save", "depth": 2, "group_by": "feature"})
+	var impact query.ImpactResult
+	if isError || json.Unmarshal([]byte(text), &impact) != nil {
+		t.Fatalf("impact failed: %s", text)
+	}
+	if len(impact.Callers) != 2 || impact.Callers[0].ID != "app:Handler" || len(impact.Paths[1].Nodes) != 3 {
+		t.Errorf("Expected Handler and Main with their paths, got %s", text)
+	}
+	if len(impact.Groups) != 2 || impact.Groups[0].Key != "feat-auth" {
+		t.Errorf("Expected callers grouped by feature, got %+v", impact.Groups)
+	}
+
+	// Ambiguous names fail with their candidates, qualifiers single one out
+	text, isError = c.tool("callers", map[string]any{"target": "Save"})
+	if !isError || !strings.Contains(text, "util:Save (util.ts:7)") {
+		t.Errorf("Expected the candidates of Save, got %s", text)
+	}
+	text, isError = c.tool("callers", map[string]any{"target": "Save", "file": "app.ts"})
+	if isError || !strings.Contains(text, "Handler") {
+		t.Errorf("Expected Handler to call app.ts Save, got %s", text)
+	}
+	text, isError = c.tool("neighbors", map[string]any{"target": "Handlr"})
+	if !isError || !strings.Contains(text, "did you mean Handler?") {
+		t.Errorf("Expected a suggestion, got %s", text)
+	}
+
+	text, isError = c.tool("fetch_source", map[string]any{"target": "app:Save"})
+	if isError || !strings.HasPrefix(text, "function Save() {") {
+		t.Errorf("Expected the raw source, got %q", text)
+	}
+
+	text, isError = c.tool("search_features", map[string]any{"query": "login", "limit": 1})
+	if isError || !strings.Contains(text, "feat-auth") || strings.Contains(text, "feat-billing") {
+		t.Errorf("Expected the auth feature, got %s", text)
+	}
+
+	text, isError = c.tool("traverse", map[string]any{"target": "Main", "edge_types": "CALLS", "depth": 2})
+	var paths []json.RawMessage
+	if isError || json.Unmarshal([]byte(text), &paths) != nil || len(paths) != 2 {
+		t.Errorf("Expected 2 paths from Main, got %s", text)
+	}
+
+	if text, isError = c.tool("globals", map[string]any{}); !isError || !strings.Contains(text, `missing required argument "target"`) {
+		t.Errorf("Expected a missing argument error, got %s", text)
+	}
+
+	// Depths and limits are bounded before they reach the provider
+	if text, isError = c.tool("traverse", map[string]any{"target": "Main", "depth": 11}); !isError || !strings.Contains(text, "depth 11 is above the maximum of 10") {
+		t.Errorf("Expected a depth error, got %s", text)
+	}
+	if text, isError = c.tool("search_similar", map[string]any{"query": "save", "limit": 5000}); !isError || !strings.Contains(text, "limit 5000 is above the maximum") {
+		t.Errorf("Expected a limit error, got %s", text)
+	}
+}
@@ -0,0 +1,339 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"graphdb/internal/embedding"
+	"graphdb/internal/query"
+)
+
+// StatusURI is the resource describing the state of the graph.
+const StatusURI = "graphdb://status"
+
+type searchArgs struct {
+	Query string `json:"query" desc:"What to look for, in natural language or code"`
+	Limit int    `json:"limit,omitempty" desc:"Maximum number of results (default 10, at most 1000)"`
+}
+
+// nodeArgs name the node a tool starts from. An ambiguous name fails with its
+// candidates, unless file or label single one out.
+type nodeArgs struct {
+	Target string `json:"target" desc:"Node ID, or the exact name of a function, class, global or feature"`
+	File   string `json:"file,omitempty" desc:"For an ambiguous name: the end of the file path of the node, e.g. auth/login.go"`
+	Label  string `json:"label,omitempty" desc:"For an ambiguous name: the node label, e.g. Function, Method or Class"`
+}
+
+type depthArgs struct {
+	nodeArgs
+	Depth int `json:"depth,omitempty" desc:"Number of hops to follow (default 1, at most 10)"`
+}
+
+type impactArgs struct {
+	depthArgs
+	GroupBy string `json:"group_by,omitempty" enum:"file,feature" desc:"Also group the callers by file or by RPG feature"`
+}
+
+type traverseArgs struct {
+	depthArgs
+	EdgeTypes string `json:"edge_types,omitempty" desc:"Comma-separated relationship types to follow, e.g. CALLS,USES_GLOBAL (default all)"`
+	Direction string `json:"direction,omitempty" enum:"outgoing,incoming,both" desc:"Direction to follow relationships in (default outgoing)"`
+}
+
+type seamsArgs struct {
+	Module string `json:"module,omitempty" desc:"Regular expression the whole file path must match (default .*)"`
+}
+
+type usageArgs struct {
+	Source string `json:"source" desc:"ID or exact name of the function to search in"`
+	Target string `json:"target" desc:"ID or exact name of the function or global it uses"`
+}
+
+type findArgs struct {
+	Label    string `json:"label,omitempty" desc:"Node label, e.g. Function (default any)"`
+	Property string `json:"property" desc:"Property to match, e.g. id, name or file"`
+	Value    string `json:"value" desc:"Value the property must equal"`
+}
+
+type resolveArgs struct {
+	Name string `json:"name" desc:"Node ID or exact name"`
+}
+
+type noArgs struct{}
+
+// NewGraphServer returns a server exposing the queries of provider as tools
+// and the graph status as a resource. embedder turns search text into
+// vectors; without one the search tools fail.
+func NewGraphServer(provider query.GraphProvider, embedder embedding.Embedder, version string) *Server {
+	s := NewServer("graphdb", version)
+	g := &graphTools{provider: provider, embedder: embedder}
+
+	addTool(s, "search_features", "Find RPG features (the intent layer) by meaning, with vector search.", g.searchFeatures)
+	addTool(s, "search_similar", "Find functions whose code is semantically similar to a description or snippet.", g.searchSimilar)
+	addTool(s, "neighbors", "List the functions and globals a function depends on, directly or within depth calls.", g.neighbors)
+	addTool(s, "callers", "List the names of the functions that call a function directly.", g.callers)
+	addTool(s, "impact", "List every function within depth calls upstream of a function, with its shortest call path, file, line and feature.", g.impact)
+	addTool(s, "globals", "List the global variables a function uses.", g.globals)
+	addTool(s, "seams", "Suggest testing seams: clean functions called from UI-contaminated code, riskiest first.", g.seams)
+	addTool(s, "fetch_source", "Return the source code of a function or other node.", g.fetchSource)
+	addTool(s, "locate_usage", "Find where in a function's source another function or global is used.", g.locateUsage)
+	addTool(s, "explore_domain", "Show a feature's place in the RPG hierarchy: parent, children, siblings and implementing functions.", g.exploreDomain)
+	addTool(s, "traverse", "Follow relationships from a node and return every path within depth hops.", g.traverse)
+	addTool(s, "find_node", "Find the first node whose property equals a value.", g.findNode)
+	addTool(s, "resolve_node", "List every node a name or ID may refer to, with label, file and line.", g.resolveNode)
+	addTool(s, "status", "Return the git commit the graph was built from.", g.status)
+
+	s.AddResource(Resource{
+		URI:         StatusURI,
+		Name:        "Graph status",
+		Description: "The git commit the graph was built from.",
+		MimeType:    "application/json",
+	}, func(ctx context.Context) (any, error) {
+		return g.status(ctx, noArgs{})
+	})
+	return s
+}
+
+// addTool registers a tool whose input schema is derived from its arguments
+// type A, decoding and checking the arguments before calling run.
+func addTool[A any](s *Server, name, description string, run func(ctx context.Context, args A) (any, error)) {
+	var zero A
+	schema := Schema(zero)
+	required, _ := schema["required"].([]string)
+
+	s.AddTool(Tool{Name: name, Description: description, InputSchema: schema}, func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var present map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &present); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		for _, field := range required {
+			if value, ok := present[field]; !ok || string(value) == `""` || string(value) == "null" {
+				return nil, fmt.Errorf("missing required argument %q", field)
+			}
+		}
+		var args A
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		return run(ctx, args)
+	})
+}
+
+type graphTools struct {
+	provider query.GraphProvider
+	embedder embedding.Embedder
+}
+
+// resolve returns the ID of the node args name; label applies unless args
+// give one.
+func resolve(p query.GraphProvider, args nodeArgs, label string) (string, error) {
+	if args.Label != "" {
+		label = args.Label
+	}
+	c, err := query.Resolve(p, args.Target, query.Qualifier{Label: label, File: args.File})
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+func (g *graphTools) embed(text string, purpose embedding.Purpose) ([]float32, error) {
+	if g.embedder == nil {
+		return nil, fmt.Errorf("search is unavailable: no embedder is configured")
+	}
+	vectors, err := g.embedder.EmbedBatch([]string{text}, purpose)
+	if err != nil {
+		return nil, fmt.Errorf("embedding failed: %w", err)
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("embedding failed: no vector returned")
+	}
+	return vectors[0], nil
+}
+
+func (g *graphTools) searchFeatures(ctx context.Context, args searchArgs) (any, error) {
+	limit, err := bounded("limit", args.Limit, 10, query.MaxLimit)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := g.embed(args.Query, embedding.PurposeQuery)
+	if err != nil {
+		return nil, err
+	}
+	return query.WithContext(ctx, g.provider).SearchFeatures(vector, limit)
+}
+
+func (g *graphTools) searchSimilar(ctx context.Context, args searchArgs) (any, error) {
+	limit, err := bounded("limit", args.Limit, 10, query.MaxLimit)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := g.embed(args.Query, embedding.PurposeCodeQuery)
+	if err != nil {
+		return nil, err
+	}
+	return query.WithContext(ctx, g.provider).SearchSimilarFunctions(vector, limit)
+}
+
+func (g *graphTools) neighbors(ctx context.Context, args depthArgs) (any, error) {
+	depth, err := bounded("depth", args.Depth, 1, query.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	p := query.WithContext(ctx, g.provider)
+	id, err := resolve(p, args.nodeArgs, "")
+	if err != nil {
+		return nil, err
+	}
+	return p.GetNeighbors(id, depth)
+}
+
+func (g *graphTools) callers(ctx context.Context, args nodeArgs) (any, error) {
+	p := query.WithContext(ctx, g.provider)
+	id, err := resolve(p, args, "")
+	if err != nil {
+		return nil, err
+	}
+	return p.GetCallers(id)
+}
+
+func (g *graphTools) impact(ctx context.Context, args impactArgs) (any, error) {
+	depth, err := bounded("depth", args.Depth, 1, query.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	p := query.WithContext(ctx, g.provider)
+	id, err := resolve(p, args.nodeArgs, "")
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.GetImpact(id, depth)
+	if err != nil {
+		return nil, err
+	}
+	if args.GroupBy != "" {
+		if err := result.GroupBy(args.GroupBy); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (g *graphTools) globals(ctx context.Context, args nodeArgs) (any, error) {
+	p := query.WithContext(ctx, g.provider)
+	id, err := resolve(p, args, "")
+	if err != nil {
+		return nil, err
+	}
+	return p.GetGlobals(id)
+}
+
+func (g *graphTools) seams(ctx context.Context, args seamsArgs) (any, error) {
+	if args.Module == "" {
+		args.Module = ".*"
+	}
+	return query.WithContext(ctx, g.provider).GetSeams(args.Module)
+}
+
+func (g *graphTools) fetchSource(ctx context.Context, args nodeArgs) (any, error) {
+	p := query.WithContext(ctx, g.provider)
+	id, err := resolve(p, args, "")
+	if err != nil {
+		return nil, err
+	}
+	return p.FetchSource(id)
+}
+
+func (g *graphTools) locateUsage(ctx context.Context, args usageArgs) (any, error) {
+	p := query.WithContext(ctx, g.provider)
+	source, err := resolve(p, nodeArgs{Target: args.Source}, "")
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolve(p, nodeArgs{Target: args.Target}, "")
+	if err != nil {
+		return nil, err
+	}
+	return p.LocateUsage(source, target)
+}
+
+func (g *graphTools) exploreDomain(ctx context.Context, args nodeArgs) (any, error) {
+	p := query.WithContext(ctx, g.provider)
+	id, err := resolve(p, args, "Feature")
+	if err != nil {
+		return nil, err
+	}
+	return p.ExploreDomain(id)
+}
+
+func (g *graphTools) traverse(ctx context.Context, args traverseArgs) (any, error) {
+	depth, err := bounded("depth", args.Depth, 1, query.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	p := query.WithContext(ctx, g.provider)
+	id, err := resolve(p, args.nodeArgs, "")
+	if err != nil {
+		return nil, err
+	}
+	direction := query.Outgoing
+	switch strings.ToLower(args.Direction) {
+	case "", "outgoing":
+	case "incoming":
+		direction = query.Incoming
+	case "both":
+		direction = query.Both
+	default:
+		return nil, fmt.Errorf("unknown direction %q: use outgoing, incoming or both", args.Direction)
+	}
+	return p.Traverse(id, args.EdgeTypes, direction, depth)
+}
+
+func (g *graphTools) findNode(ctx context.Context, args findArgs) (any, error) {
+	n, err := query.WithContext(ctx, g.provider).FindNode(args.Label, args.Property, args.Value)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, fmt.Errorf("no node with %s = %q", args.Property, args.Value)
+	}
+	return n, nil
+}
+
+func (g *graphTools) resolveNode(ctx context.Context, args resolveArgs) (any, error) {
+	p := query.WithContext(ctx, g.provider)
+	candidates, err := p.ResolveNode(args.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		suggestions, err := p.SuggestNodes(args.Name, 5)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &query.NotFoundError{Name: args.Name, Suggestions: suggestions}
+	}
+	return candidates, nil
+}
+
+func (g *graphTools) status(ctx context.Context, args noArgs) (any, error) {
+	commit, err := query.WithContext(ctx, g.provider).GetGraphState()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"commit": commit}, nil
+}
+
+// bounded returns n, or fallback if n is not positive, and fails if n is
+// above max.
+func bounded(name string, n, fallback, max int) (int, error) {
+	if n > max {
+		return 0, fmt.Errorf("%s %d is above the maximum of %d", name, n, max)
+	}
+	if n <= 0 {
+		return fallback, nil
+	}
+	return n, nil
+}
@@ -0,0 +1,194 @@
+// Package openai is a minimal client for OpenAI-compatible HTTP APIs, as
+// served by OpenAI itself and by self-hosted runtimes such as Ollama, vLLM and
+// LM Studio. Only the embeddings and chat completions endpoints are used.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is used when no base URL is configured.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// Client calls an OpenAI-compatible API.
+type Client struct {
+	BaseURL    string // e.g. "http://localhost:11434/v1"; DefaultBaseURL if empty
+	APIKey     string // Sent as a bearer token if set; local servers usually need none
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for baseURL with a generous request timeout, as
+// local models can be slow.
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type embeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embeddings returns one vector per input, in input order. dimensions is
+// only sent if positive, as not every server supports it.
+func (c *Client) Embeddings(ctx context.Context, model string, inputs []string, dimensions int) ([][]float32, error) {
+	var resp embeddingResponse
+	req := embeddingRequest{Model: model, Input: inputs, Dimensions: dimensions}
+	if err := c.post(ctx, "/embeddings", req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d", len(inputs), len(resp.Data))
+	}
+
+	sort.SliceStable(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+	vectors := make([][]float32, len(resp.Data))
+	for i, d := range resp.Data {
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Complete sends prompt as a single user message and returns the reply.
+func (c *Client) Complete(ctx context.Context, model, prompt string) (string, error) {
+	var resp chatResponse
+	req := chatRequest{
+		Model:    model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	}
+	if err := c.post(ctx, "/chat/completions", req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned from %s", c.baseURL())
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(c.BaseURL, "/")
+}
+
+// post sends body as JSON to the endpoint and decodes the response into out.
+func (c *Client) post(ctx context.Context, endpoint string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	url := c.baseURL() + endpoint
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    errorMessage(data),
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", url, err)
+	}
+	return nil
+}
+
+// APIError is a non-2xx response.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // From the Retry-After header of rate limited responses; 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(header))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// errorMessage extracts {"error": {"message": ...}} (or {"error": "..."}, as
+// some servers send), falling back to the raw body.
+func errorMessage(data []byte) string {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && len(body.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body.Error, &detail) == nil && detail.Message != "" {
+			return detail.Message
+		}
+		var message string
+		if json.Unmarshal(body.Error, &message) == nil && message != "" {
+			return message
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,110 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_Embeddings(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Method != http.MethodPost {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		// Out of order, as the spec allows
+		w.Write([]byte(`{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL+"/v1/", "secret")
+	vecs, err := c.Embeddings(context.Background(), "nomic-embed-text", []string{"a", "b"}, 0)
+	if err != nil {
+		t.Fatalf("Embeddings failed: %v", err)
+	}
+	if len(vecs) != 2 || vecs[0][0] != 1 || vecs[1][1] != 1 {
+		t.Errorf("Expected vectors in input order, got %v", vecs)
+	}
+	if got["model"] != "nomic-embed-text" || len(got["input"].([]interface{})) != 2 {
+		t.Errorf("Unexpected request body %v", got)
+	}
+	if _, ok := got["dimensions"]; ok {
+		t.Errorf("Expected dimensions to be omitted when unset")
+	}
+
+	c.Embeddings(context.Background(), "m", []string{"a", "b"}, 64)
+	if got["dimensions"] != float64(64) {
+		t.Errorf("Expected dimensions 64, got %v", got["dimensions"])
+	}
+}
+
+func TestClient_Complete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Expected no Authorization header without a key, got %q", auth)
+		}
+		var req chatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "llama3" || len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content != "hi" {
+			t.Errorf("Unexpected request %+v", req)
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "hello"}}]}`))
+	}))
+	defer server.Close()
+
+	reply, err := NewClient(server.URL, "").Complete(context.Background(), "llama3", "hi")
+	if err != nil || reply != "hello" {
+		t.Errorf("Expected hello, got %q (%v)", reply, err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusUnauthorized, `{"error": {"message": "invalid api key", "type": "auth"}}`, "invalid api key"},
+		{http.StatusNotFound, `{"error": "model \"x\" not found"}`, `model "x" not found`},
+		{http.StatusBadGateway, "upstream down", "upstream down"},
+		{http.StatusTooManyRequests, `{"error": {"message": "rate limited"}}`, "rate limited"},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "3")
+			}
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+
+		_, err := NewClient(server.URL, "").Complete(context.Background(), "x", "hi")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Message != tt.want {
+			t.Errorf("Expected API error %d %q, got %v", tt.status, tt.want, err)
+		}
+		if tt.status == http.StatusTooManyRequests && apiErr.RetryAfter != 3*time.Second {
+			t.Errorf("Expected Retry-After of 3s, got %s", apiErr.RetryAfter)
+		}
+		server.Close()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"index": 0, "embedding": [1]}]}`))
+	}))
+	defer server.Close()
+	if _, err := NewClient(server.URL, "").Embeddings(context.Background(), "m", []string{"a", "b"}, 0); err == nil {
+		t.Error("Expected error for a short embedding response")
+	}
+}
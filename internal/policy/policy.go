@@ -0,0 +1,248 @@
+// Package policy is the client-side call policy shared by the embedding and
+// LLM providers: exponential backoff on rate limiting and server errors,
+// request and token rate limits, bounded parallelism and per-attempt timeouts.
+package policy
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+
+	"graphdb/internal/openai"
+)
+
+// Policy governs the calls to one model. Its zero value retries nothing and
+// limits nothing; a nil *Policy calls straight through. The fields must not be
+// changed after the first call.
+type Policy struct {
+	MaxRetries        int           // Retries after the first attempt
+	InitialBackoff    time.Duration // Delay before the first retry, doubled for each further retry
+	MaxBackoff        time.Duration // Upper bound of the delay; 0 for none
+	Timeout           time.Duration // Per attempt; 0 for none
+	Concurrency       int           // Calls in flight at once; 0 for unbounded
+	RequestsPerMinute int           // 0 for unlimited
+	TokensPerMinute   int           // Estimated input tokens; 0 for unlimited
+
+	once     sync.Once
+	slots    chan struct{}
+	requests *bucket
+	tokens   *bucket
+
+	// Overridden in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Default returns the policy the providers use unless configured otherwise:
+// five retries from one second up to a minute, two minutes per attempt and
+// four calls in flight, without rate limits.
+func Default() *Policy {
+	return &Policy{
+		MaxRetries:     5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Timeout:        2 * time.Minute,
+		Concurrency:    4,
+	}
+}
+
+func (p *Policy) init() {
+	p.once.Do(func() {
+		if p.now == nil {
+			p.now = time.Now
+		}
+		if p.sleep == nil {
+			p.sleep = sleep
+		}
+		if p.Concurrency > 0 {
+			p.slots = make(chan struct{}, p.Concurrency)
+		}
+		if p.RequestsPerMinute > 0 {
+			p.requests = newBucket(p.RequestsPerMinute, p.now())
+		}
+		if p.TokensPerMinute > 0 {
+			p.tokens = newBucket(p.TokensPerMinute, p.now())
+		}
+	})
+}
+
+// Do calls fn, which is expected to make one request of about tokens input
+// tokens, under the policy. Each attempt gets its own context bounded by
+// Timeout; failed attempts are retried while Retryable and ctx is not done.
+func (p *Policy) Do(ctx context.Context, tokens int, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+	p.init()
+
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// Held through backoff, so a rate-limited model sees fewer callers
+		defer func() { <-p.slots }()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := p.wait(ctx, tokens); err != nil {
+			return err
+		}
+		err := p.attempt(ctx, fn)
+		if err == nil {
+			return nil
+		}
+		if attempt >= p.MaxRetries || ctx.Err() != nil || !Retryable(err) {
+			return err
+		}
+
+		delay := p.backoff(attempt, err)
+		log.Printf("Retrying in %s (attempt %d of %d): %v", delay.Round(time.Millisecond), attempt+2, p.MaxRetries+1, err)
+		if err := p.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Policy) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.Timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// wait blocks until the rate limits admit one request of tokens tokens.
+func (p *Policy) wait(ctx context.Context, tokens int) error {
+	var delay time.Duration
+	now := p.now()
+	if p.requests != nil {
+		delay = max(delay, p.requests.reserve(1, now))
+	}
+	if p.tokens != nil {
+		delay = max(delay, p.tokens.reserve(float64(tokens), now))
+	}
+	if delay <= 0 {
+		return nil
+	}
+	return p.sleep(ctx, delay)
+}
+
+// backoff is the delay before retry attempt+1: the exponential delay with
+// jitter in its upper half, or longer if the server asked for it.
+func (p *Policy) backoff(attempt int, err error) time.Duration {
+	delay := p.InitialBackoff
+	for i := 0; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay > 0 {
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+	return delay
+}
+
+// Retryable reports whether err is worth another attempt: rate limiting,
+// server errors, attempt timeouts and network failures.
+func Retryable(err error) bool {
+	if code := StatusCode(err); code != 0 {
+		return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// StatusCode returns the HTTP status of an API error from any provider, or 0.
+func StatusCode(err error) int {
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		return genaiErr.Code
+	}
+	var genaiPtr *genai.APIError
+	if errors.As(err, &genaiPtr) {
+		return genaiPtr.Code
+	}
+	var openaiErr *openai.APIError
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode
+	}
+	return 0
+}
+
+// EstimateTokens approximates the input tokens of texts at four characters
+// per token, which is close enough for rate limiting.
+func EstimateTokens(texts ...string) int {
+	n := 0
+	for _, text := range texts {
+		n += len(text)/4 + 1
+	}
+	return n
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// bucket is a token bucket refilled continuously at its per-minute capacity.
+// Reservations may drive it negative; the caller then waits until the debt
+// is repaid, so callers are served in order.
+type bucket struct {
+	mu       sync.Mutex
+	capacity float64
+	perSec   float64
+	level    float64
+	last     time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	return &bucket{
+		capacity: float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		level:    float64(perMinute),
+		last:     now,
+	}
+}
+
+// reserve takes n from the bucket and returns how long to wait before using
+// them. n is capped at the capacity, so an oversized request waits at most a
+// minute instead of forever.
+func (b *bucket) reserve(n float64, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.level = min(b.capacity, b.level+elapsed*b.perSec)
+		b.last = now
+	}
+	b.level -= min(n, b.capacity)
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / b.perSec * float64(time.Second))
+}
@@ -0,0 +1,198 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"graphdb/internal/openai"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// fakeClock records sleeps instead of sleeping, advancing its time.
+type fakeClock struct {
+	mu     sync.Mutex
+	t      time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) install(p *Policy) *Policy {
+	c.t = time.Unix(0, 0)
+	p.now = func() time.Time {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.t
+	}
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.sleeps = append(c.sleeps, d)
+		c.t = c.t.Add(d)
+		return ctx.Err()
+	}
+	return p
+}
+
+func TestPolicy_RetriesTransientErrors(t *testing.T) {
+	clock := &fakeClock{}
+	p := clock.install(&Policy{MaxRetries: 3, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second})
+
+	errs := []error{
+		genai.APIError{Code: 429, Message: "quota exceeded"},
+		&openai.APIError{StatusCode: 503, Message: "overloaded"},
+		&net.OpError{Op: "dial", Err: errors.New("connection refused")},
+	}
+	calls := 0
+	err := p.Do(context.Background(), 1, func(ctx context.Context) error {
+		calls++
+		if calls <= len(errs) {
+			return errs[calls-1]
+		}
+		return nil
+	})
+	if err != nil || calls != 4 {
+		t.Fatalf("Expected success on the 4th attempt, got %v after %d calls", err, calls)
+	}
+
+	// 1s, 2s, then capped at 3s, each with jitter in the upper half
+	bounds := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	for i, d := range clock.sleeps {
+		if d < bounds[i]/2 || d > bounds[i] {
+			t.Errorf("Backoff %d: %s not in [%s, %s]", i, d, bounds[i]/2, bounds[i])
+		}
+	}
+}
+
+func TestPolicy_GivesUp(t *testing.T) {
+	clock := &fakeClock{}
+	p := clock.install(&Policy{MaxRetries: 2, InitialBackoff: time.Millisecond})
+
+	calls := 0
+	quota := genai.APIError{Code: 429}
+	if err := p.Do(context.Background(), 1, func(ctx context.Context) error { calls++; return quota }); StatusCode(err) != 429 || calls != 3 {
+		t.Errorf("Expected the 429 after 3 attempts, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	bad := &openai.APIError{StatusCode: 400, Message: "bad request"}
+	if err := p.Do(context.Background(), 1, func(ctx context.Context) error { calls++; return bad }); err != bad || calls != 1 {
+		t.Errorf("Expected no retry of a 400, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.Do(ctx, 1, func(ctx context.Context) error { calls++; return quota })
+	if calls > 1 {
+		t.Errorf("Expected no retry after cancellation, got %d calls", calls)
+	}
+}
+
+func TestPolicy_RetryAfter(t *testing.T) {
+	clock := &fakeClock{}
+	p := clock.install(&Policy{MaxRetries: 1, InitialBackoff: time.Millisecond})
+
+	calls := 0
+	p.Do(context.Background(), 1, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &openai.APIError{StatusCode: 429, RetryAfter: 7 * time.Second}
+		}
+		return nil
+	})
+	if len(clock.sleeps) != 1 || clock.sleeps[0] != 7*time.Second {
+		t.Errorf("Expected to wait the 7s the server asked for, got %v", clock.sleeps)
+	}
+}
+
+func TestPolicy_TimeoutPerAttempt(t *testing.T) {
+	p := &Policy{MaxRetries: 1, Timeout: 10 * time.Millisecond}
+
+	calls := 0
+	err := p.Do(context.Background(), 1, func(ctx context.Context) error {
+		calls++
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected a deadline on the attempt context")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || calls != 2 {
+		t.Errorf("Expected both attempts to time out, got %v after %d calls", err, calls)
+	}
+}
+
+func TestPolicy_Concurrency(t *testing.T) {
+	p := &Policy{Concurrency: 2}
+
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Do(context.Background(), 1, func(ctx context.Context) error {
+				n := inFlight.Add(1)
+				for {
+					old := peak.Load()
+					if n <= old || peak.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				inFlight.Add(-1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 calls in flight, saw %d", peak.Load())
+	}
+}
+
+func TestPolicy_RateLimits(t *testing.T) {
+	clock := &fakeClock{}
+	p := clock.install(&Policy{RequestsPerMinute: 60})
+
+	// A full minute's worth of requests passes at once, then one per second
+	for i := 0; i < 62; i++ {
+		p.Do(context.Background(), 1, func(ctx context.Context) error { return nil })
+	}
+	if len(clock.sleeps) != 2 || clock.sleeps[0] != time.Second || clock.sleeps[1] != time.Second {
+		t.Errorf("Expected two 1s waits, got %v", clock.sleeps)
+	}
+
+	clock = &fakeClock{}
+	p = clock.install(&Policy{TokensPerMinute: 600})
+	p.Do(context.Background(), 500, func(ctx context.Context) error { return nil })
+	p.Do(context.Background(), 200, func(ctx context.Context) error { return nil })
+	// 100 tokens short at 10 tokens per second
+	if len(clock.sleeps) != 1 || clock.sleeps[0] != 10*time.Second {
+		t.Errorf("Expected a 10s wait for tokens, got %v", clock.sleeps)
+	}
+	// Larger than the budget: waits for a full bucket rather than forever
+	p.Do(context.Background(), 5000, func(ctx context.Context) error { return nil })
+	if len(clock.sleeps) != 2 || clock.sleeps[1] != time.Minute {
+		t.Errorf("Expected a 1m wait for an oversized request, got %v", clock.sleeps)
+	}
+}
+
+func TestPolicy_Nil(t *testing.T) {
+	var p *Policy
+	calls := 0
+	err := p.Do(context.Background(), 1, func(ctx context.Context) error { calls++; return genai.APIError{Code: 429} })
+	if err == nil || calls != 1 {
+		t.Errorf("Expected a nil policy to call once, got %v after %d", err, calls)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if n := EstimateTokens("", "12345678"); n != 4 {
+		t.Errorf("Expected 4 tokens, got %d", n)
+	}
+}
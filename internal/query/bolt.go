@@ -0,0 +1,460 @@
+package query
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"graphdb/internal/graph"
+)
+
+// Buckets of the embedded store. Index keys join their parts with sep, so
+// prefix scans over "<key>\x00" find every entry for a node.
+var (
+	nodesBucket      = []byte("nodes")      // id -> storedNode JSON
+	embeddingsBucket = []byte("embeddings") // id -> little-endian float32s
+	namesBucket      = []byte("names")      // name \x00 id
+	labelsBucket     = []byte("labels")     // label \x00 id
+	outBucket        = []byte("out")        // source \x00 type \x00 target
+	inBucket         = []byte("in")         // target \x00 type \x00 source
+
+	boltBuckets = [][]byte{nodesBucket, embeddingsBucket, namesBucket, labelsBucket, outBucket, inBucket}
+)
+
+const sep = "\x00"
+
+// boltBatchSize is how many emitted records are buffered per write transaction.
+const boltBatchSize = 1000
+
+type storedNode struct {
+	Label      string                 `json:"label"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// BoltStore is a single-file graph store backed by bbolt. It implements
+// storage.Emitter, storage.Deleter and storage.Updater, so ingest and
+// enrichment can write straight into it (including incremental deltas), and
+// GraphProvider, so query can read it back
+// without a server. Embeddings are stored alongside the nodes and searched
+// with a brute-force cosine scan.
+type BoltStore struct {
+	indexProvider
+
+	db *bolt.DB
+
+	mu      sync.Mutex
+	pending []func(tx *bolt.Tx) error // Buffered writes, applied in order
+}
+
+// OpenBoltStore opens (or creates) the store at path.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize store %s: %w", path, err)
+	}
+
+	s := &BoltStore{db: db}
+	s.indexProvider = indexProvider{idx: s}
+	return s, nil
+}
+
+// EmitNode buffers a node for writing. A node emitted more than once has its
+// properties merged into the stored copy.
+func (s *BoltStore) EmitNode(node *graph.Node) error {
+	return s.enqueue(func(tx *bolt.Tx) error {
+		if err := putNode(tx, node); err != nil {
+			return fmt.Errorf("failed to store node %s: %w", node.ID, err)
+		}
+		return nil
+	})
+}
+
+// EmitEdge buffers an edge for writing. Duplicate edges are stored once.
+func (s *BoltStore) EmitEdge(edge *graph.Edge) error {
+	return s.enqueue(func(tx *bolt.Tx) error {
+		if err := putEdge(tx, edge); err != nil {
+			return fmt.Errorf("failed to store edge %s->%s: %w", edge.SourceID, edge.TargetID, err)
+		}
+		return nil
+	})
+}
+
+// UpdateNode buffers a partial update: its properties are merged into the
+// stored node, if there is one.
+func (s *BoltStore) UpdateNode(node *graph.Node) error {
+	return s.enqueue(func(tx *bolt.Tx) error {
+		if tx.Bucket(nodesBucket).Get([]byte(node.ID)) == nil {
+			return nil
+		}
+		if err := putNode(tx, node); err != nil {
+			return fmt.Errorf("failed to update node %s: %w", node.ID, err)
+		}
+		return nil
+	})
+}
+
+// DeleteNode buffers the removal of a node and all of its relationships.
+func (s *BoltStore) DeleteNode(id string) error {
+	return s.enqueue(func(tx *bolt.Tx) error {
+		if err := deleteNode(tx, id); err != nil {
+			return fmt.Errorf("failed to delete node %s: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// DeleteEdge buffers the removal of an edge.
+func (s *BoltStore) DeleteEdge(edge *graph.Edge) error {
+	return s.enqueue(func(tx *bolt.Tx) error {
+		if err := deleteEdge(tx, edge.SourceID, edgeTypeOrDefault(edge.Type), edge.TargetID); err != nil {
+			return fmt.Errorf("failed to delete edge %s->%s: %w", edge.SourceID, edge.TargetID, err)
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) enqueue(op func(tx *bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, op)
+	if len(s.pending) >= boltBatchSize {
+		return s.flushLocked()
+	}
+	return nil
+}
+
+// Flush writes any buffered records.
+func (s *BoltStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+// Close flushes buffered records and closes the database file.
+func (s *BoltStore) Close() error {
+	flushErr := s.Flush()
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// flushLocked writes the buffered records in one transaction. If a record
+// fails, the rest are written one at a time so only the failing ones are
+// dropped; if the database itself fails, the records stay buffered for the
+// next flush.
+func (s *BoltStore) flushLocked() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	var opErr error
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, op := range s.pending {
+			if opErr = op(tx); opErr != nil {
+				return opErr
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		s.pending = s.pending[:0]
+		return nil
+	}
+	if opErr == nil {
+		return fmt.Errorf("failed to write %d buffered records: %w", len(s.pending), err)
+	}
+
+	var errs []error
+	for i, op := range s.pending {
+		opErr = nil
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			opErr = op(tx)
+			return opErr
+		})
+		if err != nil && opErr == nil {
+			s.pending = append(s.pending[:0], s.pending[i:]...)
+			return errors.Join(append(errs, fmt.Errorf("failed to write %d buffered records: %w", len(s.pending), err))...)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.pending = s.pending[:0]
+	return errors.Join(errs...)
+}
+
+func putNode(tx *bolt.Tx, n *graph.Node) error {
+	nodes := tx.Bucket(nodesBucket)
+	id := []byte(n.ID)
+
+	stored := storedNode{Label: n.Label, Properties: make(map[string]interface{})}
+	var oldName string
+	if raw := nodes.Get(id); raw != nil {
+		if err := json.Unmarshal(raw, &stored); err != nil {
+			return err
+		}
+		if stored.Properties == nil {
+			stored.Properties = make(map[string]interface{})
+		}
+		oldName, _ = stored.Properties["name"].(string)
+		if n.Label != "" && n.Label != stored.Label {
+			if err := tx.Bucket(labelsBucket).Delete([]byte(stored.Label + sep + n.ID)); err != nil {
+				return err
+			}
+			stored.Label = n.Label
+		}
+	}
+
+	for k, v := range n.Properties {
+		if k == "embedding" {
+			if vec, ok := embeddingValues(v); ok {
+				if err := tx.Bucket(embeddingsBucket).Put(id, encodeEmbedding(vec)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		stored.Properties[k] = v
+	}
+
+	raw, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := nodes.Put(id, raw); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(labelsBucket).Put([]byte(stored.Label+sep+n.ID), nil); err != nil {
+		return err
+	}
+	names := tx.Bucket(namesBucket)
+	name, _ := stored.Properties["name"].(string)
+	if oldName != "" && oldName != name {
+		if err := names.Delete([]byte(oldName + sep + n.ID)); err != nil {
+			return err
+		}
+	}
+	if name != "" {
+		return names.Put([]byte(name+sep+n.ID), nil)
+	}
+	return nil
+}
+
+func putEdge(tx *bolt.Tx, e *graph.Edge) error {
+	edgeType := edgeTypeOrDefault(e.Type)
+	if err := tx.Bucket(outBucket).Put([]byte(e.SourceID+sep+edgeType+sep+e.TargetID), nil); err != nil {
+		return err
+	}
+	return tx.Bucket(inBucket).Put([]byte(e.TargetID+sep+edgeType+sep+e.SourceID), nil)
+}
+
+func deleteNode(tx *bolt.Tx, id string) error {
+	n := getNode(tx, id)
+	if n == nil {
+		return nil
+	}
+	for _, parts := range scanPrefix(tx.Bucket(outBucket), id) {
+		if err := deleteEdge(tx, id, parts[0], parts[1]); err != nil {
+			return err
+		}
+	}
+	for _, parts := range scanPrefix(tx.Bucket(inBucket), id) {
+		if err := deleteEdge(tx, parts[1], parts[0], id); err != nil {
+			return err
+		}
+	}
+	if name, ok := n.Properties["name"].(string); ok {
+		if err := tx.Bucket(namesBucket).Delete([]byte(name + sep + id)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(labelsBucket).Delete([]byte(n.Label + sep + id)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(embeddingsBucket).Delete([]byte(id)); err != nil {
+		return err
+	}
+	return tx.Bucket(nodesBucket).Delete([]byte(id))
+}
+
+func deleteEdge(tx *bolt.Tx, source, edgeType, target string) error {
+	if err := tx.Bucket(outBucket).Delete([]byte(source + sep + edgeType + sep + target)); err != nil {
+		return err
+	}
+	return tx.Bucket(inBucket).Delete([]byte(target + sep + edgeType + sep + source))
+}
+
+func edgeTypeOrDefault(edgeType string) string {
+	if edgeType == "" {
+		return "RELATED_TO"
+	}
+	return edgeType
+}
+
+// readable flushes buffered writes so reads see everything emitted so far,
+// and checks that the database can still be read.
+func (s *BoltStore) readable() error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	if err := s.db.View(func(tx *bolt.Tx) error { return nil }); err != nil {
+		return fmt.Errorf("failed to read store: %w", err)
+	}
+	return nil
+}
+
+// view runs a read transaction. The lookups that use it have no error to
+// return: readable has already reported a store that cannot be read, and
+// none of their callbacks fail.
+func (s *BoltStore) view(fn func(tx *bolt.Tx) error) {
+	s.db.View(fn)
+}
+
+func (s *BoltStore) node(id string) *graph.Node {
+	var n *graph.Node
+	s.view(func(tx *bolt.Tx) error {
+		n = getNode(tx, id)
+		return nil
+	})
+	return n
+}
+
+func (s *BoltStore) nodesByName(name string) []*graph.Node {
+	return s.indexedNodes(namesBucket, name)
+}
+
+func (s *BoltStore) nodesByLabel(label string) []*graph.Node {
+	if label != "" {
+		return s.indexedNodes(labelsBucket, label)
+	}
+	var nodes []*graph.Node
+	s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(nodesBucket).ForEach(func(k, _ []byte) error {
+			if n := getNode(tx, string(k)); n != nil {
+				nodes = append(nodes, n)
+			}
+			return nil
+		})
+	})
+	return nodes
+}
+
+// indexedNodes returns the nodes listed under key in an index bucket.
+func (s *BoltStore) indexedNodes(bucket []byte, key string) []*graph.Node {
+	var nodes []*graph.Node
+	s.view(func(tx *bolt.Tx) error {
+		for _, parts := range scanPrefix(tx.Bucket(bucket), key) {
+			if n := getNode(tx, parts[0]); n != nil {
+				nodes = append(nodes, n)
+			}
+		}
+		return nil
+	})
+	return nodes
+}
+
+func (s *BoltStore) outEdges(id string) []*graph.Edge {
+	var edges []*graph.Edge
+	s.view(func(tx *bolt.Tx) error {
+		for _, parts := range scanPrefix(tx.Bucket(outBucket), id) {
+			edges = append(edges, &graph.Edge{SourceID: id, Type: parts[0], TargetID: parts[1]})
+		}
+		return nil
+	})
+	return edges
+}
+
+func (s *BoltStore) inEdges(id string) []*graph.Edge {
+	var edges []*graph.Edge
+	s.view(func(tx *bolt.Tx) error {
+		for _, parts := range scanPrefix(tx.Bucket(inBucket), id) {
+			edges = append(edges, &graph.Edge{SourceID: parts[1], Type: parts[0], TargetID: id})
+		}
+		return nil
+	})
+	return edges
+}
+
+// scanPrefix returns the remaining key parts of every entry under key.
+func scanPrefix(b *bolt.Bucket, key string) [][]string {
+	var results [][]string
+	prefix := []byte(key + sep)
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		results = append(results, strings.Split(string(k[len(prefix):]), sep))
+	}
+	return results
+}
+
+func getNode(tx *bolt.Tx, id string) *graph.Node {
+	raw := tx.Bucket(nodesBucket).Get([]byte(id))
+	if raw == nil {
+		return nil
+	}
+	var stored storedNode
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return nil
+	}
+	if stored.Properties == nil {
+		stored.Properties = make(map[string]interface{})
+	}
+	if vec := tx.Bucket(embeddingsBucket).Get([]byte(id)); vec != nil {
+		stored.Properties["embedding"] = decodeEmbedding(vec)
+	}
+	return &graph.Node{ID: id, Label: stored.Label, Properties: stored.Properties}
+}
+
+// embeddingValues accepts embeddings as produced by the embedder ([]float32)
+// or decoded from JSON ([]interface{} of float64).
+func embeddingValues(v interface{}) ([]float32, bool) {
+	switch vec := v.(type) {
+	case []float32:
+		return vec, true
+	case []float64:
+		out := make([]float32, len(vec))
+		for i, f := range vec {
+			out[i] = float32(f)
+		}
+		return out, true
+	case []interface{}:
+		return toFloat32s(vec), true
+	}
+	return nil, false
+}
+
+func encodeEmbedding(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, f := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeEmbedding(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vec
+}
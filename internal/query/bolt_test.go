@@ -0,0 +1,97 @@
+package query
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"graphdb/internal/graph"
+	"graphdb/internal/storage"
+)
+
+var (
+	_ GraphProvider   = (*BoltStore)(nil)
+	_ storage.Emitter = (*BoltStore)(nil)
+	_ storage.Deleter = (*BoltStore)(nil)
+	_ storage.Updater = (*BoltStore)(nil)
+)
+
+func TestBoltStore_PersistsAndReindexes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.db")
+
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("OpenBoltStore failed: %v", err)
+	}
+	store.EmitNode(&graph.Node{ID: "a.go:Run", Label: "Function", Properties: map[string]interface{}{
+		"name":      "Run",
+		"embedding": []float32{0.25, -1.5},
+	}})
+	store.EmitNode(&graph.Node{ID: "a.go:Stop", Label: "Function", Properties: map[string]interface{}{"name": "Stop"}})
+	store.EmitEdge(&graph.Edge{SourceID: "a.go:Run", TargetID: "a.go:Stop", Type: "CALLS"})
+	store.EmitEdge(&graph.Edge{SourceID: "a.go:Run", TargetID: "a.go:Stop", Type: "CALLS"})
+	// A later partial update renames the node and moves it to another label
+	store.EmitNode(&graph.Node{ID: "a.go:Stop", Label: "Method", Properties: map[string]interface{}{"name": "Halt"}})
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer store.Close()
+
+	run, _ := store.FindNode("Function", "name", "Run")
+	if run == nil {
+		t.Fatal("Expected Run to be found by name after reopening")
+	}
+	vec, ok := run.Properties["embedding"].([]float32)
+	if !ok || len(vec) != 2 || vec[0] != 0.25 || vec[1] != -1.5 {
+		t.Errorf("Expected embedding to round-trip, got %v", run.Properties["embedding"])
+	}
+
+	if n, _ := store.FindNode("", "name", "Stop"); n != nil {
+		t.Errorf("Expected old name to be unindexed, got %+v", n)
+	}
+	if n, _ := store.FindNode("Function", "id", "a.go:Stop"); n != nil {
+		t.Errorf("Expected label filter to exclude relabelled node, got %+v", n)
+	}
+	if n, _ := store.FindNode("Method", "name", "Halt"); n == nil {
+		t.Error("Expected relabelled node to be found under its new label and name")
+	}
+	if functions := store.nodesByLabel("Function"); len(functions) != 1 {
+		t.Errorf("Expected 1 Function, got %d", len(functions))
+	}
+
+	paths, _ := store.Traverse("Halt", "CALLS", Incoming, 3)
+	if len(paths) != 1 || paths[0].Nodes[1].ID != "a.go:Run" {
+		t.Errorf("Expected a single deduplicated CALLS path from Run, got %+v", paths)
+	}
+}
+
+func TestBoltStore_ReportsFailures(t *testing.T) {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "graph.db"))
+	if err != nil {
+		t.Fatalf("OpenBoltStore failed: %v", err)
+	}
+	store.EmitNode(&graph.Node{ID: "a.go:Run", Label: "Function", Properties: map[string]interface{}{"name": "Run"}})
+	// NaN cannot be encoded, so this record fails on its own
+	store.EmitNode(&graph.Node{ID: "a.go:Bad", Label: "Function", Properties: map[string]interface{}{"score": math.NaN()}})
+	store.EmitNode(&graph.Node{ID: "a.go:Stop", Label: "Function", Properties: map[string]interface{}{"name": "Stop"}})
+
+	if err := store.Flush(); err == nil || !strings.Contains(err.Error(), "a.go:Bad") {
+		t.Errorf("Expected the bad node to fail the flush, got %v", err)
+	}
+	for _, name := range []string{"Run", "Stop"} {
+		if n, err := store.FindNode("Function", "name", name); n == nil || err != nil {
+			t.Errorf("Expected %s to be written despite the bad node, got %v %v", name, n, err)
+		}
+	}
+
+	store.Close()
+	if _, err := store.FindNode("Function", "name", "Run"); err == nil {
+		t.Error("Expected queries on a closed store to fail")
+	}
+}
@@ -0,0 +1,99 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+
+	"graphdb/internal/graph"
+)
+
+// Keys to group impact results by.
+const (
+	GroupByFile    = "file"
+	GroupByFeature = "feature"
+)
+
+// GroupBy fills Groups with the callers sharing a file or a feature, ordered
+// by their nearest caller.
+func (r *ImpactResult) GroupBy(by string) error {
+	var property string
+	switch by {
+	case GroupByFile:
+		property = "file"
+	case GroupByFeature:
+		property = "feature_id"
+	default:
+		return fmt.Errorf("unknown impact grouping %q: use %s or %s", by, GroupByFile, GroupByFeature)
+	}
+
+	r.Groups = make([]*ImpactGroup, 0)
+	groups := make(map[string]*ImpactGroup)
+	for _, caller := range r.Callers {
+		key, _ := caller.Properties[property].(string)
+		group, ok := groups[key]
+		if !ok {
+			group = &ImpactGroup{Key: key}
+			groups[key] = group
+			r.Groups = append(r.Groups, group)
+		}
+		group.Callers = append(group.Callers, caller.ID)
+	}
+	return nil
+}
+
+// impactNode returns the identity of a node on an impact path: its ID, name
+// as the label, and file and line if known.
+func impactNode(id, name, file string, line int) *graph.Node {
+	if name == "" {
+		name = id
+	}
+	props := make(map[string]any)
+	if file != "" {
+		props["file"] = file
+	}
+	if line > 0 {
+		props["line"] = line
+	}
+	return &graph.Node{ID: id, Label: name, Properties: props}
+}
+
+// impactTarget returns the node all paths lead to, or a node labelled with
+// the requested name if there are none or several.
+func impactTarget(nodeID string, paths []*graph.Path) *graph.Node {
+	var target *graph.Node
+	for _, path := range paths {
+		end := path.Nodes[len(path.Nodes)-1]
+		if target != nil && target.ID != end.ID {
+			return &graph.Node{Label: nodeID}
+		}
+		target = end
+	}
+	if target == nil {
+		return &graph.Node{Label: nodeID}
+	}
+	return target
+}
+
+// sortImpact orders callers and their paths nearest first, then by ID.
+func sortImpact(callers []*graph.Node, paths []*graph.Path) {
+	order := make([]int, len(callers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ca, cb := callers[order[a]], callers[order[b]]
+		da, db := intProp(ca.Properties["depth"]), intProp(cb.Properties["depth"])
+		if da != db {
+			return da < db
+		}
+		return ca.ID < cb.ID
+	})
+
+	sortedCallers := make([]*graph.Node, len(callers))
+	sortedPaths := make([]*graph.Path, len(paths))
+	for i, j := range order {
+		sortedCallers[i], sortedPaths[i] = callers[j], paths[j]
+	}
+	copy(callers, sortedCallers)
+	copy(paths, sortedPaths)
+}
@@ -0,0 +1,52 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+
+	"graphdb/internal/graph"
+)
+
+func TestImpactResult_GroupBy(t *testing.T) {
+	caller := func(id, file, feature string) *graph.Node {
+		n := impactNode(id, "", file, 0)
+		if feature != "" {
+			n.Properties["feature_id"] = feature
+		}
+		return n
+	}
+	result := &ImpactResult{Callers: []*graph.Node{
+		caller("b.go:Save", "b.go", "feat-store"),
+		caller("a.go:Handle", "a.go", "feat-api"),
+		caller("b.go:Load", "b.go", ""),
+		caller("c.go:Main", "", "feat-api"),
+	}}
+
+	if err := result.GroupBy(GroupByFile); err != nil {
+		t.Fatal(err)
+	}
+	want := []*ImpactGroup{
+		{Key: "b.go", Callers: []string{"b.go:Save", "b.go:Load"}},
+		{Key: "a.go", Callers: []string{"a.go:Handle"}},
+		{Key: "", Callers: []string{"c.go:Main"}},
+	}
+	if !reflect.DeepEqual(result.Groups, want) {
+		t.Errorf("Unexpected file groups: %+v", result.Groups)
+	}
+
+	if err := result.GroupBy(GroupByFeature); err != nil {
+		t.Fatal(err)
+	}
+	want = []*ImpactGroup{
+		{Key: "feat-store", Callers: []string{"b.go:Save"}},
+		{Key: "feat-api", Callers: []string{"a.go:Handle", "c.go:Main"}},
+		{Key: "", Callers: []string{"b.go:Load"}},
+	}
+	if !reflect.DeepEqual(result.Groups, want) {
+		t.Errorf("Unexpected feature groups: %+v", result.Groups)
+	}
+
+	if err := result.GroupBy("package"); err == nil {
+		t.Error("Expected an error for an unknown grouping")
+	}
+}
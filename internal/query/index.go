@@ -0,0 +1,700 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"graphdb/internal/graph"
+	"graphdb/internal/tools/snippet"
+)
+
+// graphIndex is the read side of an embedded graph: keyed lookups that the
+// in-memory and on-disk backends both answer without a query engine.
+type graphIndex interface {
+	// node returns the node with the given ID, or nil.
+	node(id string) *graph.Node
+	// nodesByName returns every node whose name property equals name.
+	nodesByName(name string) []*graph.Node
+	// nodesByLabel returns every node with the label (all nodes if empty).
+	nodesByLabel(label string) []*graph.Node
+	outEdges(id string) []*graph.Edge
+	inEdges(id string) []*graph.Edge
+	// readable makes everything written so far visible to the lookups above,
+	// or reports why the index cannot be read. Every query calls it first.
+	readable() error
+}
+
+// indexProvider implements the GraphProvider queries on top of a graphIndex,
+// mirroring the result shapes of the Cypher queries in Neo4jProvider.
+type indexProvider struct {
+	idx graphIndex
+}
+
+// lookup returns the node with the given ID, or all nodes with that name.
+func (q indexProvider) lookup(idOrName string) []*graph.Node {
+	if n := q.idx.node(idOrName); n != nil {
+		return []*graph.Node{n}
+	}
+	return q.idx.nodesByName(idOrName)
+}
+
+// FindNode finds the first node with the given label (any label if empty)
+// whose property equals value.
+func (q indexProvider) FindNode(label string, property string, value string) (*graph.Node, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	switch property {
+	case "id":
+		if n := q.idx.node(value); n != nil && (label == "" || n.Label == label) {
+			return n, nil
+		}
+		return nil, nil
+	case "name":
+		for _, n := range q.idx.nodesByName(value) {
+			if label == "" || n.Label == label {
+				return n, nil
+			}
+		}
+		return nil, nil
+	}
+
+	for _, n := range q.idx.nodesByLabel(label) {
+		if v, ok := n.Properties[property]; ok && fmt.Sprint(v) == value {
+			return n, nil
+		}
+	}
+	return nil, nil
+}
+
+// ResolveNode returns the node with the given ID and every node with that
+// name, ordered by label, file and line.
+func (q indexProvider) ResolveNode(nameOrID string) ([]*Candidate, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	candidates := make([]*Candidate, 0)
+	seen := make(map[string]bool)
+	add := func(n *graph.Node) {
+		if seen[n.ID] {
+			return
+		}
+		seen[n.ID] = true
+		name, _ := n.Properties["name"].(string)
+		file, _ := n.Properties["file"].(string)
+		candidates = append(candidates, &Candidate{ID: n.ID, Name: name, Label: n.Label, File: file, Line: nodeLine(n)})
+	}
+
+	if n := q.idx.node(nameOrID); n != nil {
+		add(n)
+	}
+	for _, n := range q.idx.nodesByName(nameOrID) {
+		add(n)
+	}
+	sortCandidates(candidates)
+	return candidates, nil
+}
+
+// SuggestNodes returns up to limit node names close to name.
+func (q indexProvider) SuggestNodes(name string, limit int) ([]string, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, n := range q.idx.nodesByLabel("") {
+		if nodeName, ok := n.Properties["name"].(string); ok {
+			names = append(names, nodeName)
+		}
+	}
+	return closestNames(name, names, limit), nil
+}
+
+// Traverse returns every path of 1..depth relationships from the start node,
+// matching Cypher's variable-length semantics (no relationship repeats in a path).
+func (q indexProvider) Traverse(startNodeID string, relationship string, direction Direction, depth int) ([]*graph.Path, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	types := edgeTypeSet(relationship)
+
+	var paths []*graph.Path
+	var walk func(current string, nodes []*graph.Node, edges []*graph.Edge, used map[graph.Edge]bool)
+	walk = func(current string, nodes []*graph.Node, edges []*graph.Edge, used map[graph.Edge]bool) {
+		if len(edges) >= depth {
+			return
+		}
+		for _, step := range q.steps(current, direction, types) {
+			if used[*step.edge] {
+				continue
+			}
+			next := q.idx.node(step.next)
+			if next == nil {
+				continue
+			}
+			pathNodes := append(append([]*graph.Node{}, nodes...), next)
+			pathEdges := append(append([]*graph.Edge{}, edges...), step.edge)
+			paths = append(paths, &graph.Path{Nodes: pathNodes, Edges: pathEdges})
+
+			used[*step.edge] = true
+			walk(step.next, pathNodes, pathEdges, used)
+			delete(used, *step.edge)
+		}
+	}
+
+	for _, start := range q.lookup(startNodeID) {
+		walk(start.ID, []*graph.Node{start}, nil, make(map[graph.Edge]bool))
+	}
+	return paths, nil
+}
+
+type traversalStep struct {
+	edge *graph.Edge
+	next string
+}
+
+func (q indexProvider) steps(id string, direction Direction, types map[string]bool) []traversalStep {
+	var steps []traversalStep
+	if direction == Outgoing || direction == Both {
+		for _, e := range q.idx.outEdges(id) {
+			if types == nil || types[e.Type] {
+				steps = append(steps, traversalStep{edge: e, next: e.TargetID})
+			}
+		}
+	}
+	if direction == Incoming || direction == Both {
+		for _, e := range q.idx.inEdges(id) {
+			if types == nil || types[e.Type] {
+				steps = append(steps, traversalStep{edge: e, next: e.SourceID})
+			}
+		}
+	}
+	return steps
+}
+
+// SearchSimilarFunctions ranks Function nodes by cosine similarity to embedding.
+func (q indexProvider) SearchSimilarFunctions(embedding []float32, limit int) ([]*FeatureResult, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	return q.vectorSearch("Function", embedding, limit), nil
+}
+
+// SearchFeatures ranks Feature nodes by cosine similarity to embedding.
+func (q indexProvider) SearchFeatures(embedding []float32, limit int) ([]*FeatureResult, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	return q.vectorSearch("Feature", embedding, limit), nil
+}
+
+func (q indexProvider) vectorSearch(label string, embedding []float32, limit int) []*FeatureResult {
+	results := make([]*FeatureResult, 0)
+	for _, n := range q.idx.nodesByLabel(label) {
+		vec, ok := n.Properties["embedding"].([]float32)
+		if !ok || len(vec) != len(embedding) {
+			continue
+		}
+		// Same normalization as Neo4j's cosine vector index: (1 + cos) / 2
+		score := (1 + cosine(embedding, vec)) / 2
+		results = append(results, &FeatureResult{Node: n, Score: float32(score)})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// GetNeighbors retrieves the dependencies (functions, globals) of a node.
+func (q indexProvider) GetNeighbors(nodeID string, depth int) (*NeighborResult, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	matches := q.lookup(nodeID)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("node not found: %s", nodeID)
+	}
+	n := matches[0]
+
+	// Expand scope if n is a Class (include its methods)
+	scope := []*graph.Node{}
+	for _, e := range q.idx.outEdges(n.ID) {
+		if e.Type == "HAS_METHOD" {
+			if m := q.idx.node(e.TargetID); m != nil {
+				scope = append(scope, m)
+			}
+		}
+	}
+	scope = append(scope, n)
+
+	var globals, funcs []Dependency
+	seenDeps := make(map[string]bool)
+	add := func(list *[]Dependency, dep Dependency) {
+		key := dep.Name + "|" + dep.Type + "|" + strings.Join(dep.Via, ",")
+		if seenDeps[key] {
+			return
+		}
+		seenDeps[key] = true
+		*list = append(*list, dep)
+	}
+
+	for _, s := range scope {
+		// 1. Direct & Transitive Globals
+		var visit func(current *graph.Node, via []string, hops int, onPath map[string]bool)
+		visit = func(current *graph.Node, via []string, hops int, onPath map[string]bool) {
+			out := q.idx.outEdges(current.ID)
+			for _, e := range out {
+				if e.Type != "USES_GLOBAL" {
+					continue
+				}
+				if g := q.idx.node(e.TargetID); g != nil && g.Label == "Global" {
+					add(&globals, Dependency{Name: nodeName(g), Type: "Global", Via: append([]string{}, via...)})
+				}
+			}
+			if hops >= depth {
+				return
+			}
+			for _, e := range out {
+				if e.Type != "CALLS" || onPath[e.TargetID] {
+					continue
+				}
+				callee := q.idx.node(e.TargetID)
+				if callee == nil {
+					continue
+				}
+				onPath[callee.ID] = true
+				visit(callee, append(via, nodeName(callee)), hops+1, onPath)
+				delete(onPath, callee.ID)
+			}
+		}
+		visit(s, []string{}, 0, map[string]bool{s.ID: true})
+
+		// 2. Direct Function Calls / Uses
+		for _, e := range q.idx.outEdges(s.ID) {
+			if e.Type != "CALLS" && e.Type != "USES" {
+				continue
+			}
+			if d := q.idx.node(e.TargetID); d != nil {
+				add(&funcs, Dependency{Name: nodeName(d), Type: d.Label})
+			}
+		}
+	}
+
+	return &NeighborResult{
+		Node:         &graph.Node{Label: nodeID},
+		Dependencies: append(globals, funcs...),
+	}, nil
+}
+
+// GetCallers retrieves the names of the callers of a node.
+func (q indexProvider) GetCallers(nodeID string) ([]string, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	callers := []string{}
+	seen := make(map[string]bool)
+	for _, n := range q.lookup(nodeID) {
+		for _, e := range q.idx.inEdges(n.ID) {
+			if e.Type != "CALLS" {
+				continue
+			}
+			caller := q.idx.node(e.SourceID)
+			if caller == nil {
+				continue
+			}
+			name := nodeName(caller)
+			if !seen[name] {
+				seen[name] = true
+				callers = append(callers, name)
+			}
+		}
+	}
+	return callers, nil
+}
+
+// GetImpact analyzes the impact of changing a node (reverse dependencies),
+// returning every caller within depth calls with its shortest path of CALLS.
+func (q indexProvider) GetImpact(nodeID string, depth int) (*ImpactResult, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	// Breadth-first from every target, so each caller is first reached along
+	// one of its shortest paths
+	visited := make(map[string]bool)
+	var frontier []string
+	for _, target := range q.lookup(nodeID) {
+		visited[target.ID] = true
+		frontier = append(frontier, target.ID)
+	}
+
+	toward := make(map[string]*graph.Edge) // Caller ID -> its next call on the way to the target
+	var reached []string
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, id := range frontier {
+			for _, e := range q.idx.inEdges(id) {
+				if e.Type != "CALLS" || visited[e.SourceID] || q.idx.node(e.SourceID) == nil {
+					continue
+				}
+				visited[e.SourceID] = true
+				toward[e.SourceID] = e
+				next = append(next, e.SourceID)
+			}
+		}
+		reached = append(reached, next...)
+		frontier = next
+	}
+
+	callers := make([]*graph.Node, 0, len(reached))
+	paths := make([]*graph.Path, 0, len(reached))
+	for _, id := range reached {
+		path := &graph.Path{}
+		for current := id; ; {
+			path.Nodes = append(path.Nodes, q.identity(q.idx.node(current)))
+			e, ok := toward[current]
+			if !ok {
+				break
+			}
+			path.Edges = append(path.Edges, e)
+			current = e.TargetID
+		}
+
+		n := q.idx.node(id)
+		caller := q.identity(n)
+		contaminated, _ := n.Properties["ui_contaminated"].(bool)
+		caller.Properties["ui_contaminated"] = contaminated
+		if risk, ok := n.Properties["risk_score"]; ok {
+			caller.Properties["risk_score"] = floatProp(risk)
+		}
+		caller.Properties["depth"] = len(path.Edges)
+		if feature := q.featureOf(n); feature != "" {
+			caller.Properties["feature_id"] = feature
+		}
+		callers = append(callers, caller)
+		paths = append(paths, path)
+	}
+	sortImpact(callers, paths)
+
+	return &ImpactResult{
+		Target:  impactTarget(nodeID, paths),
+		Callers: callers,
+		Paths:   paths,
+	}, nil
+}
+
+// identity returns the ID, name, file and line of a node for impact results.
+func (q indexProvider) identity(n *graph.Node) *graph.Node {
+	file, _ := n.Properties["file"].(string)
+	return impactNode(n.ID, nodeName(n), file, nodeLine(n))
+}
+
+// featureOf returns the ID of the Feature a function implements, from its
+// IMPLEMENTS edge or the feature_id property enrich-features records.
+func (q indexProvider) featureOf(n *graph.Node) string {
+	for _, e := range q.idx.outEdges(n.ID) {
+		if e.Type != "IMPLEMENTS" {
+			continue
+		}
+		if feature := q.idx.node(e.TargetID); feature != nil && feature.Label == "Feature" {
+			return feature.ID
+		}
+	}
+	feature, _ := n.Properties["feature_id"].(string)
+	return feature
+}
+
+// GetGlobals identifies global variable usage.
+func (q indexProvider) GetGlobals(nodeID string) (*GlobalUsageResult, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	globals := make([]*graph.Node, 0)
+	for _, n := range q.lookup(nodeID) {
+		for _, e := range q.idx.outEdges(n.ID) {
+			if e.Type != "USES_GLOBAL" {
+				continue
+			}
+			g := q.idx.node(e.TargetID)
+			if g == nil || g.Label != "Global" {
+				continue
+			}
+			file, _ := g.Properties["file"].(string)
+			globals = append(globals, &graph.Node{
+				Label: nodeName(g),
+				Properties: map[string]any{
+					"file": file,
+				},
+			})
+		}
+	}
+
+	return &GlobalUsageResult{
+		Target:  &graph.Node{Label: nodeID},
+		Globals: globals,
+	}, nil
+}
+
+// GetSeams suggests architectural seams (boundaries) where contamination stops.
+func (q indexProvider) GetSeams(modulePattern string) ([]*SeamResult, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	// Cypher's =~ must match the whole string
+	re, err := regexp.Compile("^(?:" + modulePattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid module pattern: %w", err)
+	}
+
+	seams := make([]*SeamResult, 0)
+	seen := make(map[SeamResult]bool)
+	for _, f := range q.idx.nodesByLabel("Function") {
+		if contaminated, ok := f.Properties["ui_contaminated"].(bool); !ok || contaminated {
+			continue
+		}
+		if !q.hasContaminatedCaller(f.ID) {
+			continue
+		}
+		for _, e := range q.idx.outEdges(f.ID) {
+			if e.Type != "DEFINED_IN" {
+				continue
+			}
+			file := q.idx.node(e.TargetID)
+			if file == nil || file.Label != "File" {
+				continue
+			}
+			path, _ := file.Properties["file"].(string)
+			if !re.MatchString(path) {
+				continue
+			}
+			seam := SeamResult{Seam: nodeName(f), File: path, Risk: floatProp(f.Properties["risk_score"])}
+			if !seen[seam] {
+				seen[seam] = true
+				seams = append(seams, &seam)
+			}
+		}
+	}
+
+	sort.SliceStable(seams, func(i, j int) bool {
+		return seams[i].Risk > seams[j].Risk
+	})
+	if len(seams) > 20 {
+		seams = seams[:20]
+	}
+	return seams, nil
+}
+
+func (q indexProvider) hasContaminatedCaller(id string) bool {
+	for _, e := range q.idx.inEdges(id) {
+		if e.Type != "CALLS" {
+			continue
+		}
+		caller := q.idx.node(e.SourceID)
+		if caller == nil || caller.Label != "Function" {
+			continue
+		}
+		if contaminated, _ := caller.Properties["ui_contaminated"].(bool); contaminated {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchSource retrieves the source code for a node.
+func (q indexProvider) FetchSource(nodeID string) (string, error) {
+	if err := q.idx.readable(); err != nil {
+		return "", err
+	}
+	matches := q.lookup(nodeID)
+	if len(matches) == 0 {
+		return "", fmt.Errorf("node not found: %s", nodeID)
+	}
+	n := matches[0]
+
+	file, _ := n.Properties["file"].(string)
+	if file == "" {
+		return "", fmt.Errorf("node %s has no file associated", nodeID)
+	}
+
+	start := intProp(n.Properties["start_line"])
+	end := intProp(n.Properties["end_line"])
+	if start == 0 && end == 0 {
+		// Default to first 50 lines if no line info
+		start = 1
+		end = 50
+	}
+
+	return snippet.SliceFile(file, start, end)
+}
+
+// LocateUsage identifies where a dependency is used within a function.
+func (q indexProvider) LocateUsage(sourceID string, targetID string) (any, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	sources := q.lookup(sourceID)
+	targets := q.lookup(targetID)
+	if len(sources) == 0 || len(targets) == 0 {
+		return nil, fmt.Errorf("source or target node not found")
+	}
+	source := sources[0]
+
+	file, _ := source.Properties["file"].(string)
+	start := intProp(source.Properties["start_line"])
+	end := intProp(source.Properties["end_line"])
+	if file == "" || start == 0 || end == 0 {
+		return nil, fmt.Errorf("source node %s missing location info", sourceID)
+	}
+
+	content, err := snippet.SliceFile(file, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return snippet.FindPatternInScope(content, nodeName(targets[0]), 0, start)
+}
+
+// ExploreDomain returns the hierarchy context for a Feature node:
+// the feature itself, its parent, children, siblings, and implementing functions.
+func (q indexProvider) ExploreDomain(featureID string) (*DomainExplorationResult, error) {
+	if err := q.idx.readable(); err != nil {
+		return nil, err
+	}
+	f := q.idx.node(featureID)
+	if f == nil || f.Label != "Feature" {
+		return nil, fmt.Errorf("feature not found: %s", featureID)
+	}
+
+	result := &DomainExplorationResult{Feature: f}
+
+	incoming := q.idx.inEdges(f.ID)
+	if parents := q.related(incoming, "PARENT_OF", "Feature", false); len(parents) > 0 {
+		result.Parent = parents[0]
+	}
+
+	result.Children = q.related(q.idx.outEdges(f.ID), "PARENT_OF", "Feature", true)
+	if result.Parent != nil {
+		for _, sibling := range q.related(q.idx.outEdges(result.Parent.ID), "PARENT_OF", "Feature", true) {
+			if sibling.ID != f.ID {
+				result.Siblings = append(result.Siblings, sibling)
+			}
+		}
+	}
+	result.Functions = q.related(incoming, "IMPLEMENTS", "Function", false)
+
+	return result, nil
+}
+
+// related collects the nodes on the other end of edges of the given type.
+func (q indexProvider) related(edges []*graph.Edge, edgeType, label string, outgoing bool) []*graph.Node {
+	var nodes []*graph.Node
+	for _, e := range edges {
+		if e.Type != edgeType {
+			continue
+		}
+		id := e.SourceID
+		if outgoing {
+			id = e.TargetID
+		}
+		if n := q.idx.node(id); n != nil && n.Label == label {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// GetGraphState returns the commit recorded on a GraphState node, if any.
+func (q indexProvider) GetGraphState() (string, error) {
+	if err := q.idx.readable(); err != nil {
+		return "", err
+	}
+	for _, n := range q.idx.nodesByLabel("GraphState") {
+		commit, _ := n.Properties["commit"].(string)
+		return commit, nil
+	}
+	return "", nil
+}
+
+func edgeTypeSet(relationship string) map[string]bool {
+	if relationship == "" {
+		return nil
+	}
+	types := make(map[string]bool)
+	for _, t := range strings.Split(relationship, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types[t] = true
+		}
+	}
+	return types
+}
+
+func nodeName(n *graph.Node) string {
+	if name, ok := n.Properties["name"].(string); ok {
+		return name
+	}
+	return n.ID
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func toFloat32s(values []interface{}) []float32 {
+	out := make([]float32, 0, len(values))
+	for _, v := range values {
+		if f, ok := v.(float64); ok {
+			out = append(out, float32(f))
+		}
+	}
+	return out
+}
+
+// nodeLine returns the line a node starts on, or 0 if unknown.
+func nodeLine(n *graph.Node) int {
+	if line := intProp(n.Properties["start_line"]); line > 0 {
+		return line
+	}
+	return intProp(n.Properties["line"])
+}
+
+func intProp(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case uint32:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+func floatProp(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
@@ -0,0 +1,337 @@
+package query
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"graphdb/internal/graph"
+)
+
+// testGraph writes a source file and returns it with JSONL records for:
+//
+//	Main -CALLS-> Handler -CALLS-> Save -USES_GLOBAL-> Config
+//	Service -HAS_METHOD-> Handler
+//	domain -PARENT_OF-> [auth, billing]; Handler -IMPLEMENTS-> auth
+func testGraph(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app.ts")
+	source := "function Save() {\n  return Config.value;\n}\n"
+	if err := os.WriteFile(src, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	lines := []string{
+		`{"id": "` + src + `", "type": "File", "file": "` + src + `", "name": "` + src + `"}`,
+		`{"id": "app:Main", "type": "Function", "name": "Main", "ui_contaminated": true, "embedding": [1, 0]}`,
+		`{"id": "app:Handler", "type": "Function", "name": "Handler", "ui_contaminated": false, "risk_score": 0.5, "embedding": [0.6, 0.8]}`,
+		`{"id": "app:Save", "type": "Function", "name": "Save", "file": "` + src + `", "start_line": 1, "end_line": 3, "embedding": [0, 1]}`,
+		`{"id": "app:Service", "type": "Class", "name": "Service"}`,
+		`{"id": "app:Config", "type": "Global", "name": "Config", "file": "` + src + `"}`,
+		`{"id": "domain-app", "type": "Feature", "name": "App", "embedding": [1, 0]}`,
+		`{"id": "feat-auth", "type": "Feature", "name": "Auth", "embedding": [0, 1]}`,
+		`{"id": "feat-billing", "type": "Feature", "name": "Billing", "embedding": [0.7, 0.7]}`,
+		`{"id": "state", "type": "GraphState", "commit": "abc123"}`,
+		`{"source": "app:Main", "target": "app:Handler", "type": "CALLS"}`,
+		`{"source": "app:Handler", "target": "app:Save", "type": "CALLS"}`,
+		`{"source": "app:Save", "target": "app:Config", "type": "USES_GLOBAL"}`,
+		`{"source": "app:Service", "target": "app:Handler", "type": "HAS_METHOD"}`,
+		`{"source": "app:Handler", "target": "` + src + `", "type": "DEFINED_IN"}`,
+		`{"source": "domain-app", "target": "feat-auth", "type": "PARENT_OF"}`,
+		`{"source": "domain-app", "target": "feat-billing", "type": "PARENT_OF"}`,
+		`{"source": "app:Handler", "target": "feat-auth", "type": "IMPLEMENTS"}`,
+		// Duplicate edge and a partial node update are merged
+		`{"source": "app:Main", "target": "app:Handler", "type": "CALLS"}`,
+		`{"id": "app:Main", "type": "Function", "file": "` + src + `"}`,
+	}
+
+	return src, strings.Join(lines, "\n")
+}
+
+// embeddedProviders loads the test graph, followed by any extra records, into
+// every embedded backend.
+func embeddedProviders(t *testing.T, extra ...string) (string, map[string]GraphProvider) {
+	t.Helper()
+	src, records := testGraph(t)
+	lines := append(strings.Split(records, "\n"), extra...)
+
+	p := newJSONLProvider()
+	if err := p.Load(strings.NewReader(strings.Join(lines, "\n"))); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "graph.db"))
+	if err != nil {
+		t.Fatalf("OpenBoltStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	// Replay the raw records so the store does its own merging and deduplication
+	for _, line := range lines {
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("Bad record %s: %v", line, err)
+		}
+		deleted, _ := rec["deleted"].(bool)
+		if src, ok := rec["source"].(string); ok {
+			edge := &graph.Edge{SourceID: src, TargetID: rec["target"].(string), Type: rec["type"].(string)}
+			if deleted {
+				store.DeleteEdge(edge)
+			} else {
+				store.EmitEdge(edge)
+			}
+			continue
+		}
+		id, _ := rec["id"].(string)
+		if deleted {
+			store.DeleteNode(id)
+			continue
+		}
+		partial, _ := rec["partial"].(bool)
+		label, _ := rec["type"].(string)
+		delete(rec, "id")
+		delete(rec, "type")
+		delete(rec, "partial")
+		if partial {
+			store.UpdateNode(&graph.Node{ID: id, Label: label, Properties: rec})
+			continue
+		}
+		store.EmitNode(&graph.Node{ID: id, Label: label, Properties: rec})
+	}
+
+	return src, map[string]GraphProvider{"jsonl": p, "bolt": store}
+}
+
+func TestEmbeddedProviders_Traverse(t *testing.T) {
+	_, providers := embeddedProviders(t)
+	for name, p := range providers {
+		t.Run(name, func(t *testing.T) {
+			paths, err := p.Traverse("Main", "CALLS", Outgoing, 2)
+			if err != nil {
+				t.Fatalf("Traverse failed: %v", err)
+			}
+			// (Main)->(Handler), (Main)->(Handler)->(Save)
+			if len(paths) != 2 {
+				t.Fatalf("Expected 2 paths, got %d", len(paths))
+			}
+			last := paths[1]
+			if len(last.Nodes) != 3 || last.Nodes[2].ID != "app:Save" {
+				t.Errorf("Expected second path to end at app:Save, got %+v", last.Nodes)
+			}
+
+			paths, _ = p.Traverse("Save", "CALLS", Incoming, 5)
+			if len(paths) != 2 {
+				t.Errorf("Expected 2 incoming paths to Save, got %d", len(paths))
+			}
+
+			paths, _ = p.Traverse("Handler", "", Both, 1)
+			if len(paths) != 5 {
+				t.Errorf("Expected 5 paths in both directions, got %d", len(paths))
+			}
+		})
+	}
+}
+
+func TestEmbeddedProviders_Search(t *testing.T) {
+	_, providers := embeddedProviders(t)
+	for name, p := range providers {
+		t.Run(name, func(t *testing.T) {
+			results, err := p.SearchSimilarFunctions([]float32{0, 1}, 2)
+			if err != nil {
+				t.Fatalf("SearchSimilarFunctions failed: %v", err)
+			}
+			if len(results) != 2 || results[0].Node.ID != "app:Save" || results[1].Node.ID != "app:Handler" {
+				t.Errorf("Unexpected similarity ranking: %+v", results)
+			}
+			if results[0].Score < 0.999 {
+				t.Errorf("Expected identical vectors to score 1, got %f", results[0].Score)
+			}
+
+			features, _ := p.SearchFeatures([]float32{0, 1}, 10)
+			if len(features) != 3 || features[0].Node.ID != "feat-auth" {
+				t.Errorf("Unexpected feature ranking: %+v", features)
+			}
+		})
+	}
+}
+
+func TestEmbeddedProviders_Dependencies(t *testing.T) {
+	src, providers := embeddedProviders(t)
+	for name, p := range providers {
+		t.Run(name, func(t *testing.T) {
+			// Class scope expands to its methods
+			neighbors, err := p.GetNeighbors("Service", 1)
+			if err != nil {
+				t.Fatalf("GetNeighbors failed: %v", err)
+			}
+			var foundGlobal, foundCall bool
+			for _, d := range neighbors.Dependencies {
+				if d.Type == "Global" && d.Name == "Config" && strings.Join(d.Via, ",") == "Save" {
+					foundGlobal = true
+				}
+				if d.Type == "Function" && d.Name == "Save" {
+					foundCall = true
+				}
+			}
+			if !foundGlobal || !foundCall {
+				t.Errorf("Expected transitive global via Save and direct call to Save, got %+v", neighbors.Dependencies)
+			}
+
+			if _, err := p.GetNeighbors("Missing", 1); err == nil {
+				t.Error("Expected error for missing node")
+			}
+
+			callers, _ := p.GetCallers("Handler")
+			if len(callers) != 1 || callers[0] != "Main" {
+				t.Errorf("Expected callers [Main], got %v", callers)
+			}
+
+			impact, _ := p.GetImpact("Save", 2)
+			if len(impact.Callers) != 2 || len(impact.Paths) != 2 {
+				t.Fatalf("Expected 2 upstream callers with paths, got %+v", impact)
+			}
+			handler, main := impact.Callers[0], impact.Callers[1]
+			if handler.ID != "app:Handler" || handler.Properties["depth"] != 1 || handler.Properties["feature_id"] != "feat-auth" {
+				t.Errorf("Expected Handler first at depth 1 in feat-auth, got %+v", handler)
+			}
+			if main.ID != "app:Main" || main.Label != "Main" || main.Properties["depth"] != 2 || main.Properties["file"] != src {
+				t.Errorf("Expected Main at depth 2 with its file, got %+v", main)
+			}
+			var hops []string
+			for _, n := range impact.Paths[1].Nodes {
+				hops = append(hops, n.ID)
+			}
+			if strings.Join(hops, ",") != "app:Main,app:Handler,app:Save" || len(impact.Paths[1].Edges) != 2 {
+				t.Errorf("Expected the path Main -> Handler -> Save, got %v", hops)
+			}
+			if impact.Target.ID != "app:Save" || impact.Target.Properties["line"] != 1 {
+				t.Errorf("Expected the target's identity, got %+v", impact.Target)
+			}
+
+			globals, _ := p.GetGlobals("Save")
+			if len(globals.Globals) != 1 || globals.Globals[0].Label != "Config" {
+				t.Errorf("Expected global Config, got %+v", globals.Globals)
+			}
+
+			seams, err := p.GetSeams(".*app.ts")
+			if err != nil {
+				t.Fatalf("GetSeams failed: %v", err)
+			}
+			if len(seams) != 1 || seams[0].Seam != "Handler" || seams[0].Risk != 0.5 {
+				t.Errorf("Expected Handler seam, got %+v", seams)
+			}
+		})
+	}
+}
+
+func TestEmbeddedProviders_SourceAndDomain(t *testing.T) {
+	src, providers := embeddedProviders(t)
+	for name, p := range providers {
+		t.Run(name, func(t *testing.T) {
+			source, err := p.FetchSource("Save")
+			if err != nil {
+				t.Fatalf("FetchSource failed: %v", err)
+			}
+			if !strings.Contains(source, "return Config.value;") {
+				t.Errorf("Unexpected source: %q", source)
+			}
+
+			if _, err := p.LocateUsage("Save", "Config"); err != nil {
+				t.Errorf("LocateUsage failed: %v", err)
+			}
+
+			domain, err := p.ExploreDomain("feat-auth")
+			if err != nil {
+				t.Fatalf("ExploreDomain failed: %v", err)
+			}
+			if domain.Parent == nil || domain.Parent.ID != "domain-app" {
+				t.Errorf("Expected parent domain-app, got %+v", domain.Parent)
+			}
+			if len(domain.Siblings) != 1 || domain.Siblings[0].ID != "feat-billing" {
+				t.Errorf("Expected sibling feat-billing, got %+v", domain.Siblings)
+			}
+			if len(domain.Functions) != 1 || domain.Functions[0].ID != "app:Handler" {
+				t.Errorf("Expected implementing function Handler, got %+v", domain.Functions)
+			}
+
+			commit, _ := p.GetGraphState()
+			if commit != "abc123" {
+				t.Errorf("Expected commit abc123, got %q", commit)
+			}
+
+			n, _ := p.FindNode("Function", "file", src)
+			if n == nil || n.ID != "app:Main" {
+				t.Errorf("Expected merged properties to make Main findable by file, got %+v", n)
+			}
+		})
+	}
+}
+
+func TestEmbeddedProviders_Tombstones(t *testing.T) {
+	_, providers := embeddedProviders(t,
+		`{"id": "app:Save", "deleted": true}`,
+		`{"source": "app:Main", "target": "app:Handler", "type": "CALLS", "deleted": true}`,
+		`{"id": "app:Missing", "deleted": true}`,
+	)
+	for name, p := range providers {
+		t.Run(name, func(t *testing.T) {
+			if n, _ := p.FindNode("", "name", "Save"); n != nil {
+				t.Errorf("Expected Save to be deleted, got %+v", n)
+			}
+			// Deleting Save removes its relationships too
+			if paths, _ := p.Traverse("Config", "", Both, 1); len(paths) != 0 {
+				t.Errorf("Expected Config to be disconnected, got %d paths", len(paths))
+			}
+			if callers, _ := p.GetCallers("Handler"); len(callers) != 0 {
+				t.Errorf("Expected the Main -> Handler call to be deleted, got %v", callers)
+			}
+			if paths, _ := p.Traverse("Service", "HAS_METHOD", Outgoing, 1); len(paths) != 1 {
+				t.Errorf("Expected unrelated edges to survive, got %d paths", len(paths))
+			}
+			if results, _ := p.SearchSimilarFunctions([]float32{0, 1}, 10); len(results) != 2 {
+				t.Errorf("Expected 2 remaining embedded functions, got %d", len(results))
+			}
+		})
+	}
+}
+
+func TestEmbeddedProviders_PartialUpdates(t *testing.T) {
+	_, providers := embeddedProviders(t,
+		`{"id": "app:Save", "type": "Function", "partial": true, "atomic_features": ["persist settings"], "feature_id": "feat-billing"}`,
+		`{"id": "app:Missing", "type": "Function", "partial": true, "atomic_features": ["nothing"]}`,
+	)
+	for name, p := range providers {
+		t.Run(name, func(t *testing.T) {
+			n, _ := p.FindNode("Function", "feature_id", "feat-billing")
+			if n == nil || n.ID != "app:Save" || n.Properties["start_line"] == nil {
+				t.Fatalf("Expected the update merged into Save, got %+v", n)
+			}
+			if features, _ := n.Properties["atomic_features"].([]interface{}); len(features) != 1 {
+				t.Errorf("Expected the atomic features on Save, got %v", n.Properties["atomic_features"])
+			}
+			if _, ok := n.Properties["partial"]; ok {
+				t.Errorf("Expected the partial marker to be dropped, got %v", n.Properties)
+			}
+			if n, _ := p.FindNode("", "id", "app:Missing"); n != nil {
+				t.Errorf("Expected an update not to create a node, got %+v", n)
+			}
+		})
+	}
+}
+
+func TestJSONLProvider_PartialUpdateBeforeNode(t *testing.T) {
+	// rpg.jsonl may be loaded before graph.jsonl
+	p := newJSONLProvider()
+	p.Load(strings.NewReader(`{"id": "app:Save", "type": "Function", "partial": true, "feature_id": "feat-billing"}`))
+	p.Load(strings.NewReader(`{"id": "app:Save", "type": "Function", "name": "Save"}`))
+
+	n := p.node("app:Save")
+	if n == nil || n.Properties["feature_id"] != "feat-billing" || n.Properties["name"] != "Save" {
+		t.Errorf("Expected the held update applied on load, got %+v", n)
+	}
+}
+
+var _ GraphProvider = (*JSONLProvider)(nil)
@@ -1,6 +1,10 @@
 package query
 
-import "graphdb/internal/graph"
+import (
+	"context"
+
+	"graphdb/internal/graph"
+)
 
 // Direction represents the direction of a relationship traversal.
 type Direction int
@@ -11,6 +15,14 @@ const (
 	Both
 )
 
+// MaxDepth and MaxLimit are the largest depth and result limit the servers
+// accept from their clients. Depths become variable-length patterns, whose
+// cost grows quickly with every hop.
+const (
+	MaxDepth = 10
+	MaxLimit = 1000
+)
+
 // FeatureResult represents a result from a hybrid search (vector + structure).
 type FeatureResult struct {
 	Node  *graph.Node `json:"node"`
@@ -30,11 +42,21 @@ type Dependency struct {
 	Via  []string `json:"via,omitempty"` // Trace path (for transitive globals)
 }
 
-// ImpactResult represents the upstream dependencies (callers).
+// ImpactResult represents the upstream dependencies (callers), nearest first.
+// Paths[i] is the shortest chain of CALLS from Callers[i] to the target. Each
+// caller is labelled with its name and carries its file, line, depth (the
+// length of its path) and the feature_id of the Feature it implements.
 type ImpactResult struct {
-	Target  *graph.Node   `json:"target"`
-	Callers []*graph.Node `json:"callers"`
-	Paths   []*graph.Path `json:"paths"`
+	Target  *graph.Node    `json:"target"`
+	Callers []*graph.Node  `json:"callers"`
+	Paths   []*graph.Path  `json:"paths"`
+	Groups  []*ImpactGroup `json:"groups,omitempty"`
+}
+
+// ImpactGroup collects the impacted callers that share a file or a feature.
+type ImpactGroup struct {
+	Key     string   `json:"key"`     // File path or Feature ID; empty for callers without one
+	Callers []string `json:"callers"` // Caller IDs, nearest first
 }
 
 // GlobalUsageResult represents global variable usage.
@@ -67,6 +89,8 @@ type GraphProvider interface {
 
 	// Core Operations
 	FindNode(label string, property string, value string) (*graph.Node, error)
+	ResolveNode(nameOrID string) ([]*Candidate, error)     // The node with this ID and all nodes with this name
+	SuggestNodes(name string, limit int) ([]string, error) // Names close to one that matches nothing
 	Traverse(startNodeID string, relationship string, direction Direction, depth int) ([]*graph.Path, error)
 
 	// High-Level Features
@@ -82,3 +106,19 @@ type GraphProvider interface {
 	ExploreDomain(featureID string) (*DomainExplorationResult, error)
 	GetGraphState() (string, error)
 }
+
+// ContextBinder is implemented by providers whose queries can be cancelled.
+// WithContext returns a view of the provider that runs its queries under ctx
+// and shares its connections; close the original, not the view.
+type ContextBinder interface {
+	WithContext(ctx context.Context) GraphProvider
+}
+
+// WithContext binds p to ctx if it supports cancellation, and returns p
+// unchanged otherwise.
+func WithContext(ctx context.Context, p GraphProvider) GraphProvider {
+	if binder, ok := p.(ContextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+	return p
+}
@@ -0,0 +1,261 @@
+package query
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"graphdb/internal/graph"
+)
+
+// JSONLProvider implements GraphProvider over graph/RPG JSONL files held in
+// memory. It needs no database, which makes it suitable for CI and local use;
+// vector search is a brute-force cosine scan.
+type JSONLProvider struct {
+	indexProvider
+
+	nodes   map[string]*graph.Node
+	order   []string                 // Node IDs in load order, for deterministic results
+	byName  map[string][]*graph.Node // name -> nodes
+	out     map[string][]*graph.Edge // source ID -> edges
+	in      map[string][]*graph.Edge // target ID -> edges
+	seen    map[graph.Edge]bool
+	updates map[string]map[string]interface{} // Partial updates of nodes not loaded yet
+}
+
+// NewJSONLProvider loads the given JSONL files (as written by JSONLEmitter)
+// into memory. Nodes that appear more than once have their properties merged.
+func NewJSONLProvider(paths ...string) (*JSONLProvider, error) {
+	p := newJSONLProvider()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		err = p.Load(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
+		}
+	}
+	return p, nil
+}
+
+func newJSONLProvider() *JSONLProvider {
+	p := &JSONLProvider{
+		nodes:   make(map[string]*graph.Node),
+		byName:  make(map[string][]*graph.Node),
+		out:     make(map[string][]*graph.Edge),
+		in:      make(map[string][]*graph.Edge),
+		seen:    make(map[graph.Edge]bool),
+		updates: make(map[string]map[string]interface{}),
+	}
+	p.indexProvider = indexProvider{idx: p}
+	return p
+}
+
+// Load reads JSONL records from r. Edges are records with a "source" field;
+// everything else with an "id" is a node. Records marked "deleted" (written by
+// incremental ingest) remove the node, with its relationships, or the edge.
+// Records marked "partial" (written by enrichment) merge their properties into
+// a node that is loaded from any of the files, before or after them.
+func (p *JSONLProvider) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	// Embeddings make for long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+		var flat map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &flat); err != nil {
+			continue
+		}
+
+		deleted, _ := flat["deleted"].(bool)
+
+		if _, ok := flat["source"]; ok {
+			src, _ := flat["source"].(string)
+			tgt, _ := flat["target"].(string)
+			typ, _ := flat["type"].(string)
+			if src == "" || tgt == "" {
+				continue
+			}
+			if deleted {
+				p.removeEdge(graph.Edge{SourceID: src, TargetID: tgt, Type: typ})
+			} else {
+				p.addEdge(graph.Edge{SourceID: src, TargetID: tgt, Type: typ})
+			}
+			continue
+		}
+
+		id, _ := flat["id"].(string)
+		if id == "" {
+			continue
+		}
+		if deleted {
+			p.removeNode(id)
+			continue
+		}
+		partial, _ := flat["partial"].(bool)
+		label, _ := flat["type"].(string)
+		delete(flat, "id")
+		delete(flat, "type")
+		delete(flat, "partial")
+		if emb, ok := flat["embedding"].([]interface{}); ok {
+			flat["embedding"] = toFloat32s(emb)
+		}
+		if partial {
+			p.updateNode(id, flat)
+			continue
+		}
+		p.addNode(id, label, flat)
+	}
+	return scanner.Err()
+}
+
+// updateNode merges props into node id, or holds them until it is loaded.
+func (p *JSONLProvider) updateNode(id string, props map[string]interface{}) {
+	if _, ok := p.nodes[id]; ok {
+		p.addNode(id, "", props)
+		return
+	}
+	if p.updates[id] == nil {
+		p.updates[id] = make(map[string]interface{})
+	}
+	for k, v := range props {
+		p.updates[id][k] = v
+	}
+}
+
+func (p *JSONLProvider) addNode(id, label string, props map[string]interface{}) {
+	if update, ok := p.updates[id]; ok {
+		delete(p.updates, id)
+		for k, v := range update {
+			props[k] = v
+		}
+	}
+	if existing, ok := p.nodes[id]; ok {
+		oldName, _ := existing.Properties["name"].(string)
+		for k, v := range props {
+			existing.Properties[k] = v
+		}
+		if name, _ := existing.Properties["name"].(string); name != oldName {
+			p.byName[oldName] = withoutNode(p.byName[oldName], existing)
+			if name != "" {
+				p.byName[name] = append(p.byName[name], existing)
+			}
+		}
+		if existing.Label == "" {
+			existing.Label = label
+		}
+		return
+	}
+
+	node := &graph.Node{ID: id, Label: label, Properties: props}
+	p.nodes[id] = node
+	p.order = append(p.order, id)
+	if name, ok := props["name"].(string); ok {
+		p.byName[name] = append(p.byName[name], node)
+	}
+}
+
+func (p *JSONLProvider) addEdge(e graph.Edge) {
+	if e.Type == "" {
+		e.Type = "RELATED_TO"
+	}
+	if p.seen[e] {
+		return
+	}
+	p.seen[e] = true
+	edge := &e
+	p.out[e.SourceID] = append(p.out[e.SourceID], edge)
+	p.in[e.TargetID] = append(p.in[e.TargetID], edge)
+}
+
+func (p *JSONLProvider) removeNode(id string) {
+	node, ok := p.nodes[id]
+	if !ok {
+		return
+	}
+	for _, e := range append(append([]*graph.Edge{}, p.out[id]...), p.in[id]...) {
+		p.removeEdge(*e)
+	}
+	delete(p.nodes, id)
+	for i, existing := range p.order {
+		if existing == id {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+	if name, ok := node.Properties["name"].(string); ok {
+		p.byName[name] = withoutNode(p.byName[name], node)
+	}
+}
+
+func (p *JSONLProvider) removeEdge(e graph.Edge) {
+	if e.Type == "" {
+		e.Type = "RELATED_TO"
+	}
+	if !p.seen[e] {
+		return
+	}
+	delete(p.seen, e)
+	p.out[e.SourceID] = withoutEdge(p.out[e.SourceID], e)
+	p.in[e.TargetID] = withoutEdge(p.in[e.TargetID], e)
+}
+
+func withoutNode(nodes []*graph.Node, node *graph.Node) []*graph.Node {
+	kept := nodes[:0]
+	for _, n := range nodes {
+		if n != node {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+func withoutEdge(edges []*graph.Edge, e graph.Edge) []*graph.Edge {
+	kept := edges[:0]
+	for _, existing := range edges {
+		if *existing != e {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
+
+func (p *JSONLProvider) node(id string) *graph.Node {
+	return p.nodes[id]
+}
+
+func (p *JSONLProvider) nodesByName(name string) []*graph.Node {
+	return p.byName[name]
+}
+
+func (p *JSONLProvider) nodesByLabel(label string) []*graph.Node {
+	var nodes []*graph.Node
+	for _, id := range p.order {
+		if n := p.nodes[id]; label == "" || n.Label == label {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+func (p *JSONLProvider) outEdges(id string) []*graph.Edge {
+	return p.out[id]
+}
+
+func (p *JSONLProvider) inEdges(id string) []*graph.Edge {
+	return p.in[id]
+}
+
+func (p *JSONLProvider) readable() error {
+	return nil
+}
+
+// Close is a no-op; everything lives in memory.
+func (p *JSONLProvider) Close() error {
+	return nil
+}
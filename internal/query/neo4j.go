@@ -6,7 +6,9 @@ import (
 	"graphdb/internal/config"
 	"graphdb/internal/graph"
 	"graphdb/internal/tools/snippet"
+	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
@@ -39,15 +41,152 @@ func NewNeo4jProvider(cfg config.Config) (*Neo4jProvider, error) {
 	}, nil
 }
 
+// WithContext returns a provider running its queries under ctx on the same
+// driver, so that they stop when ctx is cancelled.
+func (p *Neo4jProvider) WithContext(ctx context.Context) GraphProvider {
+	return &Neo4jProvider{driver: p.driver, ctx: ctx}
+}
+
 // Close closes the Neo4j driver connection.
 func (p *Neo4jProvider) Close() error {
 	return p.driver.Close(p.ctx)
 }
 
-// FindNode finds a node by label and property.
+// FindNode finds the first node with the given label (any label if empty)
+// whose property equals value.
 func (p *Neo4jProvider) FindNode(label string, property string, value string) (*graph.Node, error) {
-	// TODO: Implement in Phase 2.2+
-	return nil, nil
+	if !identifierPattern.MatchString(property) || (label != "" && !identifierPattern.MatchString(label)) {
+		return nil, fmt.Errorf("invalid label %q or property %q", label, property)
+	}
+	pattern := "(n)"
+	if label != "" {
+		pattern = fmt.Sprintf("(n:`%s`)", label)
+	}
+	query := fmt.Sprintf(`
+		MATCH %s WHERE toString(n.`+"`%s`"+`) = $value
+		RETURN n
+		LIMIT 1
+	`, pattern, property)
+
+	result, err := neo4j.ExecuteQuery(p.ctx, p.driver, query, map[string]any{
+		"value": value,
+	}, neo4j.EagerResultTransformer)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute FindNode query: %w", err)
+	}
+
+	if len(result.Records) == 0 {
+		return nil, nil
+	}
+	n, _, err := neo4j.GetRecordValue[neo4j.Node](result.Records[0], "n")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node from record: %w", err)
+	}
+	return toGraphNode(n), nil
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// toGraphNode converts a driver node, identified by its id property or, failing
+// that, its name.
+func toGraphNode(n neo4j.Node) *graph.Node {
+	label := ""
+	if len(n.Labels) > 0 {
+		label = n.Labels[0]
+	}
+
+	id := ""
+	if idVal, ok := n.Props["id"].(string); ok {
+		id = idVal
+	} else if nameVal, ok := n.Props["name"].(string); ok {
+		id = nameVal
+	}
+
+	return &graph.Node{
+		ID:         id,
+		Label:      label,
+		Properties: n.Props,
+	}
+}
+
+// ResolveNode returns the node with the given ID and every node with that
+// name, ordered by label, file and line.
+func (p *Neo4jProvider) ResolveNode(nameOrID string) ([]*Candidate, error) {
+	query := fmt.Sprintf(`
+		CALL {
+			%s
+		}
+		RETURN n.id as id, n.name as name, labels(n)[0] as label, n.file as file,
+			coalesce(n.start_line, n.line) as line
+	`, matchResolvable("n.id = $id OR n.name = $id", "n"))
+	result, err := neo4j.ExecuteQuery(p.ctx, p.driver, query, map[string]any{
+		"id": nameOrID,
+	}, neo4j.EagerResultTransformer)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute ResolveNode query: %w", err)
+	}
+
+	candidates := make([]*Candidate, 0, len(result.Records))
+	for _, record := range result.Records {
+		m := record.AsMap()
+		c := &Candidate{Line: intProp(m["line"])}
+		c.ID, _ = m["id"].(string)
+		c.Name, _ = m["name"].(string)
+		c.Label, _ = m["label"].(string)
+		c.File, _ = m["file"].(string)
+		if c.ID == "" {
+			c.ID = c.Name
+		}
+		candidates = append(candidates, c)
+	}
+	sortCandidates(candidates)
+	return candidates, nil
+}
+
+// SuggestNodes returns up to limit node names close to name. Only names of a
+// length within the edit distance, or containing name, leave the database.
+func (p *Neo4jProvider) SuggestNodes(name string, limit int) ([]string, error) {
+	query := fmt.Sprintf(`
+		CALL {
+			%s
+		}
+		RETURN DISTINCT name
+	`, matchResolvable("n.name IS NOT NULL AND (size(n.name) >= $shortest AND size(n.name) <= $longest OR toLower(n.name) CONTAINS $lower)", "n.name AS name"))
+	lower := strings.ToLower(name)
+	length := utf8.RuneCountInString(lower)
+	distance := suggestDistance(lower)
+	result, err := neo4j.ExecuteQuery(p.ctx, p.driver, query, map[string]any{
+		"shortest": length - distance,
+		"longest":  length + distance,
+		"lower":    lower,
+	}, neo4j.EagerResultTransformer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute SuggestNodes query: %w", err)
+	}
+
+	names := make([]string, 0, len(result.Records))
+	for _, record := range result.Records {
+		if name, _, err := neo4j.GetRecordValue[string](record, "name"); err == nil {
+			names = append(names, name)
+		}
+	}
+	return closestNames(name, names, limit), nil
+}
+
+// resolvableLabels are the labels query targets are resolved among. The
+// loader indexes their id and name, so resolving never scans every node.
+var resolvableLabels = []string{"Function", "Method", "Class", "Global", "Feature", "File"}
+
+// matchResolvable matches the nodes of every resolvable label that satisfy
+// where, returning ret from each as one union.
+func matchResolvable(where, ret string) string {
+	parts := make([]string, len(resolvableLabels))
+	for i, label := range resolvableLabels {
+		parts[i] = fmt.Sprintf("MATCH (n:%s) WHERE %s RETURN %s", label, where, ret)
+	}
+	return strings.Join(parts, "\n\t\t\tUNION\n\t\t\t")
 }
 
 // Traverse traverses the graph from a start node.
@@ -99,23 +238,7 @@ func (p *Neo4jProvider) Traverse(startNodeID string, relationship string, direct
 		}
 
 		for i, n := range rawPath.Nodes {
-			label := ""
-			if len(n.Labels) > 0 {
-				label = n.Labels[0]
-			}
-
-			id := ""
-			if idVal, ok := n.Props["id"].(string); ok {
-				id = idVal
-			} else if nameVal, ok := n.Props["name"].(string); ok {
-				id = nameVal
-			}
-
-			gPath.Nodes[i] = &graph.Node{
-				ID:         id,
-				Label:      label,
-				Properties: n.Props,
-			}
+			gPath.Nodes[i] = toGraphNode(n)
 		}
 
 		for i, r := range rawPath.Relationships {
@@ -353,13 +476,28 @@ func (p *Neo4jProvider) GetCallers(nodeID string) ([]string, error) {
 	return callers, nil
 }
 
-// GetImpact analyzes the impact of changing a node (reverse dependencies).
+// GetImpact analyzes the impact of changing a node (reverse dependencies),
+// returning every caller within depth calls with its shortest path of CALLS.
 func (p *Neo4jProvider) GetImpact(nodeID string, depth int) (*ImpactResult, error) {
-	// Construct dynamic query for variable path length
+	// Construct dynamic query for variable path length. The distinct callers
+	// come first, so only one shortest path is expanded per caller.
 	query := fmt.Sprintf(`
 		MATCH (n) WHERE n.name = $nodeID OR n.id = $nodeID
-		MATCH (caller)-[:CALLS*1..%d]->(n) 
-		RETURN DISTINCT caller.name as caller, caller.ui_contaminated as contaminated
+		MATCH (n)<-[:CALLS*1..%[1]d]-(caller)
+		WHERE caller <> n
+		WITH DISTINCT n, caller
+		MATCH path = shortestPath((caller)-[:CALLS*1..%[1]d]->(n))
+		WITH caller, path ORDER BY length(path)
+		WITH caller, head(collect(path)) AS path
+		OPTIONAL MATCH (caller)-[:IMPLEMENTS]->(feature:Feature)
+		WITH caller, path, head(collect(feature.id)) AS feature
+		RETURN caller.id AS id, caller.name AS caller, caller.file AS file,
+			coalesce(caller.start_line, caller.line) AS line,
+			caller.ui_contaminated AS contaminated, caller.risk_score AS risk,
+			coalesce(feature, caller.feature_id) AS feature, length(path) AS depth,
+			[x IN nodes(path) | {id: x.id, name: x.name, file: x.file, line: coalesce(x.start_line, x.line)}] AS nodes,
+			[r IN relationships(path) | {source: startNode(r).id, target: endNode(r).id}] AS edges
+		ORDER BY depth, id
 	`, depth)
 
 	result, err := neo4j.ExecuteQuery(p.ctx, p.driver, query, map[string]any{
@@ -371,26 +509,52 @@ func (p *Neo4jProvider) GetImpact(nodeID string, depth int) (*ImpactResult, erro
 	}
 
 	callers := make([]*graph.Node, 0, len(result.Records))
+	paths := make([]*graph.Path, 0, len(result.Records))
 	for _, record := range result.Records {
-		label, _, err := neo4j.GetRecordValue[string](record, "caller")
-		if err != nil {
-			continue
+		m := record.AsMap()
+		id, _ := m["id"].(string)
+		name, _ := m["caller"].(string)
+		file, _ := m["file"].(string)
+		contaminated, _ := m["contaminated"].(bool)
+
+		caller := impactNode(id, name, file, intProp(m["line"]))
+		caller.Properties["ui_contaminated"] = contaminated
+		if risk, ok := m["risk"]; ok && risk != nil {
+			caller.Properties["risk_score"] = floatProp(risk)
+		}
+		caller.Properties["depth"] = intProp(m["depth"])
+		if feature, _ := m["feature"].(string); feature != "" {
+			caller.Properties["feature_id"] = feature
 		}
-		contaminated, _, _ := neo4j.GetRecordValue[bool](record, "contaminated")
 
-		node := &graph.Node{
-			Label: label,
-			Properties: map[string]any{
-				"ui_contaminated": contaminated,
-			},
+		path := &graph.Path{}
+		nodes, _ := m["nodes"].([]any)
+		for _, raw := range nodes {
+			n, _ := raw.(map[string]any)
+			id, _ := n["id"].(string)
+			name, _ := n["name"].(string)
+			file, _ := n["file"].(string)
+			path.Nodes = append(path.Nodes, impactNode(id, name, file, intProp(n["line"])))
+		}
+		edges, _ := m["edges"].([]any)
+		for _, raw := range edges {
+			e, _ := raw.(map[string]any)
+			source, _ := e["source"].(string)
+			target, _ := e["target"].(string)
+			path.Edges = append(path.Edges, &graph.Edge{SourceID: source, TargetID: target, Type: "CALLS"})
 		}
-		callers = append(callers, node)
+		if len(path.Nodes) == 0 {
+			continue
+		}
+
+		callers = append(callers, caller)
+		paths = append(paths, path)
 	}
 
 	return &ImpactResult{
-		Target:  &graph.Node{Label: nodeID},
+		Target:  impactTarget(nodeID, paths),
 		Callers: callers,
-		// Paths: nil, // Not implementing paths yet as per requirement, just callers
+		Paths:   paths,
 	}, nil
 }
 
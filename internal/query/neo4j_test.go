@@ -8,6 +8,8 @@ import (
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
+var _ ContextBinder = (*Neo4jProvider)(nil)
+
 func getProvider(t *testing.T) *Neo4jProvider {
 	uri := os.Getenv("NEO4J_URI")
 	if uri == "" {
@@ -41,6 +43,48 @@ func TestNeo4jConnection(t *testing.T) {
 	defer p.Close()
 }
 
+func TestFindAndResolveNode(t *testing.T) {
+	p := getProvider(t)
+	defer p.Close()
+	defer cleanup(t, p)
+
+	setupQuery := `
+		CREATE (:Function {name: 'TestInit', id: 'a.go:TestInit', file: 'test_fixture.go', start_line: 3})
+		CREATE (:Method {name: 'TestInit', id: 'b.go:TestInit', file: 'b.go', line: 7})
+	`
+	_, err := neo4j.ExecuteQuery(p.ctx, p.driver, setupQuery, nil, neo4j.EagerResultTransformer)
+	if err != nil {
+		t.Fatalf("Failed to setup fixture: %v", err)
+	}
+
+	n, err := p.FindNode("Method", "line", "7")
+	if err != nil || n == nil || n.ID != "b.go:TestInit" || n.Label != "Method" {
+		t.Errorf("Expected FindNode to find the method, got %+v, %v", n, err)
+	}
+	if n, _ := p.FindNode("Function", "file", "missing.go"); n != nil {
+		t.Errorf("Expected no node, got %+v", n)
+	}
+	if _, err := p.FindNode("Function) DETACH DELETE (n", "id", "x"); err == nil {
+		t.Error("Expected an invalid label to be rejected")
+	}
+
+	candidates, err := p.ResolveNode("TestInit")
+	if err != nil || len(candidates) != 2 {
+		t.Fatalf("Expected 2 candidates, got %+v, %v", candidates, err)
+	}
+	if c := candidates[0]; c.Label != "Function" || c.File != "test_fixture.go" || c.Line != 3 {
+		t.Errorf("Unexpected first candidate %+v", c)
+	}
+	if c, err := Resolve(p, "TestInit", Qualifier{Label: "Method"}); err != nil || c.ID != "b.go:TestInit" || c.Line != 7 {
+		t.Errorf("Expected -label to pick the method, got %+v, %v", c, err)
+	}
+
+	suggestions, err := p.SuggestNodes("TestInt", 3)
+	if err != nil || len(suggestions) == 0 || suggestions[0] != "TestInit" {
+		t.Errorf("Expected TestInit to be suggested, got %v, %v", suggestions, err)
+	}
+}
+
 func TestGetNeighbors(t *testing.T) {
 	p := getProvider(t)
 	defer p.Close()
@@ -159,6 +203,39 @@ func TestGetImpact(t *testing.T) {
 	if !foundMid {
 		t.Error("Expected to find TestMid")
 	}
+
+	// Nearest first, each with its shortest path to the target
+	if len(result.Paths) != 2 || result.Callers[1].ID != "TestDeepCaller" || result.Callers[1].Properties["depth"] != 2 {
+		t.Fatalf("Expected TestDeepCaller last at depth 2, got %+v", result.Callers)
+	}
+	if path := result.Paths[1]; len(path.Nodes) != 3 || path.Nodes[1].ID != "TestMid" || path.Nodes[2].ID != "TestTarget" {
+		t.Errorf("Expected the path through TestMid, got %+v", path.Nodes)
+	}
+}
+
+func TestGetImpact_CallerSharingTheName(t *testing.T) {
+	p := getProvider(t)
+	defer p.Close()
+	defer cleanup(t, p)
+
+	// The same method name on two types
+	setupQuery := `
+		CREATE (a:Function {name: 'TestRun', id: 'TestA:TestRun'})
+		CREATE (b:Function {name: 'TestRun', id: 'TestB:TestRun'})
+		CREATE (b)-[:CALLS]->(a)
+	`
+	_, err := neo4j.ExecuteQuery(p.ctx, p.driver, setupQuery, nil, neo4j.EagerResultTransformer)
+	if err != nil {
+		t.Fatalf("Failed to setup fixture: %v", err)
+	}
+
+	result, err := p.GetImpact("TestRun", 2)
+	if err != nil {
+		t.Fatalf("GetImpact failed: %v", err)
+	}
+	if len(result.Callers) != 1 || result.Callers[0].ID != "TestB:TestRun" {
+		t.Errorf("Expected TestB:TestRun as the caller, got %+v", result.Callers)
+	}
 }
 
 func TestGetGlobals(t *testing.T) {
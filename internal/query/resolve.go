@@ -0,0 +1,205 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Candidate is a node a name or ID may refer to, with what tells it apart
+// from other nodes of the same name.
+type Candidate struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Label string `json:"label"`
+	File  string `json:"file,omitempty"`
+	Line  int    `json:"line,omitempty"`
+}
+
+func (c *Candidate) String() string {
+	location := c.File
+	if location != "" && c.Line > 0 {
+		location = fmt.Sprintf("%s:%d", c.File, c.Line)
+	}
+	if location == "" {
+		return fmt.Sprintf("%s %s", c.Label, c.ID)
+	}
+	return fmt.Sprintf("%s %s (%s)", c.Label, c.ID, location)
+}
+
+// Qualifier narrows the candidates of a name: Label matches the node label,
+// ignoring case, and File the end of its file path (e.g. "auth/login.go").
+type Qualifier struct {
+	Label string
+	File  string
+}
+
+func (q Qualifier) matches(c *Candidate) bool {
+	if q.Label != "" && !strings.EqualFold(c.Label, q.Label) {
+		return false
+	}
+	if q.File != "" {
+		file := strings.TrimPrefix(q.File, "./")
+		if c.File != file && !strings.HasSuffix(c.File, "/"+file) {
+			return false
+		}
+	}
+	return true
+}
+
+// AmbiguousError reports a name that matches several nodes.
+type AmbiguousError struct {
+	Name       string
+	Candidates []*Candidate
+}
+
+func (e *AmbiguousError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q matches %d nodes; pass one of these IDs, or narrow it by file or label:", e.Name, len(e.Candidates))
+	for _, c := range e.Candidates {
+		b.WriteString("\n  ")
+		b.WriteString(c.String())
+	}
+	return b.String()
+}
+
+// NotFoundError reports a name that matches no node, with the closest names.
+type NotFoundError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *NotFoundError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("node not found: %s", e.Name)
+	}
+	return fmt.Sprintf("node not found: %s; did you mean %s?", e.Name, strings.Join(e.Suggestions, ", "))
+}
+
+// Resolve returns the one node that nameOrID refers to. An exact ID always
+// wins; otherwise the nodes named nameOrID are narrowed by q. It returns an
+// *AmbiguousError if several remain and a *NotFoundError, with suggestions,
+// if none do.
+func Resolve(p GraphProvider, nameOrID string, q Qualifier) (*Candidate, error) {
+	candidates, err := p.ResolveNode(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range candidates {
+		if c.ID == nameOrID {
+			return c, nil
+		}
+	}
+
+	var matching []*Candidate
+	for _, c := range candidates {
+		if q.matches(c) {
+			matching = append(matching, c)
+		}
+	}
+	switch len(matching) {
+	case 1:
+		return matching[0], nil
+	case 0:
+		if len(candidates) > 0 {
+			// The name exists but the qualifiers rule out every node of it
+			ids := make([]string, len(candidates))
+			for i, c := range candidates {
+				ids[i] = c.ID
+			}
+			return nil, &NotFoundError{Name: nameOrID, Suggestions: ids}
+		}
+		suggestions, err := p.SuggestNodes(nameOrID, 5)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &NotFoundError{Name: nameOrID, Suggestions: suggestions}
+	}
+	return nil, &AmbiguousError{Name: nameOrID, Candidates: matching}
+}
+
+// sortCandidates orders candidates by label, file, line and ID.
+func sortCandidates(candidates []*Candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.ID < b.ID
+	})
+}
+
+// closestNames returns up to limit of names that are near misses of name:
+// within a few edits of it, ignoring case, or containing it. The closest
+// come first.
+func closestNames(name string, names []string, limit int) []string {
+	type scored struct {
+		name     string
+		distance int
+	}
+	lower := strings.ToLower(name)
+	maxDistance := suggestDistance(lower)
+
+	var near []scored
+	seen := make(map[string]bool)
+	for _, candidate := range names {
+		if candidate == "" || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		other := strings.ToLower(candidate)
+		distance := editDistance(lower, other)
+		if distance > maxDistance && !strings.Contains(other, lower) {
+			continue
+		}
+		near = append(near, scored{candidate, distance})
+	}
+
+	sort.Slice(near, func(i, j int) bool {
+		if near[i].distance != near[j].distance {
+			return near[i].distance < near[j].distance
+		}
+		return near[i].name < near[j].name
+	})
+	suggestions := make([]string, 0, limit)
+	for _, s := range near {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, s.name)
+	}
+	return suggestions
+}
+
+// suggestDistance is the largest edit distance at which a name is suggested
+// for name.
+func suggestDistance(name string) int {
+	return max(2, len(name)/3)
+}
+
+// editDistance is the Levenshtein distance between a and b, in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,75 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	_, providers := embeddedProviders(t,
+		`{"id": "lib/store.ts:Save", "type": "Function", "name": "Save", "file": "lib/store.ts", "start_line": 4}`,
+		`{"id": "lib/store.ts:Saver", "type": "Class", "name": "Saver", "file": "lib/store.ts", "line": 1}`,
+	)
+	for name, p := range providers {
+		t.Run(name, func(t *testing.T) {
+			if c, err := Resolve(p, "app:Save", Qualifier{}); err != nil || c.ID != "app:Save" {
+				t.Errorf("Expected an exact ID to resolve, got %+v, %v", c, err)
+			}
+			if c, err := Resolve(p, "Handler", Qualifier{}); err != nil || c.ID != "app:Handler" || c.Label != "Function" {
+				t.Errorf("Expected a unique name to resolve, got %+v, %v", c, err)
+			}
+
+			_, err := Resolve(p, "Save", Qualifier{})
+			var ambiguous *AmbiguousError
+			if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+				t.Fatalf("Expected Save to be ambiguous, got %v", err)
+			}
+			if c := ambiguous.Candidates[1]; c.ID != "lib/store.ts:Save" || c.File != "lib/store.ts" || c.Line != 4 {
+				t.Errorf("Expected candidates with file and line, got %+v", c)
+			}
+			if !strings.Contains(err.Error(), "Function lib/store.ts:Save (lib/store.ts:4)") {
+				t.Errorf("Expected the candidates in the message, got %q", err)
+			}
+
+			if c, err := Resolve(p, "Save", Qualifier{File: "store.ts"}); err != nil || c.ID != "lib/store.ts:Save" {
+				t.Errorf("Expected -file to pick lib/store.ts:Save, got %+v, %v", c, err)
+			}
+			if c, err := Resolve(p, "Save", Qualifier{File: "app.ts", Label: "function"}); err != nil || c.ID != "app:Save" {
+				t.Errorf("Expected -file to pick app:Save, got %+v, %v", c, err)
+			}
+
+			var notFound *NotFoundError
+			_, err = Resolve(p, "Save", Qualifier{Label: "Class"})
+			if !errors.As(err, &notFound) || !reflect.DeepEqual(notFound.Suggestions, []string{"app:Save", "lib/store.ts:Save"}) {
+				t.Errorf("Expected the ruled out IDs as suggestions, got %v", err)
+			}
+			_, err = Resolve(p, "sav", Qualifier{})
+			if !errors.As(err, &notFound) || !reflect.DeepEqual(notFound.Suggestions, []string{"Save", "Saver"}) {
+				t.Errorf("Expected did-you-mean suggestions, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "did you mean Save, Saver?") {
+				t.Errorf("Unexpected message: %q", err)
+			}
+		})
+	}
+}
+
+func TestClosestNames(t *testing.T) {
+	names := []string{"Login", "Logout", "LoginHandler", "Register", "Login"}
+	got := closestNames("logn", names, 5)
+	want := []string{"Login"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := closestNames("logot", names, 1); !reflect.DeepEqual(got, []string{"Logout"}) {
+		t.Errorf("Expected the limit to keep the closest, got %v", got)
+	}
+	if got := closestNames("Handler", names, 5); !reflect.DeepEqual(got, []string{"LoginHandler"}) {
+		t.Errorf("Expected names containing it, got %v", got)
+	}
+	if got := closestNames("Payment", names, 5); len(got) != 0 {
+		t.Errorf("Expected no suggestions, got %v", got)
+	}
+}
@@ -2,6 +2,8 @@ package rpg
 
 import (
 	"graphdb/internal/graph"
+	"net/url"
+	"path/filepath"
 	"strings"
 )
 
@@ -34,7 +36,7 @@ func (b *Builder) Build(rootPath string, functions []graph.Node) ([]Feature, []g
 
 	for name, pathPrefix := range domains {
 		domainFeature := Feature{
-			ID:        "domain-" + name,
+			ID:        featureID("domain-", scopeKey(name, pathPrefix)...),
 			Name:      name,
 			ScopePath: pathPrefix,
 			Children:  make([]*Feature, 0),
@@ -51,12 +53,18 @@ func (b *Builder) Build(rootPath string, functions []graph.Node) ([]Feature, []g
 		}
 		domainFeature.MemberFunctions = domainFuncs
 
+		// File paths may be absolute, so clusters named after files are
+		// trimmed at the scope wherever it occurs, as the filter above does
+		scope := filepath.ToSlash(pathPrefix)
+		if scope == "" {
+			scope = filepath.ToSlash(filepath.Clean(rootPath))
+		}
 		if b.CategoryClusterer != nil {
 			// 3-level hierarchy: Domain -> Category -> Feature
-			allEdges = b.buildThreeLevel(&domainFeature, domainFuncs, name, pathPrefix, allEdges)
+			allEdges = b.buildThreeLevel(&domainFeature, domainFuncs, name, scope, allEdges)
 		} else {
 			// 2-level hierarchy: Domain -> Feature
-			allEdges = b.buildTwoLevel(&domainFeature, domainFuncs, name, pathPrefix, allEdges)
+			allEdges = b.buildTwoLevel(&domainFeature, domainFuncs, name, scope, allEdges)
 		}
 
 		rootFeatures = append(rootFeatures, domainFeature)
@@ -65,13 +73,14 @@ func (b *Builder) Build(rootPath string, functions []graph.Node) ([]Feature, []g
 	return rootFeatures, allEdges, nil
 }
 
-func (b *Builder) buildTwoLevel(domain *Feature, funcs []graph.Node, name, pathPrefix string, allEdges []graph.Edge) []graph.Edge {
+func (b *Builder) buildTwoLevel(domain *Feature, funcs []graph.Node, name, scope string, allEdges []graph.Edge) []graph.Edge {
 	clusters, _ := b.Clusterer.Cluster(funcs, name)
 	for clusterName, nodes := range clusters {
+		clusterName = scopedName(clusterName, scope)
 		child := &Feature{
-			ID:              "feat-" + clusterName,
+			ID:              featureID("feat-", append(scopeKey(name, domain.ScopePath), clusterName)...),
 			Name:            clusterName,
-			ScopePath:       pathPrefix,
+			ScopePath:       domain.ScopePath,
 			MemberFunctions: nodes,
 		}
 
@@ -94,14 +103,17 @@ func (b *Builder) buildTwoLevel(domain *Feature, funcs []graph.Node, name, pathP
 	return allEdges
 }
 
-func (b *Builder) buildThreeLevel(domain *Feature, funcs []graph.Node, name, pathPrefix string, allEdges []graph.Edge) []graph.Edge {
+func (b *Builder) buildThreeLevel(domain *Feature, funcs []graph.Node, name, scope string, allEdges []graph.Edge) []graph.Edge {
+	key := scopeKey(name, domain.ScopePath)
+
 	// First pass: coarse clustering into categories
 	categories, _ := b.CategoryClusterer.Cluster(funcs, name)
 	for catName, catNodes := range categories {
+		catName = scopedName(catName, scope)
 		category := &Feature{
-			ID:              "cat-" + catName,
+			ID:              featureID("cat-", append(key, catName)...),
 			Name:            catName,
-			ScopePath:       pathPrefix,
+			ScopePath:       domain.ScopePath,
 			MemberFunctions: catNodes,
 			Children:        make([]*Feature, 0),
 		}
@@ -115,10 +127,11 @@ func (b *Builder) buildThreeLevel(domain *Feature, funcs []graph.Node, name, pat
 		// Second pass: fine-grained clustering within each category
 		features, _ := b.Clusterer.Cluster(catNodes, catName)
 		for featName, featNodes := range features {
+			featName = scopedName(featName, scope)
 			feature := &Feature{
-				ID:              "feat-" + featName,
+				ID:              featureID("feat-", append(key, catName, featName)...),
 				Name:            featName,
-				ScopePath:       pathPrefix,
+				ScopePath:       domain.ScopePath,
 				MemberFunctions: featNodes,
 			}
 
@@ -143,3 +156,38 @@ func (b *Builder) buildThreeLevel(domain *Feature, funcs []graph.Node, name, pat
 	}
 	return allEdges
 }
+
+// scopeKey identifies a domain by the directories of its scope path, which
+// unlike its name is unique; the root domain has no scope and falls back to
+// the name.
+func scopeKey(name, pathPrefix string) []string {
+	if pathPrefix == "" {
+		return []string{name}
+	}
+	return strings.Split(filepath.ToSlash(pathPrefix), "/")
+}
+
+// scopedName trims a cluster name that is a file path, as FileClusterer's
+// are, to the part below the domain's scope.
+func scopedName(name, scope string) string {
+	if scope == "" || scope == "." {
+		return name
+	}
+	if i := strings.Index(name, scope+"/"); i >= 0 {
+		return name[i+len(scope)+1:]
+	}
+	return name
+}
+
+// featureID derives a Feature ID from the feature's path in the hierarchy
+// (domain scope, then category, then feature name), so features with the same name
+// in different domains or categories stay distinct, and reruns over the same
+// code produce the same IDs. Names are escaped so that a "/" in one cannot
+// make two paths collide.
+func featureID(prefix string, path ...string) string {
+	segments := make([]string, len(path))
+	for i, name := range path {
+		segments[i] = url.PathEscape(name)
+	}
+	return prefix + strings.Join(segments, "/")
+}
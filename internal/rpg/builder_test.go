@@ -2,6 +2,7 @@ package rpg
 
 import (
 	"graphdb/internal/graph"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -160,3 +161,89 @@ func TestBuilder_BuildThreeLevel(t *testing.T) {
 		}
 	}
 }
+
+type sameFileDiscoverer struct{}
+
+func (d *sameFileDiscoverer) DiscoverDomains(fileTree string) (map[string]string, error) {
+	return map[string]string{"a": "internal/a", "b": "internal/b"}, nil
+}
+
+func TestBuilder_FeatureIDsFollowHierarchy(t *testing.T) {
+	builder := &Builder{
+		Discoverer: &sameFileDiscoverer{},
+		Clusterer:  &FileClusterer{},
+	}
+	functions := []graph.Node{
+		{ID: "a1", Properties: map[string]interface{}{"file": "internal/a/util.go"}},
+		{ID: "b1", Properties: map[string]interface{}{"file": "internal/b/util.go"}},
+		{ID: "a2", Properties: map[string]interface{}{"file": "/repo/internal/a/x/util.go"}},
+	}
+
+	implemented := func() map[string]string {
+		_, edges, err := builder.Build("", functions)
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		featureOf := make(map[string]string)
+		for _, e := range edges {
+			if e.Type == "IMPLEMENTS" {
+				featureOf[e.SourceID] = e.TargetID
+			}
+		}
+		return featureOf
+	}
+
+	first := implemented()
+	// util.go in two domains, or two directories of one domain, must not
+	// collapse into one feature
+	want := map[string]string{
+		"a1": "feat-internal/a/util",
+		"b1": "feat-internal/b/util",
+		"a2": "feat-internal/a/x%2Futil",
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("Expected feature IDs %v, got %v", want, first)
+	}
+	if second := implemented(); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same IDs on a rerun, got %v then %v", first, second)
+	}
+}
+
+func TestBuilder_ThreeLevelFeatureIDs(t *testing.T) {
+	builder := &Builder{
+		Discoverer:        &MockDiscoverer{},
+		CategoryClusterer: &MockCategoryClusterer{},
+		Clusterer:         &MockClusterer{},
+	}
+	functions := []graph.Node{
+		{ID: "func1", Properties: map[string]interface{}{"file": "src/auth/login.go"}},
+	}
+
+	features, _, err := builder.Build("src/", functions)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	for _, domain := range features {
+		if domain.ID != "domain-src/auth" {
+			continue
+		}
+		cat := domain.Children[0]
+		if cat.ID != "cat-src/auth/Auth-cat" {
+			t.Errorf("Expected the category ID to include its domain, got %s", cat.ID)
+		}
+		if feat := cat.Children[0]; feat.ID != "feat-src/auth/Auth-cat/Auth-catCore" {
+			t.Errorf("Expected the feature ID to include its domain and category, got %s", feat.ID)
+		}
+		return
+	}
+	t.Fatal("Expected an Auth domain")
+}
+
+func TestFeatureID_EscapesSeparators(t *testing.T) {
+	if a, b := featureID("feat-", "x/y", "z"), featureID("feat-", "x", "y/z"); a == b {
+		t.Errorf("Expected distinct IDs for distinct paths, got %s for both", a)
+	}
+	if id := featureID("domain-", "rpg"); id != "domain-rpg" {
+		t.Errorf("Expected plain names unchanged, got %s", id)
+	}
+}
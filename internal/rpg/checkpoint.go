@@ -0,0 +1,310 @@
+package rpg
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Prompt versions are part of every checkpoint key. Bump them whenever the
+// corresponding prompt changes, so checkpointed answers to the old prompt are
+// asked for again.
+const (
+	FeaturePromptVersion = 1
+	SummaryPromptVersion = 1
+)
+
+// Checkpoint kinds.
+const (
+	checkpointFeatures = "features"
+	checkpointSummary  = "summary"
+)
+
+// CheckpointPath returns where the checkpoint for an output file or store lives.
+func CheckpointPath(output string) string {
+	return output + ".checkpoint.jsonl"
+}
+
+// Checkpoint records LLM answers in a JSONL sidecar as they are produced,
+// keyed by a hash of the prompt version and the prompted content, so that an
+// interrupted enrichment resumes where it stopped and unchanged functions are
+// never sent to the LLM again.
+type Checkpoint struct {
+	path    string
+	mu      sync.Mutex
+	file    *os.File
+	records map[string]CheckpointRecord
+	used    map[string]bool
+
+	reused, recorded atomic.Int64
+}
+
+// CheckpointRecord is one line of the checkpoint.
+type CheckpointRecord struct {
+	Kind           string   `json:"kind"`
+	Key            string   `json:"key"`
+	AtomicFeatures []string `json:"atomic_features,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	Description    string   `json:"description,omitempty"`
+}
+
+// OpenCheckpoint opens the checkpoint at path for appending. With resume, the
+// records already in it are served; otherwise it starts empty. A line cut
+// short by a crash is skipped.
+func OpenCheckpoint(path string, resume bool) (*Checkpoint, error) {
+	c := &Checkpoint{
+		path:    path,
+		records: make(map[string]CheckpointRecord),
+		used:    make(map[string]bool),
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		if err := c.load(); err != nil {
+			return nil, err
+		}
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	c.file = file
+	return c, nil
+}
+
+func (c *Checkpoint) load() error {
+	f, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	skipped := 0
+	for scanner.Scan() {
+		var record CheckpointRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Key == "" {
+			skipped++
+			continue
+		}
+		c.records[record.Key] = record
+	}
+	if skipped > 0 {
+		log.Printf("Skipped %d unreadable lines of checkpoint %s", skipped, c.path)
+	}
+	return scanner.Err()
+}
+
+// Len returns the number of records available.
+func (c *Checkpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.records)
+}
+
+// Stats returns how many answers were served from the checkpoint and how many
+// were recorded in this run.
+func (c *Checkpoint) Stats() (reused, recorded int64) {
+	return c.reused.Load(), c.recorded.Load()
+}
+
+func (c *Checkpoint) lookup(key string) (CheckpointRecord, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	record, ok := c.records[key]
+	if ok {
+		c.used[key] = true
+		c.reused.Add(1)
+	}
+	return record, ok
+}
+
+// record appends record to the file right away, so it survives a crash.
+func (c *Checkpoint) record(record CheckpointRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records[record.Key] = record
+	c.used[record.Key] = true
+	c.recorded.Add(1)
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Compact rewrites the checkpoint with only the records used in this run,
+// dropping answers for functions and features that no longer exist. Call it
+// after a successful run.
+func (c *Checkpoint) Compact() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to compact checkpoint: %w", err)
+	}
+	keys := make([]string, 0, len(c.used))
+	for key := range c.used {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, key := range keys {
+		if err := enc.Encode(c.records[key]); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return fmt.Errorf("failed to compact checkpoint: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compact checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compact checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to compact checkpoint: %w", err)
+	}
+
+	// Keep appending to the compacted file
+	c.file.Close()
+	c.file, err = os.OpenFile(c.path, os.O_WRONLY|os.O_APPEND, 0644)
+	return err
+}
+
+// Close closes the checkpoint file.
+func (c *Checkpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Close()
+}
+
+func checkpointKey(kind string, version int, parts ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d", kind, version)
+	for _, part := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// featureKey hashes what featurePrompt and batchFeaturePrompt send for a function.
+func featureKey(name, code string) string {
+	return checkpointKey(checkpointFeatures, FeaturePromptVersion, name, truncateCode(code))
+}
+
+// summaryKey hashes what summaryPrompt sends for a feature.
+func summaryKey(snippets []string) string {
+	return checkpointKey(checkpointSummary, SummaryPromptVersion, strings.Join(snippets, "\x00"))
+}
+
+// CheckpointExtractor serves atomic features from a Checkpoint and records
+// whatever Extractor produces.
+type CheckpointExtractor struct {
+	Extractor  FeatureExtractor
+	Checkpoint *Checkpoint
+}
+
+func (e *CheckpointExtractor) Extract(code string, functionName string) ([]string, error) {
+	key := featureKey(functionName, code)
+	if record, ok := e.Checkpoint.lookup(key); ok {
+		return record.AtomicFeatures, nil
+	}
+
+	descriptors, err := e.Extractor.Extract(code, functionName)
+	if err != nil {
+		return nil, err
+	}
+	e.save(key, descriptors)
+	return descriptors, nil
+}
+
+// ExtractBatch only prompts for the functions not in the checkpoint.
+func (e *CheckpointExtractor) ExtractBatch(functions []FunctionSource) (map[string][]string, error) {
+	result := make(map[string][]string, len(functions))
+	keys := make(map[string]string)
+	var missing []FunctionSource
+	for _, fn := range functions {
+		key := featureKey(fn.Name, fn.Code)
+		if record, ok := e.Checkpoint.lookup(key); ok {
+			result[fn.ID] = record.AtomicFeatures
+			continue
+		}
+		keys[fn.ID] = key
+		missing = append(missing, fn)
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	answered, err := e.Extractor.ExtractBatch(missing)
+	if err != nil {
+		// Keep what the checkpoint had; the rest is retried one by one
+		return result, err
+	}
+	for id, descriptors := range answered {
+		if key, ok := keys[id]; ok {
+			e.save(key, descriptors)
+			result[id] = descriptors
+		}
+	}
+	return result, nil
+}
+
+func (e *CheckpointExtractor) save(key string, descriptors []string) {
+	record := CheckpointRecord{Kind: checkpointFeatures, Key: key, AtomicFeatures: descriptors}
+	if err := e.Checkpoint.record(record); err != nil {
+		log.Printf("WARNING: %v", err)
+	}
+}
+
+// CheckpointSummarizer serves feature summaries from a Checkpoint and records
+// whatever Summarizer produces.
+type CheckpointSummarizer struct {
+	Summarizer Summarizer
+	Checkpoint *Checkpoint
+}
+
+func (s *CheckpointSummarizer) Summarize(snippets []string) (string, string, error) {
+	key := summaryKey(snippets)
+	if record, ok := s.Checkpoint.lookup(key); ok {
+		return record.Name, record.Description, nil
+	}
+
+	name, description, err := s.Summarizer.Summarize(snippets)
+	if err != nil {
+		return "", "", err
+	}
+	record := CheckpointRecord{Kind: checkpointSummary, Key: key, Name: name, Description: description}
+	if err := s.Checkpoint.record(record); err != nil {
+		log.Printf("WARNING: %v", err)
+	}
+	return name, description, nil
+}
@@ -0,0 +1,133 @@
+package rpg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// countingExtractor answers every function with its name and counts what it
+// was asked.
+type countingExtractor struct {
+	singles int
+	batched []string
+}
+
+func (c *countingExtractor) Extract(code string, functionName string) ([]string, error) {
+	c.singles++
+	return []string{"do " + functionName}, nil
+}
+
+func (c *countingExtractor) ExtractBatch(functions []FunctionSource) (map[string][]string, error) {
+	result := make(map[string][]string)
+	for _, fn := range functions {
+		c.batched = append(c.batched, fn.Name)
+		result[fn.ID] = []string{"do " + fn.Name}
+	}
+	return result, nil
+}
+
+type countingSummarizer struct{ calls int }
+
+func (c *countingSummarizer) Summarize(snippets []string) (string, string, error) {
+	c.calls++
+	return "Feature", "Does " + strings.Join(snippets, " and "), nil
+}
+
+func openTestCheckpoint(t *testing.T, path string, resume bool) *Checkpoint {
+	t.Helper()
+	checkpoint, err := OpenCheckpoint(path, resume)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint failed: %v", err)
+	}
+	t.Cleanup(func() { checkpoint.Close() })
+	return checkpoint
+}
+
+func TestCheckpointExtractor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpg.jsonl.checkpoint.jsonl")
+	functions := []FunctionSource{
+		{ID: "a", Name: "login", Code: "func login() {}"},
+		{ID: "b", Name: "logout", Code: "func logout() {}"},
+	}
+
+	// 1. First run: everything goes to the extractor and is recorded
+	inner := &countingExtractor{}
+	checkpoint := openTestCheckpoint(t, path, true)
+	extractor := &CheckpointExtractor{Extractor: inner, Checkpoint: checkpoint}
+	extractor.ExtractBatch(functions)
+	extractor.Extract("func register() {}", "register")
+	if len(inner.batched) != 2 || inner.singles != 1 {
+		t.Fatalf("Expected 2 batched and 1 single extraction, got %v and %d", inner.batched, inner.singles)
+	}
+	checkpoint.Close()
+
+	// 2. Resumed: nothing is asked again, unless the code changed
+	inner = &countingExtractor{}
+	checkpoint = openTestCheckpoint(t, path, true)
+	if checkpoint.Len() != 3 {
+		t.Fatalf("Expected 3 records, got %d", checkpoint.Len())
+	}
+	extractor = &CheckpointExtractor{Extractor: inner, Checkpoint: checkpoint}
+	functions[1].Code = "func logout() { clearSession() }"
+	result, err := extractor.ExtractBatch(functions)
+	if err != nil {
+		t.Fatalf("ExtractBatch failed: %v", err)
+	}
+	if len(inner.batched) != 1 || inner.batched[0] != "logout" {
+		t.Errorf("Expected only the changed function to be prompted, got %v", inner.batched)
+	}
+	if result["a"][0] != "do login" || result["b"][0] != "do logout" {
+		t.Errorf("Unexpected result %v", result)
+	}
+	extractor.Extract("func register() {}", "register")
+	if inner.singles != 0 {
+		t.Errorf("Expected register from the checkpoint")
+	}
+	if reused, recorded := checkpoint.Stats(); reused != 2 || recorded != 1 {
+		t.Errorf("Expected 2 reused and 1 recorded, got %d and %d", reused, recorded)
+	}
+	checkpoint.Close()
+
+	// 3. Not resumed: starts afresh
+	checkpoint = openTestCheckpoint(t, path, false)
+	if checkpoint.Len() != 0 {
+		t.Errorf("Expected an empty checkpoint without resume, got %d records", checkpoint.Len())
+	}
+}
+
+func TestCheckpoint_CrashAndCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	checkpoint := openTestCheckpoint(t, path, true)
+	summarizer := &CheckpointSummarizer{Summarizer: &countingSummarizer{}, Checkpoint: checkpoint}
+	summarizer.Summarize([]string{"func a() {}"})
+	summarizer.Summarize([]string{"func b() {}"})
+	checkpoint.Close()
+
+	// A crash mid-write leaves a partial line behind
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"kind": "summary", "key": "abc", "na`)
+	f.Close()
+
+	inner := &countingSummarizer{}
+	checkpoint = openTestCheckpoint(t, path, true)
+	summarizer = &CheckpointSummarizer{Summarizer: inner, Checkpoint: checkpoint}
+	name, desc, err := summarizer.Summarize([]string{"func a() {}"})
+	if err != nil || name != "Feature" || desc != "Does func a() {}" || inner.calls != 0 {
+		t.Fatalf("Expected the checkpointed summary, got %q %q (%v) after %d calls", name, desc, err, inner.calls)
+	}
+
+	// Only what this run used survives compaction, and appending continues
+	if err := checkpoint.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	summarizer.Summarize([]string{"func c() {}"})
+	checkpoint.Close()
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "func a()") || !strings.Contains(lines[1], "func c()") {
+		t.Errorf("Expected the a and c summaries after compaction, got:\n%s", data)
+	}
+}
@@ -3,6 +3,7 @@ package rpg
 import (
 	"graphdb/internal/graph"
 	"path/filepath"
+	"strings"
 )
 
 type FileClusterer struct{}
@@ -18,10 +19,9 @@ func (c *FileClusterer) Cluster(nodes []graph.Node, domain string) (map[string][
 			continue
 		}
 
-		// Use the base filename (without extension) as the cluster name
-		name := filepath.Base(filePath)
-		ext := filepath.Ext(name)
-		name = name[:len(name)-len(ext)]
+		// Use the file path (without extension) as the cluster name, so files
+		// with the same name in different directories stay apart
+		name := filepath.ToSlash(strings.TrimSuffix(filePath, filepath.Ext(filePath)))
 
 		clusters[name] = append(clusters[name], node)
 	}
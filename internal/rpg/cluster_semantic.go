@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"graphdb/internal/embedding"
 	"graphdb/internal/graph"
+	"hash/fnv"
 	"math"
 	"math/rand"
+	"sort"
 	"strings"
 )
 
@@ -13,7 +15,8 @@ import (
 // atomic_features using embedding vectors and K-Means clustering.
 type EmbeddingClusterer struct {
 	Embedder      embedding.Embedder
-	MaxIterations int // K-Means iterations; 0 defaults to 50
+	MaxIterations int   // K-Means iterations; 0 defaults to 50
+	Seed          int64 // Seeds centroid selection; 0 derives it from the domain
 }
 
 func (c *EmbeddingClusterer) Cluster(nodes []graph.Node, domain string) (map[string][]graph.Node, error) {
@@ -26,6 +29,11 @@ func (c *EmbeddingClusterer) Cluster(nodes []graph.Node, domain string) (map[str
 		return map[string][]graph.Node{domain + "-core": nodes}, nil
 	}
 
+	// Sort by ID so the same functions cluster the same way whatever order
+	// they arrive in
+	nodes = append([]graph.Node(nil), nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
 	// 1. Build embedding for each function from its atomic_features
 	texts := make([]string, len(nodes))
 	for i, n := range nodes {
@@ -47,7 +55,7 @@ func (c *EmbeddingClusterer) Cluster(nodes []graph.Node, domain string) (map[str
 		}
 	}
 
-	embeddings, err := c.Embedder.EmbedBatch(texts)
+	embeddings, err := c.Embedder.EmbedBatch(texts, embedding.PurposeDocument)
 	if err != nil {
 		return nil, fmt.Errorf("embedding for clustering failed: %w", err)
 	}
@@ -66,12 +74,24 @@ func (c *EmbeddingClusterer) Cluster(nodes []graph.Node, domain string) (map[str
 	if maxIter <= 0 {
 		maxIter = 50
 	}
-	assignments := kmeans(embeddings, k, maxIter)
+	seed := c.Seed
+	if seed == 0 {
+		h := fnv.New64a()
+		h.Write([]byte(domain))
+		seed = int64(h.Sum64())
+	}
+	assignments := kmeans(embeddings, k, maxIter, rand.New(rand.NewSource(seed)))
 
-	// 4. Group nodes by cluster assignment
+	// 4. Group nodes by cluster assignment, numbering the clusters by their
+	// first member rather than by the arbitrary K-Means index
 	clusters := make(map[string][]graph.Node)
+	names := make(map[int]string)
 	for i, clusterIdx := range assignments {
-		key := fmt.Sprintf("%s-cluster-%d", domain, clusterIdx)
+		key, ok := names[clusterIdx]
+		if !ok {
+			key = fmt.Sprintf("%s-cluster-%d", domain, len(names))
+			names[clusterIdx] = key
+		}
 		clusters[key] = append(clusters[key], nodes[i])
 	}
 
@@ -80,7 +100,7 @@ func (c *EmbeddingClusterer) Cluster(nodes []graph.Node, domain string) (map[str
 
 // kmeans runs K-Means clustering on a set of vectors.
 // Returns a slice of cluster assignments (one per input vector).
-func kmeans(vectors [][]float32, k int, maxIterations int) []int {
+func kmeans(vectors [][]float32, k int, maxIterations int, rng *rand.Rand) []int {
 	n := len(vectors)
 	if n == 0 || k <= 0 {
 		return nil
@@ -88,7 +108,7 @@ func kmeans(vectors [][]float32, k int, maxIterations int) []int {
 	dim := len(vectors[0])
 
 	// Initialize centroids using K-Means++ initialization
-	centroids := kmeansppInit(vectors, k)
+	centroids := kmeansppInit(vectors, k, rng)
 
 	assignments := make([]int, n)
 
@@ -143,12 +163,12 @@ func kmeans(vectors [][]float32, k int, maxIterations int) []int {
 }
 
 // kmeansppInit selects initial centroids using K-Means++ algorithm.
-func kmeansppInit(vectors [][]float32, k int) [][]float32 {
+func kmeansppInit(vectors [][]float32, k int, rng *rand.Rand) [][]float32 {
 	n := len(vectors)
 	centroids := make([][]float32, 0, k)
 
 	// Pick first centroid randomly
-	first := rand.Intn(n)
+	first := rng.Intn(n)
 	centroids = append(centroids, vectors[first])
 
 	for len(centroids) < k {
@@ -168,7 +188,7 @@ func kmeansppInit(vectors [][]float32, k int) [][]float32 {
 		}
 
 		// Weighted random selection
-		r := rand.Float64() * total
+		r := rng.Float64() * total
 		cumulative := 0.0
 		chosen := 0
 		for i, d := range dists {
@@ -1,15 +1,19 @@
 package rpg
 
 import (
+	"graphdb/internal/embedding"
 	"graphdb/internal/graph"
 	"math"
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
 )
 
 // deterministicEmbedder returns embeddings that cluster into known groups.
 type deterministicEmbedder struct{}
 
-func (d *deterministicEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
+func (d *deterministicEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
 	// Map specific texts to known embedding regions
 	res := make([][]float32, len(texts))
 	for i, t := range texts {
@@ -97,6 +101,40 @@ func TestEmbeddingClusterer_Cluster(t *testing.T) {
 	}
 }
 
+func TestEmbeddingClusterer_Stable(t *testing.T) {
+	clusterer := &EmbeddingClusterer{Embedder: embedding.NewHashedEmbedder(64)}
+
+	var nodes []graph.Node
+	for _, name := range []string{"parse", "load", "save", "write", "read", "send", "fetch", "post", "encode", "decode"} {
+		nodes = append(nodes, graph.Node{ID: name, Properties: map[string]interface{}{"name": name}})
+	}
+	reversed := make([]graph.Node, len(nodes))
+	for i, n := range nodes {
+		reversed[len(nodes)-1-i] = n
+	}
+
+	membership := func(in []graph.Node) map[string]string {
+		clusters, err := clusterer.Cluster(in, "core")
+		if err != nil {
+			t.Fatalf("Cluster failed: %v", err)
+		}
+		byID := make(map[string]string)
+		for name, fns := range clusters {
+			for _, fn := range fns {
+				byID[fn.ID] = name
+			}
+		}
+		return byID
+	}
+
+	first := membership(nodes)
+	for _, in := range [][]graph.Node{nodes, reversed} {
+		if got := membership(in); !reflect.DeepEqual(got, first) {
+			t.Errorf("Expected the same clusters on every run, got %v and %v", first, got)
+		}
+	}
+}
+
 func TestEmbeddingClusterer_SmallInput(t *testing.T) {
 	clusterer := &EmbeddingClusterer{
 		Embedder: &deterministicEmbedder{},
@@ -158,7 +196,7 @@ func TestKmeans_Basic(t *testing.T) {
 		{0, 1},
 		{0.1, 0.9},
 	}
-	assignments := kmeans(vectors, 2, 50)
+	assignments := kmeans(vectors, 2, 50, rand.New(rand.NewSource(1)))
 
 	// First two should be in one cluster, last two in another
 	if assignments[0] != assignments[1] {
@@ -171,3 +209,48 @@ func TestKmeans_Basic(t *testing.T) {
 		t.Error("Expected vectors 0 and 2 to be in different clusters")
 	}
 }
+
+func TestEmbeddingClusterer_HashedEmbedder(t *testing.T) {
+	clusterer := &EmbeddingClusterer{Embedder: embedding.NewHashedEmbedder(256), Seed: 1}
+
+	features := []struct {
+		name string
+		af   []string
+	}{
+		{"parseConfig", []string{"parse config file", "read config settings"}},
+		{"sendRequest", []string{"send http request", "retry http request"}},
+		{"loadConfig", []string{"load config file", "read config defaults"}},
+		{"postJSON", []string{"post http request", "encode json request body"}},
+		{"reloadConfig", []string{"reload config file", "watch config settings"}},
+		{"fetchURL", []string{"fetch http request", "decode http response"}},
+	}
+	var nodes []graph.Node
+	for _, f := range features {
+		nodes = append(nodes, graph.Node{ID: f.name, Properties: map[string]interface{}{"name": f.name, "atomic_features": f.af}})
+	}
+
+	clusters, err := clusterer.Cluster(nodes, "core")
+	if err != nil {
+		t.Fatalf("Cluster failed: %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("Expected 2 clusters, got %d", len(clusters))
+	}
+	for _, members := range clusters {
+		config := strings.Contains(members[0].ID, "Config")
+		for _, m := range members {
+			if strings.Contains(m.ID, "Config") != config {
+				t.Errorf("Expected config and HTTP functions in separate clusters, got %v", clusterIDs(members))
+				break
+			}
+		}
+	}
+}
+
+func clusterIDs(nodes []graph.Node) []string {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
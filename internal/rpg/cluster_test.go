@@ -13,6 +13,7 @@ func TestFileClusterer_Cluster(t *testing.T) {
 		{ID: "f2", Properties: map[string]interface{}{"file": "internal/auth/login.go"}},
 		{ID: "f3", Properties: map[string]interface{}{"file": "internal/auth/session.go"}},
 		{ID: "f4", Properties: map[string]interface{}{"file": "no_path"}},
+		{ID: "f5", Properties: map[string]interface{}{"file": "internal/auth/admin/login.go"}},
 	}
 
 	clusters, err := clusterer.Cluster(nodes, "auth")
@@ -20,11 +21,15 @@ func TestFileClusterer_Cluster(t *testing.T) {
 		t.Fatalf("Cluster failed: %v", err)
 	}
 
-	if len(clusters["login"]) != 2 {
-		t.Errorf("Expected 2 nodes in 'login' cluster, got %d", len(clusters["login"]))
+	if len(clusters["internal/auth/login"]) != 2 {
+		t.Errorf("Expected 2 nodes in 'internal/auth/login' cluster, got %d", len(clusters["internal/auth/login"]))
 	}
-	if len(clusters["session"]) != 1 {
-		t.Errorf("Expected 1 node in 'session' cluster, got %d", len(clusters["session"]))
+	if len(clusters["internal/auth/session"]) != 1 {
+		t.Errorf("Expected 1 node in 'internal/auth/session' cluster, got %d", len(clusters["internal/auth/session"]))
+	}
+	// Same file name in another directory is a separate cluster
+	if len(clusters["internal/auth/admin/login"]) != 1 {
+		t.Errorf("Expected 1 node in 'internal/auth/admin/login' cluster, got %d", len(clusters["internal/auth/admin/login"]))
 	}
 	// Note: 'no_path' is a valid filename, so it will cluster as 'no_path'
 	if len(clusters["no_path"]) != 1 {
@@ -11,6 +11,7 @@ type DirectoryDomainDiscoverer struct {
 
 func (d *DirectoryDomainDiscoverer) DiscoverDomains(rootPath string) (map[string]string, error) {
 	domains := make(map[string]string)
+	scopes := make(map[string][]string) // Directory name -> paths with that name
 
 	// Always include the root as a fallback if no subdomains are found
 	// domains["root"] = ""
@@ -28,11 +29,23 @@ func (d *DirectoryDomainDiscoverer) DiscoverDomains(rootPath string) (map[string
 			if entry.IsDir() {
 				name := entry.Name()
 				// Store path relative to rootPath
-				domains[name] = filepath.Join(base, name)
+				scopes[name] = append(scopes[name], filepath.Join(base, name))
 			}
 		}
 	}
 
+	// Directories with the same name under different bases (internal/util
+	// and pkg/util) are named by their paths instead of overwriting each other
+	for name, paths := range scopes {
+		if len(paths) == 1 {
+			domains[name] = paths[0]
+			continue
+		}
+		for _, p := range paths {
+			domains[filepath.ToSlash(p)] = p
+		}
+	}
+
 	// If no domains found, fall back to root
 	if len(domains) == 0 {
 		domains["root"] = ""
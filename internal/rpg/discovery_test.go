@@ -55,3 +55,33 @@ func TestDirectoryDomainDiscoverer_Fallback(t *testing.T) {
 		t.Errorf("Expected fallback to root, got %v", domains)
 	}
 }
+
+func TestDirectoryDomainDiscoverer_SameName(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "internal", "util"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "pkg", "util"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "pkg", "auth"), 0755)
+
+	discoverer := &DirectoryDomainDiscoverer{
+		BaseDirs: []string{"internal", "pkg"},
+	}
+
+	domains, err := discoverer.DiscoverDomains(tmpDir)
+	if err != nil {
+		t.Fatalf("DiscoverDomains failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"internal/util": filepath.Join("internal", "util"),
+		"pkg/util":      filepath.Join("pkg", "util"),
+		"auth":          filepath.Join("pkg", "auth"),
+	}
+	if len(domains) != len(expected) {
+		t.Errorf("Expected %d domains, got %v", len(expected), domains)
+	}
+	for k, v := range expected {
+		if domains[k] != v {
+			t.Errorf("Domain %s: expected path %s, got %s", k, v, domains[k])
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"graphdb/internal/embedding"
 	"graphdb/internal/graph"
+	"graphdb/internal/policy"
 	"strings"
 
 	"google.golang.org/genai"
@@ -56,7 +57,7 @@ func (e *Enricher) Enrich(feature *Feature, functions []graph.Node) error {
 
 	// Generate embedding from the description
 	if e.Embedder != nil && desc != "" {
-		embeddings, err := e.Embedder.EmbedBatch([]string{desc})
+		embeddings, err := e.Embedder.EmbedBatch([]string{desc}, embedding.PurposeDocument)
 		if err != nil {
 			return fmt.Errorf("embedding generation failed: %w", err)
 		}
@@ -71,6 +72,7 @@ func (e *Enricher) Enrich(feature *Feature, functions []graph.Node) error {
 type VertexSummarizer struct {
 	Client *genai.Client
 	Model  string
+	Policy *policy.Policy
 }
 
 func NewVertexSummarizer(ctx context.Context, projectID, location string) (*VertexSummarizer, error) {
@@ -86,6 +88,7 @@ func NewVertexSummarizer(ctx context.Context, projectID, location string) (*Vert
 	return &VertexSummarizer{
 		Client: client,
 		Model:  "gemini-1.5-flash-002",
+		Policy: policy.Default(),
 	}, nil
 }
 
@@ -94,7 +97,17 @@ func (s *VertexSummarizer) Summarize(snippets []string) (string, string, error)
 		return "Unknown Feature", "No code snippets provided for analysis.", nil
 	}
 
-	prompt := fmt.Sprintf(`You are a technical architect. Below are code snippets from a group of functions. 
+	text, err := generateText(s.Client, s.Policy, s.Model, summaryPrompt(snippets))
+	if err != nil {
+		return "", "", err
+	}
+
+	return parseSummary(text)
+}
+
+// summaryPrompt asks a model to name and describe a group of functions.
+func summaryPrompt(snippets []string) string {
+	return fmt.Sprintf(`You are a technical architect. Below are code snippets from a group of functions. 
 Your task is to:
 1. Provide a concise, professional name for this "Feature" (e.g., "User Authentication", "Database Migration Service").
 2. Provide a 1-2 sentence description of what this feature does.
@@ -104,29 +117,12 @@ Return your response in JSON format ONLY:
 
 Code Snippets:
 %s`, strings.Join(snippets, "\n---\n"))
+}
 
-	ctx := context.Background()
-	
-	resp, err := s.Client.Models.GenerateContent(ctx, s.Model, genai.Text(prompt), nil)
-	if err != nil {
-		return "", "", fmt.Errorf("generate content failed: %w", err)
-	}
-
-	if resp == nil || len(resp.Candidates) == 0 {
-		return "", "", fmt.Errorf("no candidates returned from Vertex AI")
-	}
-	
-	// Check content parts
-	cand := resp.Candidates[0]
-	if cand.Content == nil || len(cand.Content.Parts) == 0 {
-		return "", "", fmt.Errorf("empty content in response")
-	}
-
-	responseText := cand.Content.Parts[0].Text
+// parseSummary decodes the JSON object answer to summaryPrompt.
+func parseSummary(responseText string) (string, string, error) {
 	// Strip markdown blocks if present
-	responseText = strings.TrimPrefix(responseText, "```json")
-	responseText = strings.TrimSuffix(responseText, "```")
-	responseText = strings.TrimSpace(responseText)
+	responseText = stripCodeFence(responseText)
 
 	var summary struct {
 		Name        string `json:"name"`
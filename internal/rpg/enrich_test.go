@@ -1,6 +1,7 @@
 package rpg
 
 import (
+	"graphdb/internal/embedding"
 	"graphdb/internal/graph"
 	"testing"
 )
@@ -13,7 +14,7 @@ func (m *MockSummarizer) Summarize(snippets []string) (string, string, error) {
 
 type MockEmbedder struct{}
 
-func (m *MockEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
+func (m *MockEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
 	res := make([][]float32, len(texts))
 	for i := range texts {
 		res[i] = make([]float32, 768)
@@ -0,0 +1,97 @@
+package rpg
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ExtractionPool extracts the atomic features of many functions, BatchSize
+// functions per prompt, across Workers concurrent prompts. Functions a batch
+// does not answer, or all of them if the batch fails, are retried one by one.
+type ExtractionPool struct {
+	Extractor FeatureExtractor
+	BatchSize int                   // Functions per prompt; 1 or less extracts them one by one
+	Workers   int                   // Concurrent prompts; at least 1
+	Progress  func(done, total int) // Called after each batch, from one goroutine at a time; optional
+}
+
+// Run extracts the features of functions. It returns the descriptors of each
+// function that succeeded and the error of each that did not, keyed by ID.
+func (p *ExtractionPool) Run(functions []FunctionSource) (map[string][]string, map[string]error) {
+	batchSize := max(p.BatchSize, 1)
+	workers := max(p.Workers, 1)
+
+	batches := make(chan []FunctionSource)
+	go func() {
+		defer close(batches)
+		for i := 0; i < len(functions); i += batchSize {
+			batches <- functions[i:min(i+batchSize, len(functions))]
+		}
+	}()
+
+	features := make(map[string][]string, len(functions))
+	failures := make(map[string]error)
+	var mu sync.Mutex
+	done := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				answered, failed := p.extract(batch)
+
+				mu.Lock()
+				for id, descriptors := range answered {
+					features[id] = descriptors
+				}
+				for id, err := range failed {
+					failures[id] = err
+				}
+				done += len(batch)
+				if p.Progress != nil {
+					p.Progress(done, len(functions))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return features, failures
+}
+
+// extract runs one batch, falling back to Extract for the functions it
+// leaves unanswered.
+func (p *ExtractionPool) extract(batch []FunctionSource) (map[string][]string, map[string]error) {
+	answered := make(map[string][]string, len(batch))
+	failed := make(map[string]error)
+
+	var batchErr error
+	if len(batch) > 1 {
+		result, err := p.Extractor.ExtractBatch(batch)
+		if err != nil {
+			batchErr = err
+		}
+		for id, descriptors := range result {
+			answered[id] = descriptors
+		}
+	}
+
+	for _, fn := range batch {
+		if _, ok := answered[fn.ID]; ok {
+			continue
+		}
+		descriptors, err := p.Extractor.Extract(fn.Code, fn.Name)
+		if err != nil {
+			if batchErr != nil {
+				err = fmt.Errorf("%w (batch: %v)", err, batchErr)
+			}
+			failed[fn.ID] = err
+			continue
+		}
+		answered[fn.ID] = descriptors
+	}
+	return answered, failed
+}
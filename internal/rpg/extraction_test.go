@@ -0,0 +1,156 @@
+package rpg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// flakyExtractor answers batches except for functions named "skip*", fails
+// batches containing "boom*", and fails Extract for "bad*".
+type flakyExtractor struct {
+	mu       sync.Mutex
+	batches  [][]string
+	singles  []string
+	inFlight int
+	peak     int
+}
+
+func (f *flakyExtractor) enter() {
+	f.mu.Lock()
+	f.inFlight++
+	f.peak = max(f.peak, f.inFlight)
+	f.mu.Unlock()
+}
+
+func (f *flakyExtractor) leave() {
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+}
+
+func (f *flakyExtractor) Extract(code string, functionName string) ([]string, error) {
+	f.enter()
+	defer f.leave()
+	f.mu.Lock()
+	f.singles = append(f.singles, functionName)
+	f.mu.Unlock()
+	if strings.HasPrefix(functionName, "bad") {
+		return nil, errors.New("quota exceeded")
+	}
+	return []string{"single " + functionName}, nil
+}
+
+func (f *flakyExtractor) ExtractBatch(functions []FunctionSource) (map[string][]string, error) {
+	f.enter()
+	defer f.leave()
+	var names []string
+	for _, fn := range functions {
+		names = append(names, fn.Name)
+	}
+	f.mu.Lock()
+	f.batches = append(f.batches, names)
+	f.mu.Unlock()
+
+	result := make(map[string][]string)
+	for _, fn := range functions {
+		if strings.HasPrefix(fn.Name, "boom") {
+			return nil, errors.New("malformed response")
+		}
+		if !strings.HasPrefix(fn.Name, "skip") {
+			result[fn.ID] = []string{"batch " + fn.Name}
+		}
+	}
+	return result, nil
+}
+
+func TestExtractionPool_Run(t *testing.T) {
+	names := []string{"a", "skip1", "c", "d", "boom", "bad1", "g"}
+	var functions []FunctionSource
+	for _, name := range names {
+		functions = append(functions, FunctionSource{ID: "id:" + name, Name: name, Code: "func " + name + "() {}"})
+	}
+
+	extractor := &flakyExtractor{}
+	var progress []int
+	pool := &ExtractionPool{
+		Extractor: extractor,
+		BatchSize: 3,
+		Workers:   2,
+		Progress:  func(done, total int) { progress = append(progress, done) },
+	}
+	features, failures := pool.Run(functions)
+
+	want := map[string]string{
+		"id:a":     "batch a",
+		"id:skip1": "single skip1", // Unanswered by its batch
+		"id:c":     "batch c",
+		"id:d":     "single d", // Its batch failed
+		"id:boom":  "single boom",
+		"id:g":     "single g", // A batch of one is not worth the batch prompt
+	}
+	for id, descriptor := range want {
+		if got := features[id]; len(got) != 1 || got[0] != descriptor {
+			t.Errorf("%s: expected %q, got %v", id, descriptor, got)
+		}
+	}
+	if len(failures) != 1 || failures["id:bad1"] == nil {
+		t.Fatalf("Expected only bad1 to fail, got %v", failures)
+	}
+	if msg := failures["id:bad1"].Error(); !strings.Contains(msg, "quota exceeded") || !strings.Contains(msg, "malformed response") {
+		t.Errorf("Expected both the single and the batch error, got %q", msg)
+	}
+
+	sort.Strings(extractor.singles)
+	if fmt.Sprint(extractor.singles) != "[bad1 boom d g skip1]" {
+		t.Errorf("Expected only unanswered functions to be retried, got %v", extractor.singles)
+	}
+	if len(extractor.batches) != 2 {
+		t.Errorf("Expected 2 batch prompts (the last batch has one function), got %v", extractor.batches)
+	}
+	if extractor.peak > 2 {
+		t.Errorf("Expected at most 2 concurrent prompts, saw %d", extractor.peak)
+	}
+	if len(progress) != 3 || progress[2] != len(functions) {
+		t.Errorf("Expected progress after each of 3 batches, got %v", progress)
+	}
+}
+
+func TestParseBatchFeatures(t *testing.T) {
+	functions := []FunctionSource{{ID: "a.go:Login"}, {ID: "a.go:Logout"}, {ID: "a.go:Register"}}
+	reply := "```json\n{\"a.go:Login\": [\"validate credentials\"], \"a.go:Logout\": \"end session\", \"other\": [\"x\"]}\n```"
+
+	result, err := parseBatchFeatures(reply, functions)
+	if err != nil {
+		t.Fatalf("parseBatchFeatures failed: %v", err)
+	}
+	if len(result) != 1 || result["a.go:Login"][0] != "validate credentials" {
+		t.Errorf("Expected only the well-formed answer for a known ID, got %v", result)
+	}
+
+	if _, err := parseBatchFeatures(`["validate credentials"]`, functions); err == nil {
+		t.Error("Expected error for a JSON array")
+	}
+}
+
+func TestExtractBatch_SkipsEmptyCode(t *testing.T) {
+	var prompts []string
+	generate := func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return `{"f1": ["send email"]}`, nil
+	}
+
+	result, err := extractBatch([]FunctionSource{{ID: "f1", Name: "send", Code: "def send(): pass"}, {ID: "f2", Name: "empty"}}, generate)
+	if err != nil {
+		t.Fatalf("extractBatch failed: %v", err)
+	}
+	if _, ok := result["f2"]; !ok || result["f1"][0] != "send email" {
+		t.Errorf("Unexpected result %v", result)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "Function ID: f1") || strings.Contains(prompts[0], "Function ID: f2") {
+		t.Errorf("Expected a prompt for f1 only, got %v", prompts)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"graphdb/internal/policy"
 	"strings"
 
 	"google.golang.org/genai"
@@ -13,6 +14,17 @@ import (
 // Each descriptor is a Verb-Object pair (e.g., "validate email", "hash password").
 type FeatureExtractor interface {
 	Extract(code string, functionName string) ([]string, error)
+	// ExtractBatch extracts the descriptors of several functions with one
+	// prompt, keyed by function ID. Functions missing from the result were not
+	// answered and can be retried with Extract.
+	ExtractBatch(functions []FunctionSource) (map[string][]string, error)
+}
+
+// FunctionSource is a function to extract features from.
+type FunctionSource struct {
+	ID   string
+	Name string
+	Code string
 }
 
 // LLMFeatureExtractor uses a Vertex AI / Gemini model to extract
@@ -20,6 +32,7 @@ type FeatureExtractor interface {
 type LLMFeatureExtractor struct {
 	Client *genai.Client
 	Model  string
+	Policy *policy.Policy
 }
 
 // NewLLMFeatureExtractor creates an LLMFeatureExtractor with defaults.
@@ -36,6 +49,7 @@ func NewLLMFeatureExtractor(ctx context.Context, projectID, location string) (*L
 	return &LLMFeatureExtractor{
 		Client: client,
 		Model:  "gemini-1.5-flash-002",
+		Policy: policy.Default(),
 	}, nil
 }
 
@@ -44,45 +58,91 @@ func (e *LLMFeatureExtractor) Extract(code string, functionName string) ([]strin
 		return nil, nil
 	}
 
-	// Truncate very long functions to stay within context limits
-	if len(code) > 4000 {
-		code = code[:4000] + "\n// ... truncated"
+	text, err := generateText(e.Client, e.Policy, e.Model, featurePrompt(code, functionName))
+	if err != nil {
+		return nil, err
 	}
 
-	prompt := "You are analyzing source code to extract atomic feature descriptors.\n\n" +
-		"For the function below, generate a list of Verb-Object descriptors that capture what this function does.\n" +
-		"Each descriptor should be a concise action phrase like \"validate email\", \"hash password\", \"send notification\".\n\n" +
-		"Rules:\n" +
-		"- Use lowercase\n" +
-		"- Each descriptor should be 2-4 words: a verb followed by the object/target\n" +
-		"- Generate 1-5 descriptors depending on function complexity\n" +
-		"- Focus on the function's purpose, not implementation details\n" +
-		"- Normalize similar concepts (e.g., \"check\" and \"validate\" -> pick one)\n\n" +
-		"Return ONLY a JSON array of strings:\n" +
-		"[\"descriptor1\", \"descriptor2\"]\n\n" +
-		fmt.Sprintf("Function name: %s\n\n%s", functionName, code)
+	return parseFeatures(text)
+}
+
+func (e *LLMFeatureExtractor) ExtractBatch(functions []FunctionSource) (map[string][]string, error) {
+	return extractBatch(functions, func(prompt string) (string, error) {
+		return generateText(e.Client, e.Policy, e.Model, prompt)
+	})
+}
 
-	ctx := context.Background()
-	
-	resp, err := e.Client.Models.GenerateContent(ctx, e.Model, genai.Text(prompt), nil)
+// generateText sends prompt to a Gemini model under the policy and returns
+// the text of the first candidate.
+func generateText(client *genai.Client, p *policy.Policy, model, prompt string) (string, error) {
+	var resp *genai.GenerateContentResponse
+	err := p.Do(context.Background(), policy.EstimateTokens(prompt), func(ctx context.Context) error {
+		var err error
+		resp, err = client.Models.GenerateContent(ctx, model, genai.Text(prompt), nil)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("generate content failed: %w", err)
+		return "", fmt.Errorf("generate content failed: %w", err)
 	}
 
 	if resp == nil || len(resp.Candidates) == 0 {
-		return nil, fmt.Errorf("no candidates returned from Vertex AI")
+		return "", fmt.Errorf("no candidates returned from Vertex AI")
 	}
 
 	// Check content parts
 	cand := resp.Candidates[0]
 	if cand.Content == nil || len(cand.Content.Parts) == 0 {
-		return nil, fmt.Errorf("empty content in response")
+		return "", fmt.Errorf("empty content in response")
 	}
 
-	responseText := cand.Content.Parts[0].Text
-	responseText = strings.TrimPrefix(responseText, "```json")
-	responseText = strings.TrimSuffix(responseText, "```")
-	responseText = strings.TrimSpace(responseText)
+	return cand.Content.Parts[0].Text, nil
+}
+
+// featureRules describe good descriptors to the model.
+const featureRules = "Each descriptor should be a concise action phrase like \"validate email\", \"hash password\", \"send notification\".\n\n" +
+	"Rules:\n" +
+	"- Use lowercase\n" +
+	"- Each descriptor should be 2-4 words: a verb followed by the object/target\n" +
+	"- Generate 1-5 descriptors depending on function complexity\n" +
+	"- Focus on the function's purpose, not implementation details\n" +
+	"- Normalize similar concepts (e.g., \"check\" and \"validate\" -> pick one)\n\n"
+
+// truncateCode shortens very long functions to stay within context limits.
+func truncateCode(code string) string {
+	if len(code) > 4000 {
+		return code[:4000] + "\n// ... truncated"
+	}
+	return code
+}
+
+// featurePrompt asks a model for the Verb-Object descriptors of a function.
+func featurePrompt(code string, functionName string) string {
+	return "You are analyzing source code to extract atomic feature descriptors.\n\n" +
+		"For the function below, generate a list of Verb-Object descriptors that capture what this function does.\n" +
+		featureRules +
+		"Return ONLY a JSON array of strings:\n" +
+		"[\"descriptor1\", \"descriptor2\"]\n\n" +
+		fmt.Sprintf("Function name: %s\n\n%s", functionName, truncateCode(code))
+}
+
+// batchFeaturePrompt asks a model for the descriptors of several functions,
+// keyed by function ID.
+func batchFeaturePrompt(functions []FunctionSource) string {
+	var sb strings.Builder
+	sb.WriteString("You are analyzing source code to extract atomic feature descriptors.\n\n")
+	sb.WriteString("For each function below, generate a list of Verb-Object descriptors that capture what the function does.\n")
+	sb.WriteString(featureRules)
+	sb.WriteString("Return ONLY a JSON object mapping each function ID to its JSON array of descriptors, with every function ID below:\n")
+	sb.WriteString("{\"<function ID>\": [\"descriptor1\", \"descriptor2\"]}\n")
+	for _, fn := range functions {
+		fmt.Fprintf(&sb, "\n=== Function ID: %s\nFunction name: %s\n\n%s\n", fn.ID, fn.Name, truncateCode(fn.Code))
+	}
+	return sb.String()
+}
+
+// parseFeatures decodes the JSON array answer to featurePrompt.
+func parseFeatures(responseText string) ([]string, error) {
+	responseText = stripCodeFence(responseText)
 
 	var descriptors []string
 	if err := json.Unmarshal([]byte(responseText), &descriptors); err != nil {
@@ -92,9 +152,77 @@ func (e *LLMFeatureExtractor) Extract(code string, functionName string) ([]strin
 	return descriptors, nil
 }
 
+// parseBatchFeatures decodes the JSON object answer to batchFeaturePrompt.
+// Entries for unknown IDs and malformed entries are dropped, so those
+// functions count as unanswered.
+func parseBatchFeatures(responseText string, functions []FunctionSource) (map[string][]string, error) {
+	responseText = stripCodeFence(responseText)
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(responseText), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response as JSON object: %v. Raw: %s", err, responseText)
+	}
+
+	result := make(map[string][]string, len(functions))
+	for _, fn := range functions {
+		var descriptors []string
+		if entry, ok := raw[fn.ID]; ok && json.Unmarshal(entry, &descriptors) == nil {
+			result[fn.ID] = descriptors
+		}
+	}
+	return result, nil
+}
+
+// extractBatch sends one batchFeaturePrompt for the functions with code to
+// generate. Functions without code get no descriptors.
+func extractBatch(functions []FunctionSource, generate func(prompt string) (string, error)) (map[string][]string, error) {
+	result := make(map[string][]string, len(functions))
+	var withCode []FunctionSource
+	for _, fn := range functions {
+		if fn.Code == "" {
+			result[fn.ID] = nil
+		} else {
+			withCode = append(withCode, fn)
+		}
+	}
+	if len(withCode) == 0 {
+		return result, nil
+	}
+
+	text, err := generate(batchFeaturePrompt(withCode))
+	if err != nil {
+		return nil, err
+	}
+	answered, err := parseBatchFeatures(text, withCode)
+	if err != nil {
+		return nil, err
+	}
+	for id, descriptors := range answered {
+		result[id] = descriptors
+	}
+	return result, nil
+}
+
+// stripCodeFence removes a ```json markdown block around a model's answer.
+func stripCodeFence(responseText string) string {
+	responseText = strings.TrimSpace(responseText)
+	responseText = strings.TrimPrefix(responseText, "```json")
+	responseText = strings.TrimPrefix(responseText, "```")
+	responseText = strings.TrimSuffix(responseText, "```")
+	return strings.TrimSpace(responseText)
+}
+
 // MockFeatureExtractor returns fixed descriptors for testing.
 type MockFeatureExtractor struct{}
 
 func (m *MockFeatureExtractor) Extract(code string, functionName string) ([]string, error) {
 	return []string{"process data", "validate input"}, nil
 }
+
+func (m *MockFeatureExtractor) ExtractBatch(functions []FunctionSource) (map[string][]string, error) {
+	result := make(map[string][]string, len(functions))
+	for _, fn := range functions {
+		result[fn.ID], _ = m.Extract(fn.Code, fn.Name)
+	}
+	return result, nil
+}
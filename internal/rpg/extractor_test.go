@@ -41,4 +41,5 @@ func TestFeatureExtractorInterface(t *testing.T) {
 	// Verify both implementations satisfy the interface
 	var _ FeatureExtractor = &MockFeatureExtractor{}
 	var _ FeatureExtractor = &LLMFeatureExtractor{}
+	var _ FeatureExtractor = &OpenAIFeatureExtractor{}
 }
@@ -0,0 +1,79 @@
+package rpg
+
+import (
+	"context"
+	"fmt"
+	"graphdb/internal/openai"
+	"graphdb/internal/policy"
+)
+
+// OpenAISummarizer names and describes features with a chat model behind an
+// OpenAI-compatible /v1/chat/completions API.
+type OpenAISummarizer struct {
+	Client *openai.Client
+	Model  string
+	Policy *policy.Policy
+}
+
+// NewOpenAISummarizer creates an OpenAISummarizer for the API at baseURL.
+func NewOpenAISummarizer(baseURL, apiKey, model string) *OpenAISummarizer {
+	return &OpenAISummarizer{Client: openai.NewClient(baseURL, apiKey), Model: model, Policy: policy.Default()}
+}
+
+func (s *OpenAISummarizer) Summarize(snippets []string) (string, string, error) {
+	if len(snippets) == 0 {
+		return "Unknown Feature", "No code snippets provided for analysis.", nil
+	}
+
+	reply, err := complete(s.Client, s.Policy, s.Model, summaryPrompt(snippets))
+	if err != nil {
+		return "", "", fmt.Errorf("chat completion failed: %w", err)
+	}
+	return parseSummary(reply)
+}
+
+// OpenAIFeatureExtractor extracts atomic Verb-Object feature descriptors with
+// a chat model behind an OpenAI-compatible /v1/chat/completions API.
+type OpenAIFeatureExtractor struct {
+	Client *openai.Client
+	Model  string
+	Policy *policy.Policy
+}
+
+// NewOpenAIFeatureExtractor creates an OpenAIFeatureExtractor for the API at baseURL.
+func NewOpenAIFeatureExtractor(baseURL, apiKey, model string) *OpenAIFeatureExtractor {
+	return &OpenAIFeatureExtractor{Client: openai.NewClient(baseURL, apiKey), Model: model, Policy: policy.Default()}
+}
+
+func (e *OpenAIFeatureExtractor) Extract(code string, functionName string) ([]string, error) {
+	if code == "" {
+		return nil, nil
+	}
+
+	reply, err := complete(e.Client, e.Policy, e.Model, featurePrompt(code, functionName))
+	if err != nil {
+		return nil, fmt.Errorf("chat completion failed: %w", err)
+	}
+	return parseFeatures(reply)
+}
+
+func (e *OpenAIFeatureExtractor) ExtractBatch(functions []FunctionSource) (map[string][]string, error) {
+	return extractBatch(functions, func(prompt string) (string, error) {
+		reply, err := complete(e.Client, e.Policy, e.Model, prompt)
+		if err != nil {
+			return "", fmt.Errorf("chat completion failed: %w", err)
+		}
+		return reply, nil
+	})
+}
+
+// complete sends prompt to the chat model under the policy.
+func complete(client *openai.Client, p *policy.Policy, model, prompt string) (string, error) {
+	var reply string
+	err := p.Do(context.Background(), policy.EstimateTokens(prompt), func(ctx context.Context) error {
+		var err error
+		reply, err = client.Complete(ctx, model, prompt)
+		return err
+	})
+	return reply, err
+}
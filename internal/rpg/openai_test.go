@@ -0,0 +1,92 @@
+package rpg
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chatServer answers every chat completion with reply and records the prompts.
+func chatServer(t *testing.T, reply string, prompts *[]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		*prompts = append(*prompts, req.Messages[0].Content)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAISummarizer_Summarize(t *testing.T) {
+	var prompts []string
+	server := chatServer(t, "```json\n{\"name\": \"User Authentication\", \"description\": \"Logs users in.\"}\n```", &prompts)
+	summarizer := NewOpenAISummarizer(server.URL+"/v1", "", "llama3")
+
+	name, desc, err := summarizer.Summarize([]string{"func login() {}", "func logout() {}"})
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if name != "User Authentication" || desc != "Logs users in." {
+		t.Errorf("Unexpected summary %q, %q", name, desc)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "func login() {}\n---\nfunc logout() {}") {
+		t.Errorf("Expected snippets in the prompt, got %v", prompts)
+	}
+
+	// No snippets: no request
+	if name, _, _ := summarizer.Summarize(nil); name != "Unknown Feature" || len(prompts) != 1 {
+		t.Errorf("Expected the fallback summary without a request")
+	}
+}
+
+func TestOpenAIFeatureExtractor_Extract(t *testing.T) {
+	var prompts []string
+	server := chatServer(t, `["validate email", "hash password"]`, &prompts)
+	extractor := NewOpenAIFeatureExtractor(server.URL+"/v1", "", "llama3")
+
+	descriptors, err := extractor.Extract("func register(email, pw string) {}", "register")
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if len(descriptors) != 2 || descriptors[0] != "validate email" {
+		t.Errorf("Unexpected descriptors %v", descriptors)
+	}
+	if !strings.Contains(prompts[0], "Function name: register") {
+		t.Errorf("Expected the function in the prompt, got %q", prompts[0])
+	}
+
+	bad := NewOpenAIFeatureExtractor(chatServer(t, "I cannot help with that", &prompts).URL+"/v1", "", "llama3")
+	if _, err := bad.Extract("func f() {}", "f"); err == nil {
+		t.Error("Expected error for a non-JSON reply")
+	}
+}
+
+func TestOpenAIFeatureExtractor_ExtractBatch(t *testing.T) {
+	var prompts []string
+	server := chatServer(t, `{"auth.go:Login": ["check password"], "auth.go:Logout": ["clear session"]}`, &prompts)
+	extractor := NewOpenAIFeatureExtractor(server.URL+"/v1", "", "llama3")
+
+	result, err := extractor.ExtractBatch([]FunctionSource{
+		{ID: "auth.go:Login", Name: "Login", Code: "func Login() {}"},
+		{ID: "auth.go:Logout", Name: "Logout", Code: "func Logout() {}"},
+	})
+	if err != nil {
+		t.Fatalf("ExtractBatch failed: %v", err)
+	}
+	if len(prompts) != 1 || len(result) != 2 || result["auth.go:Logout"][0] != "clear session" {
+		t.Errorf("Expected both functions from one prompt, got %v after %d prompts", result, len(prompts))
+	}
+}
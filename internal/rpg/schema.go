@@ -46,3 +46,32 @@ func Flatten(features []Feature, edges []graph.Edge) ([]graph.Node, []graph.Edge
 
 	return nodes, allEdges
 }
+
+// FunctionUpdates returns the properties enrichment adds to functions, as
+// partial nodes to merge into the functions already in the graph: their
+// atomic_features and the feature_id of the feature they IMPLEMENT.
+// Functions without either are left out.
+func FunctionUpdates(functions []graph.Node, edges []graph.Edge) []graph.Node {
+	featureOf := make(map[string]string)
+	for _, e := range edges {
+		if e.Type == "IMPLEMENTS" {
+			featureOf[e.SourceID] = e.TargetID
+		}
+	}
+
+	var updates []graph.Node
+	for _, fn := range functions {
+		props := make(map[string]interface{})
+		if features, ok := fn.Properties["atomic_features"].([]string); ok && len(features) > 0 {
+			props["atomic_features"] = features
+		}
+		if featureID, ok := featureOf[fn.ID]; ok {
+			props["feature_id"] = featureID
+		}
+		if len(props) == 0 {
+			continue
+		}
+		updates = append(updates, graph.Node{ID: fn.ID, Label: fn.Label, Properties: props})
+	}
+	return updates
+}
@@ -46,3 +46,35 @@ func TestFeature_ToNode(t *testing.T) {
 		t.Errorf("expected embedding %v, got %v", embedding, emb)
 	}
 }
+
+func TestFunctionUpdates(t *testing.T) {
+	functions := []graph.Node{
+		{ID: "auth.go:Login", Label: "Function", Properties: map[string]interface{}{
+			"name": "Login", "content": "func Login() {}", "atomic_features": []string{"validate credentials"},
+		}},
+		{ID: "auth.go:helper", Label: "Function", Properties: map[string]interface{}{"name": "helper"}},
+		{ID: "auth.go:orphan", Label: "Function", Properties: map[string]interface{}{"name": "orphan"}},
+	}
+	edges := []graph.Edge{
+		{SourceID: "domain-auth", TargetID: "feat-login", Type: "PARENT_OF"},
+		{SourceID: "auth.go:Login", TargetID: "feat-login", Type: "IMPLEMENTS"},
+		{SourceID: "auth.go:helper", TargetID: "feat-login", Type: "IMPLEMENTS"},
+	}
+
+	updates := FunctionUpdates(functions, edges)
+	if len(updates) != 2 {
+		t.Fatalf("expected updates for the 2 enriched functions, got %+v", updates)
+	}
+
+	login := updates[0]
+	if login.ID != "auth.go:Login" || login.Label != "Function" {
+		t.Errorf("expected the Login function, got %+v", login)
+	}
+	want := map[string]interface{}{"atomic_features": []string{"validate credentials"}, "feature_id": "feat-login"}
+	if !reflect.DeepEqual(login.Properties, want) {
+		t.Errorf("expected only the enrichment properties, got %v", login.Properties)
+	}
+	if _, ok := updates[1].Properties["atomic_features"]; ok || updates[1].Properties["feature_id"] != "feat-login" {
+		t.Errorf("expected only a feature_id for helper, got %v", updates[1].Properties)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the request duration
+// histogram.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// metrics counts requests per endpoint and writes them in the Prometheus text
+// exposition format.
+type metrics struct {
+	mu        sync.Mutex
+	inFlight  int
+	requests  map[requestKey]int
+	durations map[string]*histogram
+}
+
+type requestKey struct {
+	endpoint string
+	code     int
+}
+
+type histogram struct {
+	counts []int // Per bucket, not cumulative
+	sum    float64
+	count  int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  make(map[requestKey]int),
+		durations: make(map[string]*histogram),
+	}
+}
+
+func (m *metrics) start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight++
+}
+
+func (m *metrics) done(endpoint string, code int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight--
+	m.requests[requestKey{endpoint, code}]++
+
+	h, ok := m.durations[endpoint]
+	if !ok {
+		h = &histogram{counts: make([]int, len(durationBuckets))}
+		m.durations[endpoint] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP graphdb_http_requests_in_flight Requests being served.")
+	fmt.Fprintln(w, "# TYPE graphdb_http_requests_in_flight gauge")
+	fmt.Fprintf(w, "graphdb_http_requests_in_flight %d\n", m.inFlight)
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].endpoint != keys[j].endpoint {
+			return keys[i].endpoint < keys[j].endpoint
+		}
+		return keys[i].code < keys[j].code
+	})
+	fmt.Fprintln(w, "# HELP graphdb_http_requests_total Requests served, by endpoint and status code.")
+	fmt.Fprintln(w, "# TYPE graphdb_http_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "graphdb_http_requests_total{endpoint=%q,code=\"%d\"} %d\n", key.endpoint, key.code, m.requests[key])
+	}
+
+	endpoints := make([]string, 0, len(m.durations))
+	for endpoint := range m.durations {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	fmt.Fprintln(w, "# HELP graphdb_http_request_duration_seconds Time to serve a request, by endpoint.")
+	fmt.Fprintln(w, "# TYPE graphdb_http_request_duration_seconds histogram")
+	for _, endpoint := range endpoints {
+		h := m.durations[endpoint]
+		cumulative := 0
+		for i, bound := range durationBuckets {
+			cumulative += h.counts[i]
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(w, "graphdb_http_request_duration_seconds_bucket{endpoint=%q,le=%q} %d\n", endpoint, le, cumulative)
+		}
+		fmt.Fprintf(w, "graphdb_http_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %d\n", endpoint, h.count)
+		fmt.Fprintf(w, "graphdb_http_request_duration_seconds_sum{endpoint=%q} %g\n", endpoint, h.sum)
+		fmt.Fprintf(w, "graphdb_http_request_duration_seconds_count{endpoint=%q} %d\n", endpoint, h.count)
+	}
+}
@@ -0,0 +1,455 @@
+// Package server serves the graph queries over HTTP as JSON endpoints, sharing
+// one provider and embedder across requests.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"graphdb/internal/embedding"
+	"graphdb/internal/query"
+)
+
+// maxBodyBytes bounds the size of a request body.
+const maxBodyBytes = 1 << 20
+
+// Request is the JSON body of the query endpoints. Its fields mirror the flags
+// of "graphdb query"; each endpoint reads the ones it needs.
+type Request struct {
+	Target    string `json:"target,omitempty"`     // Node ID or name, or the search text
+	Target2   string `json:"target2,omitempty"`    // Second node (locate-usage)
+	File      string `json:"file,omitempty"`       // Narrows an ambiguous target to the node in this file (path suffix)
+	Label     string `json:"label,omitempty"`      // Narrows an ambiguous target to the node with this label
+	Depth     int    `json:"depth,omitempty"`      // Default 1, at most query.MaxDepth
+	Limit     int    `json:"limit,omitempty"`      // Default 10, at most query.MaxLimit
+	Module    string `json:"module,omitempty"`     // Module pattern for seams; default .*
+	GroupBy   string `json:"group_by,omitempty"`   // Group impact callers by file or feature
+	EdgeTypes string `json:"edge_types,omitempty"` // Comma-separated relationship types for traverse
+	Direction string `json:"direction,omitempty"`  // outgoing (default), incoming or both
+}
+
+// ErrorResponse is the body of every failed request. An ambiguous target
+// lists its candidates and an unknown one the closest names.
+type ErrorResponse struct {
+	Error       string             `json:"error"`
+	Candidates  []*query.Candidate `json:"candidates,omitempty"`
+	Suggestions []string           `json:"suggestions,omitempty"`
+}
+
+// queryFunc runs one query type against a provider bound to the request.
+type queryFunc func(ctx context.Context, p query.GraphProvider, req *Request) (any, error)
+
+// Server answers graph queries over HTTP. Its provider and embedder are
+// shared by all requests, so they must be safe for concurrent use.
+type Server struct {
+	provider query.GraphProvider
+	embedder embedding.Embedder
+	timeout  time.Duration
+	metrics  *metrics
+	mux      *http.ServeMux
+}
+
+// New returns a server answering from provider. embedder turns search text
+// into vectors; without one the search endpoints fail. Each query is cancelled
+// after timeout, or runs as long as its client waits if timeout is 0.
+func New(provider query.GraphProvider, embedder embedding.Embedder, timeout time.Duration) *Server {
+	s := &Server{
+		provider: provider,
+		embedder: embedder,
+		timeout:  timeout,
+		metrics:  newMetrics(),
+		mux:      http.NewServeMux(),
+	}
+
+	s.handleQuery("search-features", s.searchFeatures)
+	s.handleQuery("search-similar", s.searchSimilar)
+	s.handleQuery("hybrid-context", s.hybridContext)
+	s.handleQuery("neighbors", s.neighbors)
+	s.handleQuery("impact", s.impact)
+	s.handleQuery("globals", s.globals)
+	s.handleQuery("seams", s.seams)
+	s.handleQuery("locate-usage", s.locateUsage)
+	s.handleQuery("fetch-source", s.fetchSource)
+	s.handleQuery("explore-domain", s.exploreDomain)
+	s.handleQuery("traverse", s.traverse)
+	s.handleQuery("resolve", s.resolve)
+	s.handleQuery("status", s.status)
+
+	s.handle("GET /healthz", "healthz", http.HandlerFunc(s.healthz))
+	s.handle("GET /readyz", "readyz", http.HandlerFunc(s.readyz))
+	s.handle("GET /metrics", "metrics", http.HandlerFunc(s.serveMetrics))
+	return s
+}
+
+// ServeHTTP routes a request to its endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers an endpoint, counting its requests under name.
+func (s *Server) handle(pattern, name string, h http.Handler) {
+	s.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		s.metrics.start()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() { s.metrics.done(name, rec.status, time.Since(start)) }()
+		h.ServeHTTP(rec, r)
+	}))
+}
+
+// handleQuery registers "POST /v1/<name>", which decodes and checks a
+// Request, runs fn under the request context and the server timeout, and
+// answers with its result as JSON. A client that goes away cancels the query.
+func (s *Server) handleQuery(name string, fn queryFunc) {
+	s.handle("POST /v1/"+name, name, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		body := http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		if err := json.NewDecoder(body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, badRequest("invalid request body: %v", err))
+			return
+		}
+		if req.Depth > query.MaxDepth {
+			writeError(w, badRequest("depth %d is above the maximum of %d", req.Depth, query.MaxDepth))
+			return
+		}
+		if req.Limit > query.MaxLimit {
+			writeError(w, badRequest("limit %d is above the maximum of %d", req.Limit, query.MaxLimit))
+			return
+		}
+
+		ctx := r.Context()
+		if s.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+		}
+		result, err := s.run(ctx, func(ctx context.Context) (any, error) {
+			return fn(ctx, query.WithContext(ctx, s.provider), &req)
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}))
+}
+
+// run calls fn in its own goroutine and returns when it does or ctx is done,
+// whichever comes first. Providers that cannot be cancelled finish their
+// query in the background; the others stop with ctx.
+func (s *Server) run(ctx context.Context, fn func(ctx context.Context) (any, error)) (any, error) {
+	type outcome struct {
+		result any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := fn(ctx)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyz reports whether the graph backend answers, with the commit the graph
+// was built from.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	result, err := s.run(ctx, func(ctx context.Context) (any, error) {
+		return query.WithContext(ctx, s.provider).GetGraphState()
+	})
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready", "commit": result.(string)})
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.write(w)
+}
+
+// target resolves req.Target to a node ID; label applies unless req gives one.
+func target(p query.GraphProvider, req *Request, label string) (string, error) {
+	if req.Target == "" {
+		return "", badRequest("target is required")
+	}
+	if req.Label != "" {
+		label = req.Label
+	}
+	c, err := query.Resolve(p, req.Target, query.Qualifier{Label: label, File: req.File})
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// embed returns the vector of text, or ctx's error once ctx is done. The
+// embedder cannot be cancelled, so an abandoned call finishes in the background.
+func (s *Server) embed(ctx context.Context, text string, purpose embedding.Purpose) ([]float32, error) {
+	if text == "" {
+		return nil, badRequest("target is required")
+	}
+	if s.embedder == nil {
+		return nil, fmt.Errorf("search is unavailable: no embedder is configured")
+	}
+	result, err := s.run(ctx, func(ctx context.Context) (any, error) {
+		return s.embedder.EmbedBatch([]string{text}, purpose)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding failed: %w", err)
+	}
+	vectors := result.([][]float32)
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("embedding failed: no vector returned")
+	}
+	return vectors[0], nil
+}
+
+func (s *Server) searchFeatures(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	vector, err := s.embed(ctx, req.Target, embedding.PurposeQuery)
+	if err != nil {
+		return nil, err
+	}
+	return p.SearchFeatures(vector, orDefault(req.Limit, 10))
+}
+
+func (s *Server) searchSimilar(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	vector, err := s.embed(ctx, req.Target, embedding.PurposeCodeQuery)
+	if err != nil {
+		return nil, err
+	}
+	return p.SearchSimilarFunctions(vector, orDefault(req.Limit, 10))
+}
+
+// hybridContext combines the neighbors of the target with the functions
+// similar to its name. A failed search leaves the similar list empty.
+func (s *Server) hybridContext(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	id, err := target(p, req, "")
+	if err != nil {
+		return nil, err
+	}
+	neighbors, err := p.GetNeighbors(id, orDefault(req.Depth, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	var similar []*query.FeatureResult
+	if vector, err := s.embed(ctx, req.Target, embedding.PurposeCodeQuery); err != nil {
+		log.Printf("Warning: Embedding failed for hybrid search: %v", err)
+	} else {
+		similar, _ = p.SearchSimilarFunctions(vector, orDefault(req.Limit, 10))
+	}
+	return map[string]any{
+		"neighbors": neighbors,
+		"similar":   similar,
+	}, nil
+}
+
+func (s *Server) neighbors(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	id, err := target(p, req, "")
+	if err != nil {
+		return nil, err
+	}
+	return p.GetNeighbors(id, orDefault(req.Depth, 1))
+}
+
+func (s *Server) impact(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	id, err := target(p, req, "")
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.GetImpact(id, orDefault(req.Depth, 1))
+	if err != nil {
+		return nil, err
+	}
+	if req.GroupBy != "" {
+		if err := result.GroupBy(req.GroupBy); err != nil {
+			return nil, badRequest("%v", err)
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) globals(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	id, err := target(p, req, "")
+	if err != nil {
+		return nil, err
+	}
+	return p.GetGlobals(id)
+}
+
+func (s *Server) seams(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	module := req.Module
+	if module == "" {
+		module = ".*"
+	}
+	return p.GetSeams(module)
+}
+
+func (s *Server) locateUsage(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	if req.Target2 == "" {
+		return nil, badRequest("target and target2 are required")
+	}
+	source, err := target(p, req, "")
+	if err != nil {
+		return nil, err
+	}
+	dest, err := query.Resolve(p, req.Target2, query.Qualifier{})
+	if err != nil {
+		return nil, err
+	}
+	return p.LocateUsage(source, dest.ID)
+}
+
+func (s *Server) fetchSource(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	id, err := target(p, req, "")
+	if err != nil {
+		return nil, err
+	}
+	source, err := p.FetchSource(id)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"id": id, "source": source}, nil
+}
+
+func (s *Server) exploreDomain(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	id, err := target(p, req, "Feature")
+	if err != nil {
+		return nil, err
+	}
+	return p.ExploreDomain(id)
+}
+
+func (s *Server) traverse(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	id, err := target(p, req, "")
+	if err != nil {
+		return nil, err
+	}
+	direction := query.Outgoing
+	switch strings.ToLower(req.Direction) {
+	case "", "outgoing":
+	case "incoming":
+		direction = query.Incoming
+	case "both":
+		direction = query.Both
+	default:
+		return nil, badRequest("unknown direction %q: use outgoing, incoming or both", req.Direction)
+	}
+	return p.Traverse(id, req.EdgeTypes, direction, orDefault(req.Depth, 1))
+}
+
+// resolve lists every node the target may refer to, without picking one.
+func (s *Server) resolve(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	if req.Target == "" {
+		return nil, badRequest("target is required")
+	}
+	candidates, err := p.ResolveNode(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		suggestions, err := p.SuggestNodes(req.Target, 5)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &query.NotFoundError{Name: req.Target, Suggestions: suggestions}
+	}
+	return candidates, nil
+}
+
+func (s *Server) status(ctx context.Context, p query.GraphProvider, req *Request) (any, error) {
+	commit, err := p.GetGraphState()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"commit": commit}, nil
+}
+
+// requestError is a problem with the request itself rather than the graph.
+type requestError struct{ msg string }
+
+func (e *requestError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+	return &requestError{msg: fmt.Sprintf(format, args...)}
+}
+
+// writeError answers with the status matching err: 400 for a bad request,
+// 404 and 409 for an unknown or ambiguous target, 504 when the query timed
+// out and 500 otherwise.
+func writeError(w http.ResponseWriter, err error) {
+	resp := ErrorResponse{Error: err.Error()}
+	status := http.StatusInternalServerError
+
+	var reqErr *requestError
+	var ambiguous *query.AmbiguousError
+	var notFound *query.NotFoundError
+	switch {
+	case errors.As(err, &reqErr):
+		status = http.StatusBadRequest
+	case errors.As(err, &ambiguous):
+		status = http.StatusConflict
+		resp.Candidates = ambiguous.Candidates
+	case errors.As(err, &notFound):
+		status = http.StatusNotFound
+		resp.Suggestions = notFound.Suggestions
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+		resp.Error = "query timed out"
+	case errors.Is(err, context.Canceled):
+		// The client is gone; the status only shows in the metrics
+		status = 499
+	}
+	writeJSON(w, status, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// statusRecorder remembers the status written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func orDefault(n, fallback int) int {
+	if n <= 0 {
+		return fallback
+	}
+	return n
+}
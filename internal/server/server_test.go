@@ -0,0 +1,280 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"graphdb/internal/embedding"
+	"graphdb/internal/query"
+)
+
+// fixedEmbedder embeds every text as the same vector.
+type fixedEmbedder []float32
+
+func (e fixedEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = e
+	}
+	return vectors, nil
+}
+
+// testGraph loads a small graph:
+//
+//	Main -CALLS-> Handler -CALLS-> Save; util.Save is a second Save
+//	Handler -IMPLEMENTS-> feat-auth
+func testGraph(t *testing.T) query.GraphProvider {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app.ts")
+	if err := os.WriteFile(src, []byte("function Save() {\n  return 1;\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	records := []string{
+		`{"id": "app:Main", "type": "Function", "name": "Main", "file": "` + src + `", "start_line": 10}`,
+		`{"id": "app:Handler", "type": "Function", "name": "Handler", "file": "` + src + `", "start_line": 5}`,
+		`{"id": "app:Save", "type": "Function", "name": "Save", "file": "` + src + `", "start_line": 1, "end_line": 3}`,
+		`{"id": "util:Save", "type": "Function", "name": "Save", "file": "util.ts", "start_line": 7}`,
+		`{"id": "feat-auth", "type": "Feature", "name": "Auth", "embedding": [0, 1]}`,
+		`{"id": "feat-billing", "type": "Feature", "name": "Billing", "embedding": [1, 0]}`,
+		`{"id": "state", "type": "GraphState", "commit": "abc123"}`,
+		`{"source": "app:Main", "target": "app:Handler", "type": "CALLS"}`,
+		`{"source": "app:Handler", "target": "app:Save", "type": "CALLS"}`,
+		`{"source": "app:Handler", "target": "feat-auth", "type": "IMPLEMENTS"}`,
+	}
+	path := filepath.Join(dir, "graph.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(records, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := query.NewJSONLProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.Close() })
+	return provider
+}
+
+func startServer(t *testing.T, s *Server) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// post sends body to a query endpoint and decodes the response into out,
+// returning the status code.
+func post(t *testing.T, ts *httptest.Server, endpoint string, body any, out any) int {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(ts.URL+"/v1/"+endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Failed to decode the response of %s: %v", endpoint, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func get(t *testing.T, ts *httptest.Server, path string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestServer_Queries(t *testing.T) {
+	ts := startServer(t, New(testGraph(t), fixedEmbedder{0, 1}, time.Minute))
+
+	var impact query.ImpactResult
+	if code := post(t, ts, "impact", Request{Target: "app:Save", Depth: 2, GroupBy: "feature"}, &impact); code != http.StatusOK {
+		t.Fatalf("impact failed with %d", code)
+	}
+	if len(impact.Callers) != 2 || impact.Callers[0].ID != "app:Handler" || len(impact.Paths[1].Nodes) != 3 {
+		t.Errorf("Expected Handler and Main with their paths, got %+v", impact)
+	}
+	if len(impact.Groups) != 2 || impact.Groups[0].Key != "feat-auth" {
+		t.Errorf("Expected callers grouped by feature, got %+v", impact.Groups)
+	}
+
+	// Save is ambiguous on its own
+	if code := post(t, ts, "neighbors", Request{Target: "Save", File: "util.ts"}, nil); code != http.StatusOK {
+		t.Errorf("Expected the file to single out util.ts Save, got %d", code)
+	}
+
+	var source map[string]string
+	if code := post(t, ts, "fetch-source", Request{Target: "app:Save"}, &source); code != http.StatusOK || !strings.HasPrefix(source["source"], "function Save() {") {
+		t.Errorf("Expected the source of Save, got %d %+v", code, source)
+	}
+
+	var features []*query.FeatureResult
+	if code := post(t, ts, "search-features", Request{Target: "login", Limit: 1}, &features); code != http.StatusOK || len(features) != 1 || features[0].Node.ID != "feat-auth" {
+		t.Errorf("Expected the auth feature, got %d %+v", code, features)
+	}
+
+	var paths []json.RawMessage
+	if code := post(t, ts, "traverse", Request{Target: "Main", EdgeTypes: "CALLS", Depth: 2}, &paths); code != http.StatusOK || len(paths) != 2 {
+		t.Errorf("Expected 2 paths from Main, got %d %d", code, len(paths))
+	}
+
+	var status map[string]string
+	if code := post(t, ts, "status", nil, &status); code != http.StatusOK || status["commit"] != "abc123" {
+		t.Errorf("Expected the commit, got %d %+v", code, status)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	ts := startServer(t, New(testGraph(t), nil, time.Minute))
+
+	var resp ErrorResponse
+	if code := post(t, ts, "globals", Request{Target: "Save"}, &resp); code != http.StatusConflict || len(resp.Candidates) != 2 {
+		t.Errorf("Expected 409 with the candidates of Save, got %d %+v", code, resp)
+	}
+
+	resp = ErrorResponse{}
+	if code := post(t, ts, "neighbors", Request{Target: "Handlr"}, &resp); code != http.StatusNotFound || len(resp.Suggestions) != 1 || resp.Suggestions[0] != "Handler" {
+		t.Errorf("Expected 404 suggesting Handler, got %d %+v", code, resp)
+	}
+
+	for endpoint, req := range map[string]Request{
+		"impact":         {},
+		"locate-usage":   {Target: "Main"},
+		"traverse":       {Target: "Main", Direction: "sideways"},
+		"neighbors":      {Target: "Main", Depth: query.MaxDepth + 1},
+		"search-similar": {Target: "save", Limit: query.MaxLimit + 1},
+	} {
+		resp = ErrorResponse{}
+		if code := post(t, ts, endpoint, req, &resp); code != http.StatusBadRequest || resp.Error == "" {
+			t.Errorf("Expected 400 from %s, got %d %+v", endpoint, code, resp)
+		}
+	}
+
+	r, err := http.Post(ts.URL+"/v1/impact", "application/json", strings.NewReader("{"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed body, got %d", r.StatusCode)
+	}
+
+	resp = ErrorResponse{}
+	if code := post(t, ts, "search-features", Request{Target: "login"}, &resp); code != http.StatusInternalServerError || !strings.Contains(resp.Error, "no embedder") {
+		t.Errorf("Expected search to fail without an embedder, got %d %+v", code, resp)
+	}
+}
+
+// blockingProvider answers GetSeams only once its context is done, and
+// reports the error it saw on cancelled.
+type blockingProvider struct {
+	query.GraphProvider
+	ctx       context.Context
+	cancelled chan error
+}
+
+func (p *blockingProvider) WithContext(ctx context.Context) query.GraphProvider {
+	return &blockingProvider{GraphProvider: p.GraphProvider, ctx: ctx, cancelled: p.cancelled}
+}
+
+func (p *blockingProvider) GetSeams(modulePattern string) ([]*query.SeamResult, error) {
+	<-p.ctx.Done()
+	p.cancelled <- p.ctx.Err()
+	return nil, p.ctx.Err()
+}
+
+func (p *blockingProvider) GetGraphState() (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func TestServer_Cancellation(t *testing.T) {
+	provider := &blockingProvider{GraphProvider: testGraph(t), ctx: context.Background(), cancelled: make(chan error, 1)}
+	ts := startServer(t, New(provider, nil, 50*time.Millisecond))
+
+	var resp ErrorResponse
+	if code := post(t, ts, "seams", Request{}, &resp); code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504, got %d %+v", code, resp)
+	}
+	select {
+	case err := <-provider.cancelled:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the query to see the deadline, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the query context to be cancelled")
+	}
+
+	if code, body := get(t, ts, "/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "connection refused") {
+		t.Errorf("Expected not ready, got %d %s", code, body)
+	}
+}
+
+// blockingEmbedder answers once release is closed.
+type blockingEmbedder chan struct{}
+
+func (e blockingEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
+	<-e
+	return fixedEmbedder{0, 1}.EmbedBatch(texts, purpose)
+}
+
+func TestServer_EmbeddingTimeout(t *testing.T) {
+	release := make(blockingEmbedder)
+	defer close(release)
+	ts := startServer(t, New(testGraph(t), release, 50*time.Millisecond))
+
+	var resp ErrorResponse
+	if code := post(t, ts, "search-features", Request{Target: "login"}, &resp); code != http.StatusGatewayTimeout {
+		t.Errorf("Expected a hung embedder to time out with 504, got %d %+v", code, resp)
+	}
+}
+
+func TestServer_HealthAndMetrics(t *testing.T) {
+	ts := startServer(t, New(testGraph(t), nil, 0))
+
+	if code, body := get(t, ts, "/healthz"); code != http.StatusOK || !strings.Contains(body, `"ok"`) {
+		t.Errorf("Expected healthy, got %d %s", code, body)
+	}
+	if code, body := get(t, ts, "/readyz"); code != http.StatusOK || !strings.Contains(body, `"commit": "abc123"`) {
+		t.Errorf("Expected ready at abc123, got %d %s", code, body)
+	}
+	post(t, ts, "globals", Request{Target: "app:Handler"}, nil)
+	post(t, ts, "globals", Request{Target: "Save"}, nil)
+
+	code, body := get(t, ts, "/metrics")
+	if code != http.StatusOK {
+		t.Fatalf("metrics failed with %d", code)
+	}
+	for _, want := range []string{
+		`graphdb_http_requests_total{endpoint="globals",code="200"} 1`,
+		`graphdb_http_requests_total{endpoint="globals",code="409"} 1`,
+		`graphdb_http_requests_total{endpoint="readyz",code="200"} 1`,
+		`graphdb_http_request_duration_seconds_bucket{endpoint="globals",le="+Inf"} 2`,
+		`graphdb_http_request_duration_seconds_count{endpoint="globals"} 2`,
+		"# TYPE graphdb_http_request_duration_seconds histogram",
+		// The metrics request itself
+		"graphdb_http_requests_in_flight 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the metrics:\n%s", want, body)
+		}
+	}
+}
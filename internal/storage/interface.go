@@ -7,3 +7,19 @@ type Emitter interface {
 	EmitEdge(edge *graph.Edge) error
 	Close() error
 }
+
+// Deleter is implemented by emitters that can record removals. Incremental
+// ingest uses it to emit tombstones for nodes and edges that no longer exist;
+// deleting a node also removes its relationships.
+type Deleter interface {
+	DeleteNode(id string) error
+	DeleteEdge(edge *graph.Edge) error
+}
+
+// Updater is implemented by emitters that can record partial updates:
+// properties to merge into a node that another run emitted. Enrichment uses
+// it to attach atomic features to Function nodes; an update never creates
+// the node.
+type Updater interface {
+	UpdateNode(node *graph.Node) error
+}
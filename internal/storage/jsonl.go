@@ -67,6 +67,21 @@ func (e *SplitJSONLEmitter) EmitEdge(edge *graph.Edge) error {
 	return e.edgeEncoder.Encode(out)
 }
 
+// UpdateNode writes a partial update record to the nodes file.
+func (e *SplitJSONLEmitter) UpdateNode(node *graph.Node) error {
+	return e.nodeEncoder.Encode(partialNode(node))
+}
+
+// DeleteNode writes a node tombstone to the nodes file.
+func (e *SplitJSONLEmitter) DeleteNode(id string) error {
+	return e.nodeEncoder.Encode(nodeTombstone(id))
+}
+
+// DeleteEdge writes an edge tombstone to the edges file.
+func (e *SplitJSONLEmitter) DeleteEdge(edge *graph.Edge) error {
+	return e.edgeEncoder.Encode(edgeTombstone(edge))
+}
+
 func (e *SplitJSONLEmitter) Close() error {
 	if e.nodeCloser != nil {
 		e.nodeCloser.Close()
@@ -119,6 +134,54 @@ func (e *JSONLEmitter) EmitEdge(edge *graph.Edge) error {
 	return e.encoder.Encode(out)
 }
 
+// UpdateNode writes a partial update record: the node with "partial": true.
+func (e *JSONLEmitter) UpdateNode(node *graph.Node) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.encoder.Encode(partialNode(node))
+}
+
+// DeleteNode writes a node tombstone: {"id": ..., "deleted": true}.
+func (e *JSONLEmitter) DeleteNode(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.encoder.Encode(nodeTombstone(id))
+}
+
+// DeleteEdge writes an edge tombstone: the edge record with "deleted": true.
+func (e *JSONLEmitter) DeleteEdge(edge *graph.Edge) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.encoder.Encode(edgeTombstone(edge))
+}
+
+func partialNode(node *graph.Node) map[string]interface{} {
+	out := make(map[string]interface{}, len(node.Properties)+3)
+	for k, v := range node.Properties {
+		out[k] = v
+	}
+	out["id"] = node.ID
+	out["type"] = node.Label
+	out["partial"] = true
+	return out
+}
+
+func nodeTombstone(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"deleted": true,
+	}
+}
+
+func edgeTombstone(edge *graph.Edge) map[string]interface{} {
+	return map[string]interface{}{
+		"source":  edge.SourceID,
+		"target":  edge.TargetID,
+		"type":    edge.Type,
+		"deleted": true,
+	}
+}
+
 // Close closes the underlying writer if it implements io.Closer.
 func (e *JSONLEmitter) Close() error {
 	if c, ok := e.w.(io.Closer); ok {
@@ -117,3 +117,58 @@ func TestJSONLEmitter_Concurrent(t *testing.T) {
 		t.Errorf("Expected %d lines, got %d", expected, lines)
 	}
 }
+
+func TestJSONLEmitter_Tombstones(t *testing.T) {
+	var buf bytes.Buffer
+	emitter := storage.NewJSONLEmitter(&buf)
+
+	var deleter storage.Deleter = emitter
+	if err := deleter.DeleteNode("node-1"); err != nil {
+		t.Fatalf("DeleteNode failed: %v", err)
+	}
+	if err := deleter.DeleteEdge(&graph.Edge{SourceID: "node-1", TargetID: "node-2", Type: "CALLS"}); err != nil {
+		t.Fatalf("DeleteEdge failed: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(lines))
+	}
+
+	var node, edge map[string]interface{}
+	json.Unmarshal(lines[0], &node)
+	json.Unmarshal(lines[1], &edge)
+
+	if node["id"] != "node-1" || node["deleted"] != true {
+		t.Errorf("Unexpected node tombstone: %v", node)
+	}
+	if _, ok := node["type"]; ok {
+		t.Errorf("Node tombstone should not carry a label: %v", node)
+	}
+	if edge["source"] != "node-1" || edge["target"] != "node-2" || edge["type"] != "CALLS" || edge["deleted"] != true {
+		t.Errorf("Unexpected edge tombstone: %v", edge)
+	}
+}
+
+func TestJSONLEmitter_UpdateNode(t *testing.T) {
+	var buf bytes.Buffer
+	var updater storage.Updater = storage.NewJSONLEmitter(&buf)
+
+	err := updater.UpdateNode(&graph.Node{
+		ID:         "a.go:login",
+		Label:      "Function",
+		Properties: map[string]interface{}{"atomic_features": []string{"validate credentials"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+
+	var record map[string]interface{}
+	json.Unmarshal(buf.Bytes(), &record)
+	if record["id"] != "a.go:login" || record["type"] != "Function" || record["partial"] != true {
+		t.Errorf("Unexpected partial record: %v", record)
+	}
+	if features, _ := record["atomic_features"].([]interface{}); len(features) != 1 {
+		t.Errorf("Expected the atomic features in the record: %v", record)
+	}
+}
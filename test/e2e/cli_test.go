@@ -1,11 +1,18 @@
 package e2e_test
 
 import (
+	"encoding/json"
+	"graphdb/internal/embedding"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func getRepoRoot(t *testing.T) string {
@@ -111,3 +118,371 @@ func TestCLI_Query_Seams(t *testing.T) {
 		t.Errorf("Expected JSON array output, got: %s", outStr)
 	}
 }
+
+func TestCLI_EmbeddedStore_IngestAndQuery(t *testing.T) {
+	cliPath := buildCLI(t)
+	root := getRepoRoot(t)
+
+	dbPath := filepath.Join(t.TempDir(), "graph.db")
+	fixturesPath := filepath.Join(root, "test", "fixtures", "typescript")
+
+	cmd := exec.Command(cliPath, "ingest", "-dir", fixturesPath, "-db", dbPath)
+	cmd.Env = append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Ingest command failed: %v\nOutput: %s", err, output)
+	}
+
+	// No NEO4J_URI needed: the query reads the store directly
+	cmd = exec.Command(cliPath, "query", "-backend", "bolt", "-db", dbPath,
+		"-type", "traverse", "-target", "Greeter", "-edge-types", "DEFINED_IN")
+	cmd.Env = append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true", "NEO4J_URI=")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Query command failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), `"label": "File"`) {
+		t.Errorf("Expected traversal to reach the File node, got: %s", output)
+	}
+}
+
+func TestCLI_Ingest_ScoresSeams(t *testing.T) {
+	cliPath := buildCLI(t)
+
+	src := t.TempDir()
+	files := map[string]string{
+		"view.py":  "import tkinter\n\ndef render(order):\n    return tkinter.Label(text=total(order))\n",
+		"price.py": "def total(order):\n    return sum(order)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbPath := filepath.Join(t.TempDir(), "graph.db")
+
+	env := append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true", "NEO4J_URI=")
+	cmd := exec.Command(cliPath, "ingest", "-dir", src, "-db", dbPath)
+	cmd.Env = env
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Ingest command failed: %v\nOutput: %s", err, output)
+	}
+
+	// render is a UI seed; total, which it calls, is the seam
+	cmd = exec.Command(cliPath, "query", "-backend", "bolt", "-db", dbPath, "-type", "seams", "-module", ".*")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Query command failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), `"seam": "total"`) || strings.Contains(string(output), `"seam": "render"`) {
+		t.Errorf("Expected total as the only seam, got: %s", output)
+	}
+}
+
+func TestCLI_Query_ResolvesTargets(t *testing.T) {
+	cliPath := buildCLI(t)
+
+	graphPath := filepath.Join(t.TempDir(), "graph.jsonl")
+	records := strings.Join([]string{
+		`{"id": "app/user.go:Save", "type": "Function", "name": "Save", "file": "app/user.go", "start_line": 10}`,
+		`{"id": "app/order.go:Save", "type": "Function", "name": "Save", "file": "app/order.go", "start_line": 20}`,
+		`{"id": "app/order.go:Checkout", "type": "Function", "name": "Checkout", "file": "app/order.go", "start_line": 5}`,
+		`{"source": "app/order.go:Checkout", "target": "app/order.go:Save", "type": "CALLS"}`,
+	}, "\n")
+	if err := os.WriteFile(graphPath, []byte(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) (string, error) {
+		args = append([]string{"query", "-backend", "jsonl", "-input", graphPath, "-type", "impact"}, args...)
+		cmd := exec.Command(cliPath, args...)
+		cmd.Env = append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true", "NEO4J_URI=")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run("-target", "Save")
+	if err == nil || !strings.Contains(output, "app/user.go:Save (app/user.go:10)") || !strings.Contains(output, "app/order.go:Save (app/order.go:20)") {
+		t.Errorf("Expected an ambiguous name to fail with its candidates, got %v: %s", err, output)
+	}
+
+	output, err = run("-target", "Save", "-file", "order.go")
+	if err != nil || !strings.Contains(output, `"id": "app/order.go:Checkout"`) {
+		t.Errorf("Expected -file to select the order Save, got %v: %s", err, output)
+	}
+
+	output, err = run("-target", "Chekout")
+	if err == nil || !strings.Contains(output, "did you mean Checkout?") {
+		t.Errorf("Expected a suggestion for a misspelt name, got %v: %s", err, output)
+	}
+}
+
+func TestCLI_MCP(t *testing.T) {
+	cliPath := buildCLI(t)
+
+	graphPath := filepath.Join(t.TempDir(), "graph.jsonl")
+	records := `{"id": "state", "type": "GraphState", "commit": "abc123"}`
+	if err := os.WriteFile(graphPath, []byte(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(cliPath, "mcp", "-backend", "jsonl", "-input", graphPath)
+	cmd.Env = append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true", "NEO4J_URI=")
+	cmd.Stdin = strings.NewReader(strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2025-06-18"}}`,
+		`{"jsonrpc": "2.0", "method": "notifications/initialized"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "status", "arguments": {}}}`,
+	}, "\n"))
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("MCP session failed: %v", err)
+	}
+
+	// One response per request, and nothing else on stdout
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"serverInfo"`) || !strings.Contains(lines[1], `abc123`) {
+		t.Errorf("Unexpected MCP output: %s", output)
+	}
+}
+
+func TestCLI_Serve(t *testing.T) {
+	cliPath := buildCLI(t)
+
+	graphPath := filepath.Join(t.TempDir(), "graph.jsonl")
+	records := strings.Join([]string{
+		`{"id": "state", "type": "GraphState", "commit": "abc123"}`,
+		`{"id": "app:Main", "type": "Function", "name": "Main", "file": "app.ts"}`,
+		`{"id": "app:Save", "type": "Function", "name": "Save", "file": "app.ts"}`,
+		`{"source": "app:Main", "target": "app:Save", "type": "CALLS"}`,
+	}, "\n")
+	if err := os.WriteFile(graphPath, []byte(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	cmd := exec.Command(cliPath, "serve", "-addr", addr, "-backend", "jsonl", "-input", graphPath)
+	cmd.Env = append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true", "NEO4J_URI=")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	base := "http://" + addr
+	ready := false
+	for i := 0; i < 100 && !ready; i++ {
+		if resp, err := http.Get(base + "/readyz"); err == nil {
+			ready = resp.StatusCode == http.StatusOK
+			resp.Body.Close()
+		}
+		if !ready {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	if !ready {
+		t.Fatal("Server never became ready")
+	}
+
+	resp, err := http.Post(base+"/v1/impact", "application/json", strings.NewReader(`{"target": "Save"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var impact struct {
+		Callers []struct {
+			ID string `json:"id"`
+		} `json:"callers"`
+	}
+	json.NewDecoder(resp.Body).Decode(&impact)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(impact.Callers) != 1 || impact.Callers[0].ID != "app:Main" {
+		t.Errorf("Expected Main to call Save, got %d %+v", resp.StatusCode, impact)
+	}
+
+	// Shuts down cleanly on SIGTERM
+	cmd.Process.Signal(syscall.SIGTERM)
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Expected a clean exit, got %v", err)
+	}
+}
+
+func TestCLI_HashedEmbedder_SearchSimilar(t *testing.T) {
+	cliPath := buildCLI(t)
+
+	src := t.TempDir()
+	files := map[string]string{
+		"config.py": "def parse_config_file(path):\n    \"\"\"Read and parse the YAML config file.\"\"\"\n    return yaml.load(open(path))\n",
+		"http.py":   "def send_http_request(url, body):\n    \"\"\"POST the body to the url.\"\"\"\n    return requests.post(url, body)\n",
+		"users.py":  "def load_user_profile(user_id):\n    \"\"\"Fetch a user profile by id.\"\"\"\n    return db.users.get(user_id)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbPath := filepath.Join(t.TempDir(), "graph.db")
+
+	// No mocks and no credentials: the hashed embedder runs offline
+	env := append(os.Environ(), "GRAPHDB_EMBEDDER=hashed", "GOOGLE_CLOUD_PROJECT=", "NEO4J_URI=")
+	cmd := exec.Command(cliPath, "ingest", "-dir", src, "-db", dbPath)
+	cmd.Env = env
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Ingest command failed: %v\nOutput: %s", err, output)
+	}
+
+	cmd = exec.Command(cliPath, "query", "-backend", "bolt", "-db", dbPath,
+		"-type", "search-similar", "-target", "parse the config file", "-limit", "1")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Query command failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), "parse_config_file") {
+		t.Errorf("Expected the config parser as the closest function, got: %s", output)
+	}
+}
+
+func TestCLI_OpenAIEmbedder_SearchSimilar(t *testing.T) {
+	cliPath := buildCLI(t)
+
+	// A stand-in for an OpenAI-compatible server such as Ollama
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "nomic-embed-text" {
+			t.Errorf("Expected the configured model, got %q", req.Model)
+		}
+		vecs, _ := embedding.NewHashedEmbedder(64).EmbedBatch(req.Input, embedding.PurposeDocument)
+		var data []map[string]interface{}
+		for i, v := range vecs {
+			data = append(data, map[string]interface{}{"index": i, "embedding": v})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	src := t.TempDir()
+	files := map[string]string{
+		"config.py": "def parse_config_file(path):\n    \"\"\"Read and parse the YAML config file.\"\"\"\n    return yaml.load(open(path))\n",
+		"http.py":   "def send_http_request(url, body):\n    \"\"\"POST the body to the url.\"\"\"\n    return requests.post(url, body)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbPath := filepath.Join(t.TempDir(), "graph.db")
+
+	env := append(os.Environ(), "GRAPHDB_EMBEDDER=openai", "OPENAI_BASE_URL="+server.URL+"/v1",
+		"OPENAI_EMBEDDING_MODEL=nomic-embed-text", "GRAPHDB_EMBEDDING_CACHE=off", "GOOGLE_CLOUD_PROJECT=", "NEO4J_URI=")
+	cmd := exec.Command(cliPath, "ingest", "-dir", src, "-db", dbPath)
+	cmd.Env = env
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Ingest command failed: %v\nOutput: %s", err, output)
+	}
+
+	cmd = exec.Command(cliPath, "query", "-backend", "bolt", "-db", dbPath,
+		"-type", "search-similar", "-target", "parse the config file", "-limit", "1")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Query command failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), "parse_config_file") {
+		t.Errorf("Expected the config parser as the closest function, got: %s", output)
+	}
+	if requests < 2 {
+		t.Errorf("Expected ingest and query to call the server, got %d requests", requests)
+	}
+}
+
+func TestCLI_EnrichFeatures_Resume(t *testing.T) {
+	cliPath := buildCLI(t)
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "graph.jsonl")
+	graph := `{"id": "a.go:login", "type": "Function", "name": "login", "file": "a.go", "content": "func login() {}"}
+{"id": "a.go:logout", "type": "Function", "name": "logout", "file": "a.go", "content": "func logout() {}"}
+`
+	if err := os.WriteFile(inputPath, []byte(graph), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(dir, "rpg.jsonl")
+
+	run := func(extra ...string) string {
+		args := append([]string{"enrich-features", "-dir", dir, "-input", inputPath, "-output", outputPath}, extra...)
+		cmd := exec.Command(cliPath, args...)
+		cmd.Env = append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("EnrichFeatures failed: %v\nOutput: %s", err, output)
+		}
+		return string(output)
+	}
+
+	if output := run(); !strings.Contains(output, "(0 reusable)") {
+		t.Errorf("Expected an empty checkpoint on the first run, got:\n%s", output)
+	}
+	if _, err := os.Stat(outputPath + ".checkpoint.jsonl"); err != nil {
+		t.Fatalf("Expected a checkpoint next to the output: %v", err)
+	}
+	if output := run("-resume"); !strings.Contains(output, "recorded 0") {
+		t.Errorf("Expected everything from the checkpoint on the second run, got:\n%s", output)
+	}
+	if output := run("-resume=false"); !strings.Contains(output, "(0 reusable)") {
+		t.Errorf("Expected -resume=false to start afresh, got:\n%s", output)
+	}
+}
+
+func TestCLI_EnrichFeatures_UpdatesFunctions(t *testing.T) {
+	cliPath := buildCLI(t)
+
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "graph.jsonl")
+	graph := `{"id": "a.go:login", "type": "Function", "name": "login", "file": "a.go", "content": "func login() {}"}
+`
+	if err := os.WriteFile(inputPath, []byte(graph), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(dir, "rpg.jsonl")
+
+	cmd := exec.Command(cliPath, "enrich-features", "-dir", dir, "-input", inputPath, "-output", outputPath, "-checkpoint", "off")
+	cmd.Env = append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("EnrichFeatures failed: %v\nOutput: %s", err, output)
+	}
+
+	rpgOutput, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(rpgOutput), `"id":"a.go:login","partial":true`) {
+		t.Errorf("Expected a partial update of the function in rpg.jsonl, got:\n%s", rpgOutput)
+	}
+
+	// The update is merged into the function from graph.jsonl
+	cmd = exec.Command(cliPath, "query", "-backend", "jsonl", "-input", inputPath, "-rpg", outputPath,
+		"-type", "traverse", "-target", "login", "-edge-types", "IMPLEMENTS", "-depth", "1")
+	cmd.Env = append(os.Environ(), "GRAPHDB_MOCK_ENABLED=true")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Query command failed: %v\nOutput: %s", err, output)
+	}
+	for _, want := range []string{`"atomic_features"`, `"feature_id"`, `"content": "func login() {}"`} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Expected %s on the function, got:\n%s", want, output)
+		}
+	}
+}
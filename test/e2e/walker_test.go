@@ -15,7 +15,7 @@ type MockEmbedder struct{}
 
 var _ embedding.Embedder = (*MockEmbedder)(nil)
 
-func (m *MockEmbedder) EmbedBatch(texts []string) ([][]float32, error) {
+func (m *MockEmbedder) EmbedBatch(texts []string, purpose embedding.Purpose) ([][]float32, error) {
 	result := make([][]float32, len(texts))
 	for i := range texts {
 		result[i] = []float32{0.1, 0.2, 0.3} // Dummy embedding
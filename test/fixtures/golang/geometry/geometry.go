@@ -0,0 +1,30 @@
+package geometry
+
+import "math"
+
+// Precision is the number of decimal places used when rounding areas.
+var Precision = 2
+
+const Unit = "cm"
+
+type Shape interface {
+	Area() float64
+	Name() string
+}
+
+type Circle struct {
+	Radius float64
+}
+
+func (c *Circle) Area() float64 {
+	return Round(math.Pi * c.Radius * c.Radius)
+}
+
+func (c *Circle) Name() string {
+	return "circle"
+}
+
+func Round(v float64) float64 {
+	scale := math.Pow(10, float64(Precision))
+	return math.Round(v*scale) / scale
+}
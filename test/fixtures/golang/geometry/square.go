@@ -0,0 +1,18 @@
+package geometry
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return Round(s.Side * s.Side)
+}
+
+// Resize calls the grow it is given, not the package function.
+func Resize(s Square, grow func(float64) float64) Meters {
+	return Meters(grow(s.Side) * float64(Precision))
+}
+
+func grow(v float64) float64 {
+	return v * 2
+}
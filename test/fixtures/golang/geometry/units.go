@@ -0,0 +1,7 @@
+package geometry
+
+type Meters float64
+
+func (s Square) Name() string {
+	return "square"
+}
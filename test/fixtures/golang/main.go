@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+
+	geo "example.com/shapes/geometry"
+)
+
+func describe(s geo.Shape) string {
+	return fmt.Sprintf("%s: %.2f%s", s.Name(), s.Area(), geo.Unit)
+}
+
+func main() {
+	c := &geo.Circle{Radius: 2}
+	fmt.Println(describe(c))
+	fmt.Println(geo.Round(c.Area()))
+}
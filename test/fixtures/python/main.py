@@ -0,0 +1,11 @@
+import shop.models.order as order_mod
+from shop.models import base
+
+
+def main():
+    order_mod.create_order()
+    base.Model().save()
+
+
+if __name__ == "__main__":
+    main()
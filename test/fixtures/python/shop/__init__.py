@@ -0,0 +1 @@
+from .config import DEFAULT_CURRENCY
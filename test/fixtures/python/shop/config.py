@@ -0,0 +1,5 @@
+DEFAULT_CURRENCY = "EUR"
+
+
+def load_settings(path):
+    return {"path": path}
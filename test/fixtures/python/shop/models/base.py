@@ -0,0 +1,10 @@
+import logging
+
+
+class Model(object):
+    def save(self):
+        logging.info("saving")
+        return self.validate()
+
+    def validate(self):
+        return True
@@ -0,0 +1,32 @@
+import functools
+from .base import Model
+from .. import config
+from ..config import load_settings as settings
+
+TAX_RATE = 0.2
+
+
+def cached(fn):
+    return functools.wraps(fn)(fn)
+
+
+class Order(Model, metaclass=type):
+    @cached
+    def total(self, amount):
+        rate = self.tax()
+        return amount * (1 + rate)
+
+    @staticmethod
+    def tax():
+        settings("shop.ini")
+        return TAX_RATE
+
+    def checkout(self):
+        self.save()
+        print(config.DEFAULT_CURRENCY)
+        return config.load_settings("checkout.ini")
+
+
+def create_order():
+    order = Order()
+    return order.total(10)